@@ -0,0 +1,160 @@
+package common
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/privacybydesign/gabi/big"
+)
+
+// Precomputed table for fast fixed base modular exponentiation, following
+// github.com/bwesterb/go-exptable, but with a serializable representation.
+//
+// Entry i*(2^w-1) + (j-1) of the table holds b^(j * 2^(i*w)) mod m.
+
+type ExpTable struct {
+	m   big.Int
+	mod FastMod
+	w   uint
+	v   []big.Int
+}
+
+// Fill the table for base b and modulus m using window width w
+func (t *ExpTable) Compute(b, m *big.Int, w uint) {
+	t.m.Set(m)
+	t.mod.Set(m)
+	t.w = w
+
+	wm := (uint(1) << w) - 1
+	n := uint(m.BitLen()-1)/w + 1
+	t.v = make([]big.Int, n*wm)
+
+	var x, rb, tmp big.Int
+	x.Set(b)
+	rb.Set(b)
+	for i := uint(0); i < uint(len(t.v)); i += wm {
+		for j := uint(0); j < wm; j++ {
+			t.v[i+j].Set(&x)
+			tmp.Mul(&x, &rb)
+			t.mod.Mod(&x, &tmp)
+		}
+		rb.Set(&x)
+	}
+}
+
+// Set r to b^s mod m, where b and m are the arguments given to Compute.
+// s should be non-negative and smaller than 2^(bitlen(m)).
+func (t *ExpTable) Exp(r, s *big.Int) *big.Int {
+	wm := (uint(1) << t.w) - 1
+	var s2, tmp, res big.Int
+	res.SetUint64(1)
+	s2.Set(s)
+	for i := uint(0); i*wm < uint(len(t.v)); i++ {
+		if s2.Sign() == 0 {
+			break
+		}
+		ws := uint(s2.Bits()[0]) & wm
+		s2.Rsh(&s2, t.w)
+		if ws == 0 {
+			continue
+		}
+		tmp.Mul(&res, &t.v[i*wm+ws-1])
+		if !t.mod.enabled {
+			res.Mod(&tmp, &t.m)
+			continue
+		}
+
+		// For m = 2^b - c, only partially reduce, twice folding
+		// the part above 2^b back in, the result stays small.
+		var carry big.Int
+		carry.Rsh(&tmp, t.mod.b)
+		res.And(&tmp, &t.mod.mask)
+		tmp.Mul(&carry, &t.mod.c)
+		res.Add(&res, &tmp)
+		carry.Rsh(&res, t.mod.b)
+		res.And(&res, &t.mod.mask)
+		tmp.Mul(&carry, &t.mod.c)
+		res.Add(&res, &tmp)
+	}
+	return t.mod.Mod(r, &res)
+}
+
+// Serialized form: window width (1 byte), entry size in bytes (4 bytes),
+// the modulus and then all entries, each as fixed size big endian numbers.
+func (t *ExpTable) MarshalBinary() ([]byte, error) {
+	size := (t.m.BitLen() + 7) / 8
+	res := make([]byte, 5+size*(len(t.v)+1))
+	res[0] = byte(t.w)
+	binary.BigEndian.PutUint32(res[1:5], uint32(size))
+	put := func(pos int, x *big.Int) {
+		b := x.Bytes()
+		copy(res[pos+size-len(b):pos+size], b)
+	}
+	put(5, &t.m)
+	for i := range t.v {
+		put(5+size*(i+1), &t.v[i])
+	}
+	return res, nil
+}
+
+func (t *ExpTable) UnmarshalBinary(data []byte) error {
+	if len(data) < 5 {
+		return errors.New("exptable: data too short")
+	}
+	w := uint(data[0])
+	size := int(binary.BigEndian.Uint32(data[1:5]))
+	if w == 0 || w > 16 || size == 0 || (len(data)-5)%size != 0 {
+		return errors.New("exptable: invalid header")
+	}
+
+	var m big.Int
+	m.SetBytes(data[5 : 5+size])
+	if m.Sign() == 0 || (m.BitLen()+7)/8 != size {
+		return errors.New("exptable: invalid modulus")
+	}
+	wm := (uint(1) << w) - 1
+	n := uint(m.BitLen()-1)/w + 1
+	if uint((len(data)-5)/size-1) != n*wm {
+		return errors.New("exptable: incorrect number of entries")
+	}
+
+	v := make([]big.Int, n*wm)
+	for i := range v {
+		pos := 5 + size*(i+1)
+		v[i].SetBytes(data[pos : pos+size])
+		if v[i].Cmp(&m) >= 0 {
+			return errors.New("exptable: entry out of range")
+		}
+	}
+
+	t.m.Set(&m)
+	t.mod.Set(&m)
+	t.w = w
+	t.v = v
+	return nil
+}
+
+// Check entry j*2^(i*w) against a direct calculation for base b,
+// to spot check a table that was loaded.
+func (t *ExpTable) CheckEntry(b *big.Int, index int) bool {
+	if index < 0 || index >= len(t.v) {
+		return false
+	}
+	wm := (1 << t.w) - 1
+	i, j := index/wm, index%wm+1
+	exp := new(big.Int).Lsh(big.NewInt(int64(j)), uint(i)*t.w)
+	return new(big.Int).Exp(b, exp, &t.m).Cmp(&t.v[index]) == 0
+}
+
+// Number of entries in the table
+func (t *ExpTable) Len() int {
+	return len(t.v)
+}
+
+func (t *ExpTable) Window() uint {
+	return t.w
+}
+
+func (t *ExpTable) Modulus() *big.Int {
+	return new(big.Int).Set(&t.m)
+}
@@ -0,0 +1,97 @@
+package common
+
+import (
+	"github.com/privacybydesign/gabi/big"
+
+	"testing"
+)
+
+func testExpTable(t *testing.T, b, m *big.Int) {
+	var table ExpTable
+	table.Compute(b, m, 4)
+
+	var e, r1, r2 big.Int
+	for i := 0; i < 20; i++ {
+		e.Rand(rnd, m)
+		r1.Exp(b, &e, m)
+		table.Exp(&r2, &e)
+		if r1.Cmp(&r2) != 0 {
+			t.Fatalf("%v^%v mod %v = %v != %v", b, &e, m, &r1, &r2)
+		}
+	}
+}
+
+func TestExpTable(t *testing.T) {
+	var m, l big.Int
+	for j := 4; j < 12; j++ {
+		l.SetUint64(1)
+		l.Lsh(&l, 1<<uint(j))
+		for i := 0; i < 5; i++ {
+			m.Rand(rnd, &l)
+			m.SetBit(&m, 0, 1)
+			if m.Cmp(big.NewInt(3)) < 0 {
+				continue
+			}
+			testExpTable(t, big.NewInt(3), &m)
+		}
+	}
+}
+
+func TestExpTableFastMod(t *testing.T) {
+	// 2^787 - 7341, a modulus for which FastMod is used
+	m := new(big.Int).Lsh(big.NewInt(1), 787)
+	m.Sub(m, big.NewInt(7341))
+	testExpTable(t, big.NewInt(0x41424344), m)
+}
+
+func TestExpTableSerialization(t *testing.T) {
+	m := new(big.Int).Lsh(big.NewInt(1), 787)
+	m.Sub(m, big.NewInt(7341))
+
+	var table ExpTable
+	table.Compute(big.NewInt(5), m, 5)
+	data, err := table.MarshalBinary()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var loaded ExpTable
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err.Error())
+	}
+	if loaded.Len() != table.Len() || loaded.Window() != 5 || loaded.Modulus().Cmp(m) != 0 {
+		t.Error("Table parameters changed by serialization")
+	}
+	for _, index := range []int{0, 17, loaded.Len() - 1} {
+		if !loaded.CheckEntry(big.NewInt(5), index) {
+			t.Errorf("Incorrect entry %d after serialization", index)
+		}
+	}
+	var e, r1, r2 big.Int
+	e.Rand(rnd, m)
+	table.Exp(&r1, &e)
+	loaded.Exp(&r2, &e)
+	if r1.Cmp(&r2) != 0 {
+		t.Error("Loaded table gives different result")
+	}
+
+	if loaded.UnmarshalBinary(data[:len(data)-1]) == nil {
+		t.Error("Accepted truncated table")
+	}
+	if loaded.UnmarshalBinary(data[:3]) == nil {
+		t.Error("Accepted table without header")
+	}
+
+	// Entry larger than the modulus
+	corrupt := append([]byte{}, data...)
+	for i := len(corrupt) - 99; i < len(corrupt); i++ {
+		corrupt[i] = 0xff
+	}
+	if loaded.UnmarshalBinary(corrupt) == nil {
+		t.Error("Accepted entry out of range")
+	}
+
+	if loaded.CheckEntry(big.NewInt(5), -1) || loaded.CheckEntry(big.NewInt(5), loaded.Len()) {
+		t.Error("Accepted check of entry out of bounds")
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"github.com/privacybydesign/gabi/big"
 )
 
-// Fast modulo operation for p = 2^b - c for small c.
+// Fast modulo operation for p = 2^b - c for small c. The part above 2^b is
+// repeatedly folded back in. Other moduli use big.Int.Mod, as neither Barrett
+// nor Montgomery reduction measurably beat it for 1024 to 4096 bit moduli.
 
 type FastMod struct {
-	enabled bool
+	enabled bool // Whether p = 2^b - c with small c
 	p       big.Int
 	c       big.Int
 	b       uint
@@ -35,10 +37,21 @@ func (m *FastMod) Mod(ret, x *big.Int) *big.Int {
 		return ret.Mod(x, &m.p)
 	}
 
-    if x.Sign() == -1 {
-		return ret.Mod(x, &m.p) // TODO
-    }
+	if x.Sign() == -1 {
+		// -x mod p = p - (x mod p), for x mod p != 0
+		var abs big.Int
+		abs.Neg(x)
+		m.modPositive(ret, &abs)
+		if ret.Sign() != 0 {
+			ret.Sub(&m.p, ret)
+		}
+		return ret
+	}
+
+	return m.modPositive(ret, x)
+}
 
+func (m *FastMod) modPositive(ret, x *big.Int) *big.Int {
 	if x.Cmp(&m.p) < 0 {
 		return ret.Set(x)
 	}
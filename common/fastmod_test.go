@@ -56,6 +56,73 @@ func TestFastModSlow(t *testing.T) {
 	}
 }
 
+func TestFastModGeneral(t *testing.T) {
+	var p, l big.Int
+	for _, bits := range []uint{511, 512, 513, 1024, 2048, 2111, 4096} {
+		l.SetUint64(1)
+		l.Lsh(&l, bits)
+		for i := 0; i < 5; i++ {
+			p.Rand(rnd, &l)
+			p.SetBit(&p, int(bits)-1, 1)
+			testFastMod(t, &p)
+			testFastModSquares(t, &p)
+		}
+	}
+}
+
+// Inputs up to p^2, as in modular multiplication, and beyond
+func testFastModSquares(t *testing.T, p *big.Int) {
+	var fm FastMod
+	var a, l, r1, r2 big.Int
+	fm.Set(p)
+	for _, bound := range []*big.Int{
+		new(big.Int).Mul(p, p),
+		new(big.Int).Lsh(big.NewInt(1), uint(2*p.BitLen())),
+		new(big.Int).Lsh(big.NewInt(1), uint(3*p.BitLen())),
+	} {
+		l.Set(bound)
+		for i := 0; i < 10; i++ {
+			a.Rand(rnd, &l)
+			r1.Mod(&a, p)
+			fm.Mod(&r2, &a)
+			if r1.Cmp(&r2) != 0 {
+				t.Fatalf("%v mod %v = %v != %v", &a, p, &r1, &r2)
+			}
+		}
+	}
+}
+
+func TestFastModNegative(t *testing.T) {
+	var fm FastMod
+	var r1, r2 big.Int
+	for _, p := range []*big.Int{
+		new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 787), big.NewInt(7341)),
+		new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), 1023), big.NewInt(12345)),
+		big.NewInt(47),
+	} {
+		fm.Set(p)
+		for _, x := range []*big.Int{
+			big.NewInt(-1),
+			new(big.Int).Neg(p),
+			new(big.Int).Neg(new(big.Int).Mul(p, big.NewInt(12345))),
+			new(big.Int).Neg(new(big.Int).Rand(rnd, new(big.Int).Mul(p, p))),
+		} {
+			r1.Mod(x, p)
+			fm.Mod(&r2, x)
+			if r1.Cmp(&r2) != 0 {
+				t.Fatalf("%v mod %v = %v != %v", x, p, &r1, &r2)
+			}
+		}
+
+		// In place, as used by the proofs
+		x := big.NewInt(-5)
+		fm.Mod(x, x)
+		if x.Cmp(new(big.Int).Sub(p, big.NewInt(5))) != 0 {
+			t.Fatalf("-5 mod %v in place = %v", p, x)
+		}
+	}
+}
+
 func benchmarkFastMod(b *testing.B, f float32, bits uint) {
 	var fm FastMod
 	var l, bi12345, n, r big.Int
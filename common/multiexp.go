@@ -0,0 +1,109 @@
+package common
+
+import (
+	"github.com/privacybydesign/gabi/big"
+)
+
+// Simultaneous multi-exponentiation (Straus' method). The product of
+// bases[i]^exps[i] is computed with a single chain of squarings shared by all
+// bases, each base multiplying in its precomputed small powers once every w_i
+// bits of its exponent, with w_i chosen by the size of that exponent.
+
+// Minimal number of bases for which a multi-exponentiation with a general
+// modulus is faster than separate exponentiations
+const multiExpMinBases = 3
+
+// Window width minimizing the 2^w precomputed powers plus bits/w multiplications
+func multiExpWindow(bits int) uint {
+	best := uint(1)
+	bestCost := 1 + bits
+	for w := uint(2); w <= 8; w++ {
+		cost := (1 << w) + bits/int(w)
+		if cost < bestCost {
+			best, bestCost = w, cost
+		}
+	}
+	return best
+}
+
+// Set ret to the product of bases[i]^exps[i] modulo the modulus of mod.
+// Exponents should be non-negative.
+func MultiExp(ret *big.Int, bases, exps []*big.Int, mod *FastMod) *big.Int {
+	if len(bases) != len(exps) {
+		panic("MultiExp: number of bases and exponents differs")
+	}
+
+	// The Montgomery multiplication in big.Int.Exp beats a multiplication
+	// followed by a general reduction, so for few bases separate
+	// exponentiations are faster unless the modulus is of the form 2^b - c
+	if !mod.enabled && len(bases) < multiExpMinBases {
+		var res, tmp big.Int
+		res.SetUint64(1)
+		for i, exp := range exps {
+			if exp.Sign() < 0 {
+				panic("MultiExp: negative exponent")
+			}
+			tmp.Exp(bases[i], exp, &mod.p)
+			tmp.Mul(&tmp, &res)
+			mod.Mod(&res, &tmp)
+		}
+		return mod.Mod(ret, &res)
+	}
+
+	type term struct {
+		w     uint
+		exp   *big.Int
+		table []big.Int // base^1 .. base^(2^w-1)
+	}
+
+	var tmp big.Int
+	terms := []term{}
+	maxBits := 0
+	for i, exp := range exps {
+		if exp.Sign() < 0 {
+			panic("MultiExp: negative exponent")
+		}
+		if exp.Sign() == 0 {
+			continue
+		}
+		t := term{w: multiExpWindow(exp.BitLen()), exp: exp}
+		t.table = make([]big.Int, (1<<t.w)-1)
+		mod.Mod(&t.table[0], bases[i])
+		for j := 1; j < len(t.table); j++ {
+			tmp.Mul(&t.table[j-1], &t.table[0])
+			mod.Mod(&t.table[j], &tmp)
+		}
+		terms = append(terms, t)
+		if exp.BitLen() > maxBits {
+			maxBits = exp.BitLen()
+		}
+	}
+
+	var res big.Int
+	res.SetUint64(1)
+	started := false
+	for j := maxBits - 1; j >= 0; j-- {
+		if started {
+			tmp.Mul(&res, &res)
+			mod.Mod(&res, &tmp)
+		}
+		for k := range terms {
+			t := &terms[k]
+			if uint(j)%t.w != 0 {
+				continue
+			}
+			d := uint(0)
+			for b := int(t.w) - 1; b >= 0; b-- {
+				d = d<<1 | t.exp.Bit(j+b)
+			}
+			if d == 0 {
+				continue
+			}
+			tmp.Mul(&res, &t.table[d-1])
+			mod.Mod(&res, &tmp)
+			started = true
+		}
+	}
+
+	return mod.Mod(ret, &res)
+}
@@ -0,0 +1,118 @@
+package common
+
+import (
+	"github.com/privacybydesign/gabi/big"
+
+	"testing"
+)
+
+func testMultiExp(t *testing.T, m *big.Int, bits []uint) {
+	var fm FastMod
+	fm.Set(m)
+
+	bases := []*big.Int{}
+	exps := []*big.Int{}
+	expected := big.NewInt(1)
+	for _, b := range bits {
+		base := new(big.Int).Rand(rnd, m)
+		exp := new(big.Int).Rand(rnd, new(big.Int).Lsh(big.NewInt(1), b))
+		bases = append(bases, base)
+		exps = append(exps, exp)
+		expected.Mul(expected, new(big.Int).Exp(base, exp, m))
+		expected.Mod(expected, m)
+	}
+
+	var result big.Int
+	MultiExp(&result, bases, exps, &fm)
+	if result.Cmp(expected) != 0 {
+		t.Fatalf("MultiExp mod %v: %v != %v", m, &result, expected)
+	}
+}
+
+func TestMultiExp(t *testing.T) {
+	var m, l big.Int
+	for _, size := range []uint{16, 256, 787, 1024, 2048} {
+		l.SetUint64(1)
+		l.Lsh(&l, size)
+		m.Rand(rnd, &l)
+		m.SetBit(&m, int(size)-1, 1)
+		m.SetBit(&m, 0, 1)
+		testMultiExp(t, &m, []uint{size})
+		testMultiExp(t, &m, []uint{size, size})
+		testMultiExp(t, &m, []uint{1, 7, 256, size + 300, 2})
+	}
+
+	// Modulus of the form 2^b - c
+	m.SetUint64(1)
+	m.Lsh(&m, 787)
+	m.Sub(&m, big.NewInt(7341))
+	testMultiExp(t, &m, []uint{787, 787, 256})
+}
+
+func TestMultiExpEdgeCases(t *testing.T) {
+	var fm FastMod
+	m := big.NewInt(1019)
+	fm.Set(m)
+
+	var result big.Int
+	if MultiExp(&result, nil, nil, &fm).Cmp(big.NewInt(1)) != 0 {
+		t.Error("Empty product is not 1")
+	}
+	if MultiExp(&result, []*big.Int{big.NewInt(5)}, []*big.Int{big.NewInt(0)}, &fm).Cmp(big.NewInt(1)) != 0 {
+		t.Error("Zero exponent does not give 1")
+	}
+	// Bases larger than the modulus
+	if MultiExp(&result, []*big.Int{big.NewInt(1019 + 5)}, []*big.Int{big.NewInt(3)}, &fm).Cmp(big.NewInt(125)) != 0 {
+		t.Error("Base larger than modulus not reduced")
+	}
+}
+
+// Compare against separate exponentiations, for a general modulus and one
+// of the form 2^b - c as used for the group primes
+func benchmarkMultiExp(b *testing.B, n int, separate bool, special bool) {
+	var fm FastMod
+	var m big.Int
+	m.SetUint64(1)
+	m.Lsh(&m, 2570)
+	if special {
+		m.Sub(&m, big.NewInt(12345))
+	} else {
+		m.Rand(rnd, &m)
+		m.SetBit(&m, 2569, 1)
+		m.SetBit(&m, 0, 1)
+	}
+	fm.Set(&m)
+
+	bases := []*big.Int{}
+	exps := []*big.Int{}
+	for i := 0; i < n; i++ {
+		bases = append(bases, new(big.Int).Rand(rnd, &m))
+		exps = append(exps, new(big.Int).Rand(rnd, &m))
+	}
+
+	b.ResetTimer()
+	var r, tmp big.Int
+	for i := 0; i < b.N; i++ {
+		if !separate {
+			MultiExp(&r, bases, exps, &fm)
+			continue
+		}
+		r.SetUint64(1)
+		for j := range bases {
+			tmp.Exp(bases[j], exps[j], &m)
+			r.Mul(&r, &tmp)
+			r.Mod(&r, &m)
+		}
+	}
+}
+
+func BenchmarkMultiExp2(b *testing.B)           { benchmarkMultiExp(b, 2, false, false) }
+func BenchmarkMultiExp4(b *testing.B)           { benchmarkMultiExp(b, 4, false, false) }
+func BenchmarkMultiExp8(b *testing.B)           { benchmarkMultiExp(b, 8, false, false) }
+func BenchmarkSeparateExp2(b *testing.B)        { benchmarkMultiExp(b, 2, true, false) }
+func BenchmarkSeparateExp4(b *testing.B)        { benchmarkMultiExp(b, 4, true, false) }
+func BenchmarkSeparateExp8(b *testing.B)        { benchmarkMultiExp(b, 8, true, false) }
+func BenchmarkMultiExp2Special(b *testing.B)    { benchmarkMultiExp(b, 2, false, true) }
+func BenchmarkMultiExp4Special(b *testing.B)    { benchmarkMultiExp(b, 4, false, true) }
+func BenchmarkSeparateExp2Special(b *testing.B) { benchmarkMultiExp(b, 2, true, true) }
+func BenchmarkSeparateExp4Special(b *testing.B) { benchmarkMultiExp(b, 4, true, true) }
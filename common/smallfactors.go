@@ -0,0 +1,71 @@
+package common
+
+import (
+	"math/bits"
+	"sort"
+	"sync"
+
+	"github.com/privacybydesign/gabi/big"
+)
+
+// Checking for small factors by a single GCD with the product of all primes
+// below the bound. That product is computed modulo N, so it never grows
+// beyond a few words more than N, which keeps this fast for bounds of 2^16
+// and well beyond.
+
+var smallPrimesLock sync.Mutex
+var smallPrimesBound int
+var smallPrimes []uint64
+
+// The primes below bound, sieved once for the largest bound asked for so far.
+// The result is shared, so should not be modified.
+func SmallPrimes(bound int) []uint64 {
+	smallPrimesLock.Lock()
+	defer smallPrimesLock.Unlock()
+
+	if bound > smallPrimesBound {
+		composite := make([]bool, bound)
+		primes := []uint64{}
+		for i := 2; i < bound; i++ {
+			if composite[i] {
+				continue
+			}
+			primes = append(primes, uint64(i))
+			for j := i * i; j < bound; j += i {
+				composite[j] = true
+			}
+		}
+		smallPrimes = primes
+		smallPrimesBound = bound
+	}
+
+	// Callers only get the primes below their own bound
+	end := sort.Search(len(smallPrimes), func(i int) bool { return smallPrimes[i] >= uint64(bound) })
+	return smallPrimes[:end]
+}
+
+// Whether N has a factor larger than 1 below bound
+func HasSmallFactor(N *big.Int, bound int) bool {
+	if N.Sign() <= 0 {
+		return true
+	}
+
+	var acc, tmp big.Int
+	acc.SetUint64(1)
+	word := uint64(1)
+	for _, p := range SmallPrimes(bound) {
+		if hi, _ := bits.Mul64(word, p); hi != 0 {
+			tmp.SetUint64(word)
+			acc.Mul(&acc, &tmp)
+			if acc.BitLen() > 2*N.BitLen() {
+				acc.Mod(&acc, N)
+			}
+			word = 1
+		}
+		word *= p
+	}
+	tmp.SetUint64(word)
+	acc.Mul(&acc, &tmp)
+
+	return tmp.GCD(nil, nil, &acc, N).Cmp(bigONE) != 0
+}
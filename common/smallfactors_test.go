@@ -0,0 +1,90 @@
+package common
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/privacybydesign/gabi/big"
+)
+
+// Trial division by every integer below the bound, as was done before
+func hasSmallFactorNaive(N *big.Int, bound int) bool {
+	for i := 2; i < bound; i++ {
+		if new(big.Int).GCD(nil, nil, N, big.NewInt(int64(i))).Cmp(big.NewInt(1)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSmallPrimes(t *testing.T) {
+	primes := SmallPrimes(30)
+	expected := []uint64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29}
+	if len(primes) != len(expected) {
+		t.Fatalf("Incorrect primes below 30: %v", primes)
+	}
+	for i, p := range expected {
+		if primes[i] != p {
+			t.Errorf("Incorrect primes below 30: %v", primes)
+		}
+	}
+
+	// After sieving further, smaller bounds should still be honored
+	if len(SmallPrimes(1<<16)) != 6542 {
+		t.Error("Incorrect number of primes below 2^16")
+	}
+	if len(SmallPrimes(29)) != 9 {
+		t.Error("Prime equal to bound included")
+	}
+}
+
+func TestHasSmallFactor(t *testing.T) {
+	var l big.Int
+	l.Lsh(big.NewInt(1), 2048)
+	for i := 0; i < 20; i++ {
+		N := new(big.Int).Rand(rnd, &l)
+		if HasSmallFactor(N, 1024) != hasSmallFactorNaive(N, 1024) {
+			t.Errorf("Disagreement with trial division for %v", N)
+		}
+	}
+
+	// 65521 is the largest prime below 2^16, 65537 the smallest above
+	N := new(big.Int).Mul(big.NewInt(65537), big.NewInt(65539))
+	if HasSmallFactor(N, 1<<16) {
+		t.Error("Small factor found in product of primes above the bound")
+	}
+	N.Mul(N, big.NewInt(65521))
+	if !HasSmallFactor(N, 1<<16) {
+		t.Error("Missed factor just below the bound")
+	}
+	if HasSmallFactor(N, 65521) {
+		t.Error("Factor equal to bound counted as small")
+	}
+	if HasSmallFactor(big.NewInt(1), 1024) || !HasSmallFactor(big.NewInt(0), 1024) {
+		t.Error("Incorrect result for 0 or 1")
+	}
+}
+
+func benchmarkHasSmallFactor(b *testing.B, bound int, naive bool) {
+	// Without small factors, so trial division can't stop early
+	prime, err := rand.Prime(rand.Reader, 2048)
+	if err != nil {
+		b.Fatal(err)
+	}
+	N := big.Convert(prime)
+	SmallPrimes(bound)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if naive {
+			hasSmallFactorNaive(N, bound)
+		} else {
+			HasSmallFactor(N, bound)
+		}
+	}
+}
+
+func BenchmarkHasSmallFactor1024(b *testing.B)       { benchmarkHasSmallFactor(b, 1024, false) }
+func BenchmarkHasSmallFactor65536(b *testing.B)      { benchmarkHasSmallFactor(b, 1<<16, false) }
+func BenchmarkHasSmallFactorNaive1024(b *testing.B)  { benchmarkHasSmallFactor(b, 1024, true) }
+func BenchmarkHasSmallFactorNaive65536(b *testing.B) { benchmarkHasSmallFactor(b, 1<<16, true) }
@@ -6,11 +6,13 @@ import (
 	"github.com/privacybydesign/keyproof/primeproofs"
 
 	"encoding/json"
+	"encoding/xml"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"runtime/pprof"
+	"strconv"
 	"time"
 )
 
@@ -146,41 +148,143 @@ func StartLogFollower() *LogFollower {
 
 func printHelp() {
 	fmt.Printf("Usage: keyproof [action] [keyfile(s)] [prooffile]\n")
-	fmt.Printf("Possible actions: buildproof, verify\n")
+	fmt.Printf("Possible actions: buildproof, verify, buildfamilyproof, verifyfamily, searchprimes\n")
+	fmt.Printf("Usage for buildfamilyproof: keyproof buildfamilyproof [prooffile] [publickey] [privatekey] ...\n")
+	fmt.Printf("Usage for verifyfamily: keyproof verifyfamily [prooffile] [publickey] ...\n")
+	fmt.Printf("Usage for searchprimes: keyproof searchprimes [minsize] [maxsize] [tablefile]\n")
 }
 
-func buildProof(pkfilename, skfilename, prooffilename string) {
+func searchPrimes(minsizeArg, maxsizeArg, tablefilename string) {
+	minsize, err := strconv.Atoi(minsizeArg)
+	if err != nil {
+		fmt.Printf("Invalid minimum size: %s\n", err.Error())
+		return
+	}
+	maxsize, err := strconv.Atoi(maxsizeArg)
+	if err != nil {
+		fmt.Printf("Invalid maximum size: %s\n", err.Error())
+		return
+	}
+
+	// Open table file for writing
+	tableFile, err := os.Create(tablefilename)
+	if err != nil {
+		fmt.Printf("Error opening table file for writing: %s\n", err.Error())
+		return
+	}
+	defer tableFile.Close()
+
+	table, err := primeproofs.SearchConvenientSafePrimeTable(minsize, maxsize)
+	if err != nil {
+		follower.FinalEvents <- SetFinalMessage{fmt.Sprintf("Error searching safe primes: %s", err.Error())}
+		return
+	}
+
+	follower.StepStart("Writing table", 0)
+	primeproofs.WriteConvenientSafePrimeTable(tableFile, table)
+	follower.StepDone()
+}
+
+// Without a convenient group prime one is generated while building, which
+// can take very long for large keys
+func warnGroupPrime(err error) {
+	if err != nil {
+		fmt.Printf("Warning: %s, generating a group prime may take very long\n", err.Error())
+	}
+}
+
+func loadPrimeTable(tablefilename string) error {
+	tableFile, err := os.Open(tablefilename)
+	if err != nil {
+		return err
+	}
+	defer tableFile.Close()
+
+	table, err := primeproofs.ReadConvenientSafePrimeTable(tableFile)
+	if err != nil {
+		return err
+	}
+	return primeproofs.RegisterConvenientSafePrimes(table)
+}
+
+func loadGroupCache(cachefilename string) error {
+	cacheFile, err := os.Open(cachefilename)
+	if os.IsNotExist(err) {
+		return nil // Will be created after use
+	}
+	if err != nil {
+		return err
+	}
+	defer cacheFile.Close()
+	return primeproofs.ReadGroupCache(cacheFile)
+}
+
+func saveGroupCache(cachefilename string) error {
+	cacheFile, err := os.Create(cachefilename)
+	if err != nil {
+		return err
+	}
+	defer cacheFile.Close()
+	return primeproofs.WriteGroupCache(cacheFile)
+}
+
+// Read a key pair and check it can be proven, returning P' and Q'
+func readKeyPair(pkfilename, skfilename string) (*gabi.PublicKey, *big.Int, *big.Int, bool) {
 	// Try to read public key
 	pk, err := gabi.NewPublicKeyFromFile(pkfilename)
 	if err != nil {
 		fmt.Printf("Error reading in public key: %s", err.Error())
-		return
+		return nil, nil, nil, false
 	}
 
 	// Try to read private key
 	sk, err := gabi.NewPrivateKeyFromFile(skfilename)
 	if err != nil {
 		fmt.Printf("Error reading in private key: %s\n", err.Error())
-		return
+		return nil, nil, nil, false
+	}
+
+	if !checkKeyPrimes(pk.N, sk.P, sk.Q, sk.PPrime, sk.QPrime) {
+		return nil, nil, nil, false
 	}
 
+	return pk, sk.PPrime, sk.QPrime, true
+}
+
+// Check N = PQ for P = 2P'+1 and Q = 2Q'+1 that can be proven
+func checkKeyPrimes(N, P, Q, PPrime, QPrime *big.Int) bool {
 	// Validate that they match
-	if pk.N.Cmp(new(big.Int).Mul(sk.P, sk.Q)) != 0 {
+	if P == nil || Q == nil || N.Cmp(new(big.Int).Mul(P, Q)) != 0 {
 		fmt.Printf("Private and public key do not match\n")
-		return
+		return false
+	}
+	if PPrime == nil || QPrime == nil ||
+		P.Cmp(new(big.Int).Add(new(big.Int).Lsh(PPrime, 1), big.NewInt(1))) != 0 ||
+		Q.Cmp(new(big.Int).Add(new(big.Int).Lsh(QPrime, 1), big.NewInt(1))) != 0 {
+		fmt.Printf("Private key primes not of the form 2P'+1\n")
+		return false
 	}
 
 	// Validate that it is amenable
 	ConstEight := big.NewInt(8)
 	ConstOne := big.NewInt(1)
-	PMod := new(big.Int).Mod(sk.P, ConstEight)
-	QMod := new(big.Int).Mod(sk.Q, ConstEight)
-	PPrimeMod := new(big.Int).Mod(sk.PPrime, ConstEight)
-	QPrimeMod := new(big.Int).Mod(sk.QPrime, ConstEight)
+	PMod := new(big.Int).Mod(P, ConstEight)
+	QMod := new(big.Int).Mod(Q, ConstEight)
+	PPrimeMod := new(big.Int).Mod(PPrime, ConstEight)
+	QPrimeMod := new(big.Int).Mod(QPrime, ConstEight)
 	if PMod.Cmp(ConstOne) == 0 || QMod.Cmp(ConstOne) == 0 ||
 		PPrimeMod.Cmp(ConstOne) == 0 || QPrimeMod.Cmp(ConstOne) == 0 ||
 		PMod.Cmp(QMod) == 0 || PPrimeMod.Cmp(QPrimeMod) == 0 {
 		fmt.Printf("Private key not amenable to proving\n")
+		return false
+	}
+
+	return true
+}
+
+func buildProof(pkfilename, skfilename, prooffilename string) {
+	pk, pprime, qprime, ok := readKeyPair(pkfilename, skfilename)
+	if !ok {
 		return
 	}
 
@@ -192,15 +296,41 @@ func buildProof(pkfilename, skfilename, prooffilename string) {
 	}
 	defer proofFile.Close()
 
+	// Read the discrete logs of Z and the R_i, if the key generator kept them
+	var logs []*big.Int
+	if *discretelogs != "" {
+		logs, err = readDiscreteLogs(*discretelogs)
+		if err != nil {
+			fmt.Printf("Error reading in discrete logs: %s\n", err.Error())
+			return
+		}
+	}
+
 	// Build the proof
-	s := primeproofs.NewValidKeyProofStructure(pk.N, pk.Z, pk.S, pk.R)
-	proof := s.BuildProof(sk.PPrime, sk.QPrime)
+	s := primeproofs.NewValidKeyProofStructure(pk.N, pk.Z, pk.S, pk.R, primeproofs.WithSecurityParams(*securityParams))
+	warnGroupPrime(s.CheckGroupPrime())
+	proof := s.BuildProofWithDiscreteLogs(pprime, qprime, logs)
 
 	// And write it to file
 	follower.StepStart("Writing proof", 0)
 	proofEncoder := json.NewEncoder(proofFile)
 	proofEncoder.Encode(proof)
 	follower.StepDone()
+
+	follower.FinalEvents <- SetFinalMessage{fmt.Sprintf("This proof gives %s", s.SecurityEstimate())}
+}
+
+// Read the discrete logs of Z and the R_i to base S, in that order, as a JSON
+// array of integers
+func readDiscreteLogs(filename string) ([]*big.Int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var logs []*big.Int
+	err = json.NewDecoder(file).Decode(&logs)
+	return logs, err
 }
 
 func verifyProof(pkfilename, prooffilename string) {
@@ -230,20 +360,295 @@ func verifyProof(pkfilename, prooffilename string) {
 	}
 	follower.StepDone()
 
-	// Construct proof structure
-	s := primeproofs.NewValidKeyProofStructure(pk.N, pk.Z, pk.S, pk.R)
+	// Construct proof structure with the parameters the proof was built with
+	params, ok := proofSecurityParams(proof.SecurityParams)
+	if !ok {
+		follower.FinalEvents <- SetFinalMessage{fmt.Sprintf("Proof uses unknown security parameter set %s", params)}
+		return
+	}
+	s := primeproofs.NewValidKeyProofStructure(pk.N, pk.Z, pk.S, pk.R, verifierOptions(params)...)
+
+	// Check it is strong enough for our policy
+	estimate := s.SecurityEstimate()
+	if !estimate.Meets(*minSoundness, *minZeroKnowledge) {
+		follower.FinalEvents <- SetFinalMessage{fmt.Sprintf("Proof parameters too weak, proof gives %s", estimate)}
+		return
+	}
 
 	// And use it to validate the proof
 	if !s.VerifyProof(proof) {
 		follower.FinalEvents <- SetFinalMessage{"Proof is INVALID!"}
 	} else {
-		follower.FinalEvents <- SetFinalMessage{"Proof is valid"}
+		follower.FinalEvents <- SetFinalMessage{fmt.Sprintf("Proof is valid, proof gives %s", estimate)}
+	}
+}
+
+// Revocation accumulator keys. The gabi version used here predates its
+// revocation support, so these only contain what the proofs need.
+type revocationPublicKey struct {
+	XMLName xml.Name `xml:"RevocationPublicKey"`
+	Counter uint     `xml:"Counter"`
+	N       *big.Int `xml:"Elements>N"`
+	G       *big.Int `xml:"Elements>G"`
+	H       *big.Int `xml:"Elements>H"`
+}
+
+type revocationPrivateKey struct {
+	XMLName xml.Name `xml:"RevocationPrivateKey"`
+	Counter uint     `xml:"Counter"`
+	P       *big.Int `xml:"Elements>P"`
+	Q       *big.Int `xml:"Elements>Q"`
+}
+
+func readXMLFile(filename string, v interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return xml.NewDecoder(file).Decode(v)
+}
+
+func readRevocationPublicKey(filename string) (*revocationPublicKey, error) {
+	var pk revocationPublicKey
+	if err := readXMLFile(filename, &pk); err != nil {
+		return nil, err
+	}
+	if pk.N == nil || pk.G == nil || pk.H == nil {
+		return nil, fmt.Errorf("missing key elements")
+	}
+	return &pk, nil
+}
+
+func buildRevocationProof(pkfilename, skfilename, prooffilename string) {
+	// Try to read keys
+	pk, err := readRevocationPublicKey(pkfilename)
+	if err != nil {
+		fmt.Printf("Error reading in revocation public key: %s\n", err.Error())
+		return
+	}
+	var sk revocationPrivateKey
+	if err := readXMLFile(skfilename, &sk); err != nil {
+		fmt.Printf("Error reading in revocation private key: %s\n", err.Error())
+		return
+	}
+	if sk.P == nil || sk.Q == nil {
+		fmt.Printf("Private and public key do not match\n")
+		return
+	}
+	pprime := new(big.Int).Rsh(sk.P, 1)
+	qprime := new(big.Int).Rsh(sk.Q, 1)
+	if !checkKeyPrimes(pk.N, sk.P, sk.Q, pprime, qprime) {
+		return
+	}
+
+	// Open proof file for writing
+	proofFile, err := os.Create(prooffilename)
+	if err != nil {
+		fmt.Printf("Error opening proof file for writing: %s\n", err.Error())
+		return
+	}
+	defer proofFile.Close()
+
+	// Build the proof
+	s := primeproofs.NewRevocationKeyProofStructure(pk.N, pk.G, pk.H, primeproofs.WithSecurityParams(*securityParams))
+	warnGroupPrime(s.CheckGroupPrime())
+	proof := s.BuildProof(pprime, qprime)
+
+	// And write it to file
+	follower.StepStart("Writing proof", 0)
+	proofEncoder := json.NewEncoder(proofFile)
+	proofEncoder.Encode(proof)
+	follower.StepDone()
+
+	follower.FinalEvents <- SetFinalMessage{fmt.Sprintf("This proof gives %s", s.SecurityEstimate())}
+}
+
+func verifyRevocationProof(pkfilename, prooffilename string) {
+	// Try to read public key
+	pk, err := readRevocationPublicKey(pkfilename)
+	if err != nil {
+		fmt.Printf("Error reading in revocation public key: %s\n", err.Error())
+		return
+	}
+
+	// Try to read proof
+	follower.StepStart("Reading proofdata", 0)
+	proofFile, err := os.Open(prooffilename)
+	if err != nil {
+		follower.StepDone()
+		follower.FinalEvents <- SetFinalMessage{fmt.Sprintf("Error opening proof: %s\n", err.Error())}
+		return
+	}
+	defer proofFile.Close()
+	proofDecoder := json.NewDecoder(proofFile)
+	var proof primeproofs.RevocationKeyProof
+	err = proofDecoder.Decode(&proof)
+	if err != nil {
+		follower.StepDone()
+		follower.FinalEvents <- SetFinalMessage{fmt.Sprintf("Error reading in proof data: %s\n", err.Error())}
+		return
+	}
+	follower.StepDone()
+
+	// Construct proof structure with the parameters the proof was built with
+	params, ok := proofSecurityParams(proof.SecurityParams)
+	if !ok {
+		follower.FinalEvents <- SetFinalMessage{fmt.Sprintf("Proof uses unknown security parameter set %s", params)}
+		return
+	}
+	s := primeproofs.NewRevocationKeyProofStructure(pk.N, pk.G, pk.H,
+		primeproofs.WithSecurityParams(params),
+		primeproofs.WithVerifierPolicy(primeproofs.VerifierPolicy{
+			RequireConvenientPrime: *requireConvenientPrime,
+		}))
+
+	// Check it is strong enough for our policy
+	estimate := s.SecurityEstimate()
+	if !estimate.Meets(*minSoundness, *minZeroKnowledge) {
+		follower.FinalEvents <- SetFinalMessage{fmt.Sprintf("Proof parameters too weak, proof gives %s", estimate)}
+		return
+	}
+
+	// And use it to validate the proof
+	if !s.VerifyProof(proof) {
+		follower.FinalEvents <- SetFinalMessage{"Proof is INVALID!"}
+	} else {
+		follower.FinalEvents <- SetFinalMessage{fmt.Sprintf("Proof is valid, proof gives %s", estimate)}
+	}
+}
+
+// Name of the security parameter set recorded in a proof, and whether it is known
+func proofSecurityParams(name string) (string, bool) {
+	if name == "" {
+		name = primeproofs.LegacySecurityParams.Name
+	}
+	_, ok := primeproofs.GetSecurityParams(name)
+	return name, ok
+}
+
+// Options for verifying a proof built with the given parameter set, following
+// the command line flags
+func verifierOptions(params string) []primeproofs.ValidKeyProofOption {
+	return []primeproofs.ValidKeyProofOption{
+		primeproofs.WithSecurityParams(params),
+		primeproofs.WithVerifierPolicy(primeproofs.VerifierPolicy{
+			RequireConvenientPrime:  *requireConvenientPrime,
+			RequireDiscreteLogProof: *requireDiscreteLogs,
+		}),
+	}
+}
+
+func buildFamilyProof(prooffilename string, keyfilenames []string) {
+	if *discretelogs != "" {
+		fmt.Printf("Discrete logs are not supported for key families\n")
+		return
+	}
+
+	var keys []primeproofs.ValidKeyProofStructure
+	var pprimes, qprimes []*big.Int
+	for i := 0; i < len(keyfilenames); i += 2 {
+		pk, pprime, qprime, ok := readKeyPair(keyfilenames[i], keyfilenames[i+1])
+		if !ok {
+			return
+		}
+		keys = append(keys, primeproofs.NewValidKeyProofStructure(pk.N, pk.Z, pk.S, pk.R, primeproofs.WithSecurityParams(*securityParams)))
+		pprimes = append(pprimes, pprime)
+		qprimes = append(qprimes, qprime)
+	}
+
+	// Open proof file for writing
+	proofFile, err := os.Create(prooffilename)
+	if err != nil {
+		fmt.Printf("Error opening proof file for writing: %s\n", err.Error())
+		return
+	}
+	defer proofFile.Close()
+
+	// Build the proof
+	s := primeproofs.NewKeyFamilyProofStructure(keys...)
+	warnGroupPrime(s.CheckGroupPrime())
+	proof := s.BuildProof(pprimes, qprimes)
+
+	// And write it to file
+	follower.StepStart("Writing proof", 0)
+	proofEncoder := json.NewEncoder(proofFile)
+	proofEncoder.Encode(proof)
+	follower.StepDone()
+
+	follower.FinalEvents <- SetFinalMessage{fmt.Sprintf("This proof gives %s", s.SecurityEstimate())}
+}
+
+func verifyFamilyProof(prooffilename string, pkfilenames []string) {
+	// Try to read public keys
+	var pks []*gabi.PublicKey
+	for _, pkfilename := range pkfilenames {
+		pk, err := gabi.NewPublicKeyFromFile(pkfilename)
+		if err != nil {
+			fmt.Printf("Error reading in public key: %s\n", err.Error())
+			return
+		}
+		pks = append(pks, pk)
+	}
+
+	// Try to read proof
+	follower.StepStart("Reading proofdata", 0)
+	proofFile, err := os.Open(prooffilename)
+	if err != nil {
+		follower.StepDone()
+		follower.FinalEvents <- SetFinalMessage{fmt.Sprintf("Error opening proof: %s\n", err.Error())}
+		return
+	}
+	defer proofFile.Close()
+	proofDecoder := json.NewDecoder(proofFile)
+	var proof primeproofs.KeyFamilyProof
+	err = proofDecoder.Decode(&proof)
+	if err != nil {
+		follower.StepDone()
+		follower.FinalEvents <- SetFinalMessage{fmt.Sprintf("Error reading in proof data: %s\n", err.Error())}
+		return
+	}
+	follower.StepDone()
+
+	// Construct proof structure with the parameters the proof was built with
+	params, ok := proofSecurityParams(proof.SecurityParams)
+	if !ok {
+		follower.FinalEvents <- SetFinalMessage{fmt.Sprintf("Proof uses unknown security parameter set %s", params)}
+		return
+	}
+	var keys []primeproofs.ValidKeyProofStructure
+	for _, pk := range pks {
+		keys = append(keys, primeproofs.NewValidKeyProofStructure(pk.N, pk.Z, pk.S, pk.R, verifierOptions(params)...))
+	}
+	s := primeproofs.NewKeyFamilyProofStructure(keys...)
+
+	// Check it is strong enough for our policy
+	estimate := s.SecurityEstimate()
+	if !estimate.Meets(*minSoundness, *minZeroKnowledge) {
+		follower.FinalEvents <- SetFinalMessage{fmt.Sprintf("Proof parameters too weak, proof gives %s", estimate)}
+		return
+	}
+
+	// And use it to validate the proof
+	if !s.VerifyProof(proof) {
+		follower.FinalEvents <- SetFinalMessage{"Proof is INVALID!"}
+	} else {
+		follower.FinalEvents <- SetFinalMessage{fmt.Sprintf("Proof is valid, proof gives %s", estimate)}
 	}
 }
 
 var follower *LogFollower
 
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
+var primetable = flag.String("primetable", "", "load additional convenient safe primes from file")
+var groupcache = flag.String("groupcache", "", "load precomputed groups from, and save them to, file")
+var minSoundness = flag.Float64("minsoundness", 0, "minimum soundness in bits required of verified proofs")
+var minZeroKnowledge = flag.Float64("minzeroknowledge", 0, "minimum zero-knowledge in bits required of verified proofs")
+var requireConvenientPrime = flag.Bool("requireconvenientprime", false, "only accept proofs using a group prime from the convenient safe prime tables")
+var discretelogs = flag.String("discretelogs", "", "also prove knowledge of the discrete logs of Z and the R_i to base S, read as JSON array from file")
+var revocation = flag.Bool("revocation", false, "prove or verify a revocation accumulator key instead of an issuer key")
+var requireDiscreteLogs = flag.Bool("requirediscretelogs", false, "only accept proofs including the discrete logs of Z and the R_i")
+var securityParams = flag.String("securityparams", primeproofs.LegacySecurityParams.Name, "name of the security parameter set to build proofs with, verification uses the set recorded in the proof")
 
 func main() {
 	flag.Parse()
@@ -262,6 +667,30 @@ func main() {
 		return
 	}
 
+	if _, ok := primeproofs.GetSecurityParams(*securityParams); !ok {
+		fmt.Printf("Unknown security parameter set %s\n", *securityParams)
+		return
+	}
+
+	if *primetable != "" {
+		if err := loadPrimeTable(*primetable); err != nil {
+			fmt.Printf("Error loading prime table: %s\n", err.Error())
+			return
+		}
+	}
+
+	if *groupcache != "" {
+		if err := loadGroupCache(*groupcache); err != nil {
+			fmt.Printf("Error loading group cache: %s\n", err.Error())
+			return
+		}
+		defer func() {
+			if err := saveGroupCache(*groupcache); err != nil {
+				fmt.Printf("Error saving group cache: %s\n", err.Error())
+			}
+		}()
+	}
+
 	follower = StartLogFollower()
 	defer func() {
 		follower.QuitEvents <- QuitMessage{}
@@ -273,6 +702,10 @@ func main() {
 			printHelp()
 			return
 		}
+		if *revocation {
+			buildRevocationProof(flag.Arg(1), flag.Arg(2), flag.Arg(3))
+			return
+		}
 		buildProof(flag.Arg(1), flag.Arg(2), flag.Arg(3))
 		return
 	}
@@ -281,9 +714,37 @@ func main() {
 			printHelp()
 			return
 		}
+		if *revocation {
+			verifyRevocationProof(flag.Arg(1), flag.Arg(2))
+			return
+		}
 		verifyProof(flag.Arg(1), flag.Arg(2))
 		return
 	}
+	if flag.Arg(0) == "buildfamilyproof" {
+		if len(flag.Args()) < 4 || len(flag.Args())%2 != 0 {
+			printHelp()
+			return
+		}
+		buildFamilyProof(flag.Arg(1), flag.Args()[2:])
+		return
+	}
+	if flag.Arg(0) == "verifyfamily" {
+		if len(flag.Args()) < 3 {
+			printHelp()
+			return
+		}
+		verifyFamilyProof(flag.Arg(1), flag.Args()[2:])
+		return
+	}
+	if flag.Arg(0) == "searchprimes" {
+		if len(flag.Args()) != 4 {
+			printHelp()
+			return
+		}
+		searchPrimes(flag.Arg(1), flag.Arg(2), flag.Arg(3))
+		return
+	}
 
 	printHelp()
 }
@@ -62,7 +62,11 @@ func (c *additionProofCommit) getRandomizer(name string) *big.Int {
 	return nil
 }
 
-func newAdditionProofStructure(a1, a2, mod, result string, l uint) additionProofStructure {
+func (c *additionProofCommit) secretNames() []string {
+	return []string{c.nameMod, c.nameHider}
+}
+
+func newAdditionProofStructure(a1, a2, mod, result string, l uint, params *SecurityParams) additionProofStructure {
 	var structure additionProofStructure
 	structure.a1 = a1
 	structure.a2 = a2
@@ -85,6 +89,8 @@ func newAdditionProofStructure(a1, a2, mod, result string, l uint) additionProof
 		strings.Join([]string{structure.myname, "mod"}, "_"),
 		0,
 		l,
+		params,
+		nil,
 	}
 	return structure
 }
@@ -97,12 +103,26 @@ func (s *additionProofStructure) numCommitments() int {
 	return s.addRepresentation.numCommitments() + s.addRange.numCommitments()
 }
 
-func (s *additionProofStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, additionProofCommit) {
+// A commit carrying only the names of its secrets
+func (s *additionProofStructure) newCommit() additionProofCommit {
 	var commit additionProofCommit
-
-	// Generate needed commit data
 	commit.nameMod = strings.Join([]string{s.myname, "mod"}, "_")
 	commit.nameHider = strings.Join([]string{s.myname, "hider"}, "_")
+	return commit
+}
+
+func (s *additionProofStructure) collectNames(t *nameTracker) {
+	commit := s.newCommit()
+	t.provideSecrets(&commit)
+	t.usePedersonSecret(s.a1, s.a2, s.mod, s.result)
+	s.addRepresentation.collectNames(t)
+	s.addRange.collectNames(t)
+}
+
+func (s *additionProofStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, additionProofCommit) {
+	commit := s.newCommit()
+
+	// Generate needed commit data
 	commit.modAdd = new(big.Int).Div(
 		new(big.Int).Sub(
 			secretdata.getSecret(s.result),
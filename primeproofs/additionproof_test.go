@@ -26,7 +26,7 @@ func TestAdditionProofFlow(t *testing.T) {
 	bases := newBaseMerge(&g, &a1, &a2, &mod, &result)
 	secrets := newSecretMerge(&a1, &a2, &mod, &result)
 
-	s := newAdditionProofStructure("a1", "a2", "mod", "result", 3)
+	s := newAdditionProofStructure("a1", "a2", "mod", "result", 3, &LegacySecurityParams)
 	if !s.isTrue(&secrets) {
 		t.Error("Incorrectly assessed proof setup as incorrect.")
 	}
@@ -82,7 +82,7 @@ func TestAdditionProofVerifyStructure(t *testing.T) {
 	proof.ModAddResult = big.NewInt(1)
 	proof.HiderResult = big.NewInt(1)
 
-	s := newAdditionProofStructure("a1", "a2", "mod", "result", 3)
+	s := newAdditionProofStructure("a1", "a2", "mod", "result", 3, &LegacySecurityParams)
 	if s.verifyProofStructure(proof) {
 		t.Error("Accepting missing rangeproof.\n")
 	}
@@ -107,7 +107,7 @@ func TestAdditionProofFake(t *testing.T) {
 		return
 	}
 
-	s := newAdditionProofStructure("a1", "a2", "mod", "result", 3)
+	s := newAdditionProofStructure("a1", "a2", "mod", "result", 3, &LegacySecurityParams)
 
 	proof := s.fakeProof(g)
 
@@ -123,7 +123,7 @@ func TestAdditionProofJSON(t *testing.T) {
 		return
 	}
 
-	s := newAdditionProofStructure("a1", "a2", "mod", "result", 3)
+	s := newAdditionProofStructure("a1", "a2", "mod", "result", 3, &LegacySecurityParams)
 
 	proofBefore := s.fakeProof(g)
 
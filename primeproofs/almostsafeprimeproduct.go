@@ -15,7 +15,7 @@ type almostSafePrimeProductCommit struct {
 	logs        []*big.Int
 }
 
-func almostSafePrimeProductBuildCommitments(list []*big.Int, Pprime *big.Int, Qprime *big.Int) ([]*big.Int, almostSafePrimeProductCommit) {
+func almostSafePrimeProductBuildCommitments(list []*big.Int, Pprime *big.Int, Qprime *big.Int, params *SecurityParams) ([]*big.Int, almostSafePrimeProductCommit) {
 	// Setup proof structure
 	var commit almostSafePrimeProductCommit
 	commit.commitments = []*big.Int{}
@@ -26,10 +26,10 @@ func almostSafePrimeProductBuildCommitments(list []*big.Int, Pprime *big.Int, Qp
 	phiN := new(big.Int).Lsh(new(big.Int).Mul(Pprime, Qprime), 2)
 
 	// Generate nonce
-	nonceMax := new(big.Int).Lsh(big.NewInt(1), almostSafePrimeProductNonceSize)
+	nonceMax := new(big.Int).Lsh(big.NewInt(1), params.AlmostSafePrimeProductNonceSize)
 	commit.nonce = common.RandomBigInt(nonceMax)
 
-	for i := 0; i < almostSafePrimeProductIters; i++ {
+	for i := 0; i < params.AlmostSafePrimeProductIters; i++ {
 		// Calculate base from nonce
 		curc := common.GetHashNumber(commit.nonce, nil, i, uint(N.BitLen()))
 		curc.Mod(curc, N)
@@ -48,7 +48,7 @@ func almostSafePrimeProductBuildCommitments(list []*big.Int, Pprime *big.Int, Qp
 	return list, commit
 }
 
-func almostSafePrimeProductBuildProof(Pprime *big.Int, Qprime *big.Int, challenge *big.Int, index *big.Int, commit almostSafePrimeProductCommit) AlmostSafePrimeProductProof {
+func almostSafePrimeProductBuildProof(Pprime *big.Int, Qprime *big.Int, challenge *big.Int, index *big.Int, commit almostSafePrimeProductCommit, params *SecurityParams) AlmostSafePrimeProductProof {
 	// Setup proof structure
 	var proof AlmostSafePrimeProductProof
 	proof.Nonce = commit.nonce
@@ -65,7 +65,7 @@ func almostSafePrimeProductBuildProof(Pprime *big.Int, Qprime *big.Int, challeng
 	}
 
 	// Calculate responses
-	for i := 0; i < almostSafePrimeProductIters; i++ {
+	for i := 0; i < params.AlmostSafePrimeProductIters; i++ {
 		// Derive challenge
 		curc := common.GetHashNumber(challenge, index, i, uint(2*N.BitLen()))
 
@@ -99,14 +99,14 @@ func almostSafePrimeProductBuildProof(Pprime *big.Int, Qprime *big.Int, challeng
 	return proof
 }
 
-func almostSafePrimeProductVerifyStructure(proof AlmostSafePrimeProductProof) bool {
+func almostSafePrimeProductVerifyStructure(proof AlmostSafePrimeProductProof, params *SecurityParams) bool {
 	if proof.Nonce == nil {
 		return false
 	}
 	if proof.Commitments == nil || proof.Responses == nil {
 		return false
 	}
-	if len(proof.Commitments) != almostSafePrimeProductIters || len(proof.Responses) != almostSafePrimeProductIters {
+	if len(proof.Commitments) != params.AlmostSafePrimeProductIters || len(proof.Responses) != params.AlmostSafePrimeProductIters {
 		return false
 	}
 
@@ -129,7 +129,7 @@ func almostSafePrimeProductExtractCommitments(list []*big.Int, proof AlmostSafeP
 	return append(list, proof.Commitments...)
 }
 
-func almostSafePrimeProductVerifyProof(N *big.Int, challenge *big.Int, index *big.Int, proof AlmostSafePrimeProductProof) bool {
+func almostSafePrimeProductVerifyProof(N *big.Int, challenge *big.Int, index *big.Int, proof AlmostSafePrimeProductProof, params *SecurityParams) bool {
 	// Verify N=1(mod 3), as this decreases the error prob from 9/10 to 4/5
 	if new(big.Int).Mod(N, big.NewInt(3)).Cmp(big.NewInt(1)) != 0 {
 		return false
@@ -139,7 +139,7 @@ func almostSafePrimeProductVerifyProof(N *big.Int, challenge *big.Int, index *bi
 	gamma := new(big.Int).Lsh(big.NewInt(1), uint(N.BitLen()))
 
 	// Check responses
-	for i := 0; i < almostSafePrimeProductIters; i++ {
+	for i := 0; i < params.AlmostSafePrimeProductIters; i++ {
 		// Generate base
 		base := common.GetHashNumber(proof.Nonce, nil, i, uint(N.BitLen()))
 		base.Mod(base, N)
@@ -6,14 +6,14 @@ import "github.com/privacybydesign/gabi/big"
 func TestAlmostSafePrimeProductCycle(t *testing.T) {
 	const p = 13451
 	const q = 13901
-	listBefore, commit := almostSafePrimeProductBuildCommitments([]*big.Int{}, big.NewInt(p), big.NewInt(q))
-	proof := almostSafePrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(3), commit)
-	if !almostSafePrimeProductVerifyStructure(proof) {
+	listBefore, commit := almostSafePrimeProductBuildCommitments([]*big.Int{}, big.NewInt(p), big.NewInt(q), &LegacySecurityParams)
+	proof := almostSafePrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(3), commit, &LegacySecurityParams)
+	if !almostSafePrimeProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Proof structure rejected")
 		return
 	}
 	listAfter := almostSafePrimeProductExtractCommitments([]*big.Int{}, proof)
-	ok := almostSafePrimeProductVerifyProof(big.NewInt((2*p+1)*(2*q+1)), big.NewInt(12345), big.NewInt(3), proof)
+	ok := almostSafePrimeProductVerifyProof(big.NewInt((2*p+1)*(2*q+1)), big.NewInt(12345), big.NewInt(3), proof, &LegacySecurityParams)
 	if !ok {
 		t.Error("AlmostSafePrimeProduct rejected")
 	}
@@ -30,10 +30,10 @@ func TestAlmostSafePrimeProductCycle(t *testing.T) {
 func TestAlmostSafePrimeProductCycleIncorrectNonce(t *testing.T) {
 	const p = 13451
 	const q = 13901
-	_, commit := almostSafePrimeProductBuildCommitments([]*big.Int{}, big.NewInt(p), big.NewInt(q))
-	proof := almostSafePrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(3), commit)
+	_, commit := almostSafePrimeProductBuildCommitments([]*big.Int{}, big.NewInt(p), big.NewInt(q), &LegacySecurityParams)
+	proof := almostSafePrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(3), commit, &LegacySecurityParams)
 	proof.Nonce.Sub(proof.Nonce, big.NewInt(1))
-	ok := almostSafePrimeProductVerifyProof(big.NewInt((2*p+1)*(2*q+1)), big.NewInt(12345), big.NewInt(3), proof)
+	ok := almostSafePrimeProductVerifyProof(big.NewInt((2*p+1)*(2*q+1)), big.NewInt(12345), big.NewInt(3), proof, &LegacySecurityParams)
 	if ok {
 		t.Error("Incorrect AlmostSafePrimeProductProof accepted.")
 	}
@@ -42,10 +42,10 @@ func TestAlmostSafePrimeProductCycleIncorrectNonce(t *testing.T) {
 func TestAlmostSafePrimeProductCycleIncorrectCommitment(t *testing.T) {
 	const p = 13451
 	const q = 13901
-	_, commit := almostSafePrimeProductBuildCommitments([]*big.Int{}, big.NewInt(p), big.NewInt(q))
-	proof := almostSafePrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(3), commit)
+	_, commit := almostSafePrimeProductBuildCommitments([]*big.Int{}, big.NewInt(p), big.NewInt(q), &LegacySecurityParams)
+	proof := almostSafePrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(3), commit, &LegacySecurityParams)
 	proof.Commitments[0].Add(proof.Commitments[0], big.NewInt(1))
-	ok := almostSafePrimeProductVerifyProof(big.NewInt((2*p+1)*(2*q+1)), big.NewInt(12345), big.NewInt(3), proof)
+	ok := almostSafePrimeProductVerifyProof(big.NewInt((2*p+1)*(2*q+1)), big.NewInt(12345), big.NewInt(3), proof, &LegacySecurityParams)
 	if ok {
 		t.Error("Incorrect AlmostSafePrimeProductProof accepted.")
 	}
@@ -54,10 +54,10 @@ func TestAlmostSafePrimeProductCycleIncorrectCommitment(t *testing.T) {
 func TestAlmostSafePrimeProductCycleIncorrectResponse(t *testing.T) {
 	const p = 13451
 	const q = 13901
-	_, commit := almostSafePrimeProductBuildCommitments([]*big.Int{}, big.NewInt(p), big.NewInt(q))
-	proof := almostSafePrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(3), commit)
+	_, commit := almostSafePrimeProductBuildCommitments([]*big.Int{}, big.NewInt(p), big.NewInt(q), &LegacySecurityParams)
+	proof := almostSafePrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(3), commit, &LegacySecurityParams)
 	proof.Responses[0].Add(proof.Responses[0], big.NewInt(1))
-	ok := almostSafePrimeProductVerifyProof(big.NewInt((2*p+1)*(2*q+1)), big.NewInt(12345), big.NewInt(3), proof)
+	ok := almostSafePrimeProductVerifyProof(big.NewInt((2*p+1)*(2*q+1)), big.NewInt(12345), big.NewInt(3), proof, &LegacySecurityParams)
 	if ok {
 		t.Error("Incorrect AlmostSafePrimeProductProof accepted.")
 	}
@@ -66,45 +66,45 @@ func TestAlmostSafePrimeProductCycleIncorrectResponse(t *testing.T) {
 func TestAlmostSafePrimeProductVerifyStructure(t *testing.T) {
 	const p = 13451
 	const q = 13901
-	_, commit := almostSafePrimeProductBuildCommitments([]*big.Int{}, big.NewInt(p), big.NewInt(q))
-	proof := almostSafePrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(3), commit)
+	_, commit := almostSafePrimeProductBuildCommitments([]*big.Int{}, big.NewInt(p), big.NewInt(q), &LegacySecurityParams)
+	proof := almostSafePrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(3), commit, &LegacySecurityParams)
 
 	listBackup := proof.Commitments
 	proof.Commitments = proof.Commitments[:len(proof.Commitments)-1]
-	if almostSafePrimeProductVerifyStructure(proof) {
+	if almostSafePrimeProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Accepiting too short commitments")
 	}
 	proof.Commitments = listBackup
 
 	listBackup = proof.Responses
 	proof.Responses = proof.Responses[:len(proof.Responses)-1]
-	if almostSafePrimeProductVerifyStructure(proof) {
+	if almostSafePrimeProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Accepting too short responses")
 	}
 	proof.Responses = listBackup
 
 	valBackup := proof.Commitments[2]
 	proof.Commitments[2] = nil
-	if almostSafePrimeProductVerifyStructure(proof) {
+	if almostSafePrimeProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Accepting missing commitment")
 	}
 	proof.Commitments[2] = valBackup
 
 	valBackup = proof.Responses[3]
 	proof.Responses[3] = nil
-	if almostSafePrimeProductVerifyStructure(proof) {
+	if almostSafePrimeProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Accepting missing response")
 	}
 	proof.Responses[3] = valBackup
 
 	valBackup = proof.Nonce
 	proof.Nonce = nil
-	if almostSafePrimeProductVerifyStructure(proof) {
+	if almostSafePrimeProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Accepting missing nonce")
 	}
 	proof.Nonce = valBackup
 
-	if !almostSafePrimeProductVerifyStructure(proof) {
+	if !almostSafePrimeProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Testing messed up testdata")
 	}
 }
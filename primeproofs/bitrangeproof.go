@@ -0,0 +1,553 @@
+package primeproofs
+
+import "github.com/privacybydesign/keyproof/common"
+import "github.com/privacybydesign/gabi/big"
+import "strings"
+import "fmt"
+
+// Exact range proof for a pederson committed value x, showing l1 <= x <= l2.
+//
+// Where rangeProofStructure is cheap but only shows x lies roughly within
+// [-2^(l2+rangeProofEpsilon), 2^(l2+rangeProofEpsilon)], this proof decomposes
+// both x - l1 and l2 - x into bits, proves each bit is 0 or 1, and proves
+// the bits add up to the committed value. This is more expensive for large
+// intervals, but gives the exact bounds. Both bounds (and 2^bitlen) need to
+// be small compared to the group order.
+//
+// Exact range proofs use it in place of the binary challenge iterations, see
+// newPedersonExactRangeProofStructure.
+type bitRangeProofStructure struct {
+	rangeSecret string
+	myname      string
+	l1          *big.Int
+	l2          *big.Int
+
+	lower bitDecompositionStructure
+	upper bitDecompositionStructure
+}
+
+type BitRangeProof struct {
+	LowerProof BitDecompositionProof
+	UpperProof BitDecompositionProof
+}
+
+type bitRangeCommit struct {
+	lowerCommit bitDecompositionCommit
+	upperCommit bitDecompositionCommit
+}
+
+// Proves that sign*value + offset equals a committed bitlen-bit number
+type bitDecompositionStructure struct {
+	value  string
+	myname string
+	sign   int64
+	offset *big.Int
+	bitlen uint
+
+	bits  []bitProofStructure
+	eqRep representationProofStructure
+}
+
+type BitDecompositionProof struct {
+	nameEqHider string
+	BitCommits  []PedersonProof
+	BitProofs   []BitProof
+	EqResult    *big.Int
+}
+
+type bitDecompositionCommit struct {
+	nameEqHider       string
+	bitPedersons      []pedersonSecret
+	bitCommits        []bitProofCommit
+	eqHider           *big.Int
+	eqHiderRandomizer *big.Int
+}
+
+// Proves that a pederson commitment opens to either 0 or 1
+type bitProofStructure struct {
+	bitname string
+	zeroRep representationProofStructure
+	oneRep  representationProofStructure
+}
+
+// The challenge of the one branch is the challenge xor ZeroChallenge
+type BitProof struct {
+	nameZero      string
+	nameOne       string
+	ZeroChallenge *big.Int
+	ZeroResult    *big.Int
+	OneResult     *big.Int
+}
+
+type bitProofCommit struct {
+	nameValid       string
+	nameInvalid     string
+	isOne           bool
+	hider           *big.Int
+	hiderRandomizer *big.Int
+	fakeChallenge   *big.Int
+	fakeResult      *big.Int
+}
+
+func newPedersonBitRangeProofStructure(name string, l1, l2 *big.Int) bitRangeProofStructure {
+	var structure bitRangeProofStructure
+	structure.rangeSecret = name
+	structure.myname = strings.Join([]string{name, "bitrange"}, "_")
+	structure.l1 = new(big.Int).Set(l1)
+	structure.l2 = new(big.Int).Set(l2)
+
+	bitlen := uint(new(big.Int).Sub(l2, l1).BitLen())
+	if bitlen == 0 {
+		bitlen = 1
+	}
+	structure.lower = newBitDecompositionStructure(
+		name,
+		strings.Join([]string{structure.myname, "lower"}, "_"),
+		1,
+		new(big.Int).Neg(l1),
+		bitlen)
+	structure.upper = newBitDecompositionStructure(
+		name,
+		strings.Join([]string{structure.myname, "upper"}, "_"),
+		-1,
+		new(big.Int).Set(l2),
+		bitlen)
+	return structure
+}
+
+func (s *bitRangeProofStructure) numRangeProofs() int {
+	return 1
+}
+
+func (s *bitRangeProofStructure) numCommitments() int {
+	return s.lower.numCommitments() + s.upper.numCommitments()
+}
+
+func (s *bitRangeProofStructure) collectNames(t *nameTracker) {
+	s.lower.collectNames(t)
+	s.upper.collectNames(t)
+}
+
+func (s *bitRangeProofStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, bitRangeCommit) {
+	var commit bitRangeCommit
+	list, commit.lowerCommit = s.lower.generateCommitmentsFromSecrets(g, list, bases, secretdata)
+	list, commit.upperCommit = s.upper.generateCommitmentsFromSecrets(g, list, bases, secretdata)
+
+	Follower.Tick()
+
+	return list, commit
+}
+
+func (s *bitRangeProofStructure) buildProof(g group, challenge *big.Int, commit bitRangeCommit, secretdata secretLookup) BitRangeProof {
+	var proof BitRangeProof
+	proof.LowerProof = s.lower.buildProof(g, challenge, commit.lowerCommit, secretdata)
+	proof.UpperProof = s.upper.buildProof(g, challenge, commit.upperCommit, secretdata)
+	return proof
+}
+
+func (s *bitRangeProofStructure) fakeProof(g group) BitRangeProof {
+	var proof BitRangeProof
+	proof.LowerProof = s.lower.fakeProof(g)
+	proof.UpperProof = s.upper.fakeProof(g)
+	return proof
+}
+
+func (s *bitRangeProofStructure) verifyProofStructure(proof BitRangeProof) bool {
+	return s.lower.verifyProofStructure(proof.LowerProof) &&
+		s.upper.verifyProofStructure(proof.UpperProof)
+}
+
+func (s *bitRangeProofStructure) generateCommitmentsFromProof(g group, list []*big.Int, challenge *big.Int, bases baseLookup, proof BitRangeProof) []*big.Int {
+	list = s.lower.generateCommitmentsFromProof(g, list, challenge, bases, proof.LowerProof)
+	list = s.upper.generateCommitmentsFromProof(g, list, challenge, bases, proof.UpperProof)
+
+	Follower.Tick()
+
+	return list
+}
+
+func (s *bitRangeProofStructure) isTrue(secretdata secretLookup) bool {
+	x := secretdata.getSecret(s.rangeSecret)
+	return x.Cmp(s.l1) >= 0 && x.Cmp(s.l2) <= 0
+}
+
+func newBitDecompositionStructure(value, myname string, sign int64, offset *big.Int, bitlen uint) bitDecompositionStructure {
+	var structure bitDecompositionStructure
+	structure.value = value
+	structure.myname = myname
+	structure.sign = sign
+	structure.offset = offset
+	structure.bitlen = bitlen
+
+	structure.bits = []bitProofStructure{}
+	for i := uint(0); i < bitlen; i++ {
+		structure.bits = append(
+			structure.bits,
+			newBitProofStructure(strings.Join([]string{myname, "bit", fmt.Sprintf("%v", i)}, "_")))
+	}
+
+	// value^sign * g^offset * prod bit_i^(-2^i) = h^eqhider
+	structure.eqRep = representationProofStructure{
+		[]lhsContribution{
+			lhsContribution{value, big.NewInt(sign)},
+			lhsContribution{"g", new(big.Int).Set(offset)},
+		},
+		[]rhsContribution{
+			rhsContribution{"h", strings.Join([]string{myname, "eqhider"}, "_"), 1},
+		},
+	}
+	for i := uint(0); i < bitlen; i++ {
+		structure.eqRep.lhs = append(
+			structure.eqRep.lhs,
+			lhsContribution{
+				strings.Join([]string{myname, "bit", fmt.Sprintf("%v", i)}, "_"),
+				new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), i)),
+			})
+	}
+
+	return structure
+}
+
+func (s *bitDecompositionStructure) numCommitments() int {
+	res := int(s.bitlen)
+	for i, _ := range s.bits {
+		res += s.bits[i].numCommitments()
+	}
+	res += s.eqRep.numCommitments()
+	return res
+}
+
+// A commit carrying only the names of its secrets and pederson commitments
+func (s *bitDecompositionStructure) newCommit() bitDecompositionCommit {
+	var commit bitDecompositionCommit
+	commit.nameEqHider = strings.Join([]string{s.myname, "eqhider"}, "_")
+	commit.bitPedersons = []pedersonSecret{}
+	for i := uint(0); i < s.bitlen; i++ {
+		commit.bitPedersons = append(
+			commit.bitPedersons,
+			newPedersonName(strings.Join([]string{s.myname, "bit", fmt.Sprintf("%v", i)}, "_")))
+	}
+	return commit
+}
+
+func (s *bitDecompositionStructure) collectNames(t *nameTracker) {
+	commit := s.newCommit()
+	for i, _ := range commit.bitPedersons {
+		t.providePederson(&commit.bitPedersons[i])
+	}
+	t.provideSecrets(&commit)
+	t.usePedersonSecret(s.value)
+	for i, _ := range s.bits {
+		s.bits[i].collectNames(t)
+	}
+	s.eqRep.collectNames(t)
+}
+
+func (s *bitDecompositionStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, bitDecompositionCommit) {
+	commit := s.newCommit()
+
+	// Calculate the value to decompose
+	d := new(big.Int).Mul(big.NewInt(s.sign), secretdata.getSecret(s.value))
+	d.Add(d, s.offset)
+	if d.Sign() < 0 || uint(d.BitLen()) > s.bitlen {
+		panic("Value outside of range")
+	}
+
+	// Commit to the bits, and derive the hider for the equality proof
+	commit.eqHider = new(big.Int).Mul(
+		big.NewInt(s.sign),
+		secretdata.getSecret(strings.Join([]string{s.value, "hider"}, "_")))
+	commit.eqHiderRandomizer = common.RandomBigInt(g.order)
+	for i := uint(0); i < s.bitlen; i++ {
+		commit.bitPedersons[i] = newPedersonSecret(
+			g,
+			commit.bitPedersons[i].name,
+			big.NewInt(int64(d.Bit(int(i)))))
+		commit.eqHider.Sub(
+			commit.eqHider,
+			new(big.Int).Lsh(commit.bitPedersons[i].hider, i))
+	}
+	commit.eqHider.Mod(commit.eqHider, g.order)
+
+	// Inner bases and secrets
+	baseList := []baseLookup{}
+	secretList := []secretLookup{&commit}
+	for i, _ := range commit.bitPedersons {
+		baseList = append(baseList, &commit.bitPedersons[i])
+		secretList = append(secretList, &commit.bitPedersons[i])
+	}
+	baseList = append(baseList, bases)
+	secretList = append(secretList, secretdata)
+	innerBases := newBaseMerge(baseList...)
+	innerSecrets := newSecretMerge(secretList...)
+
+	// Generate commitments
+	for i, _ := range commit.bitPedersons {
+		list = commit.bitPedersons[i].generateCommitments(list)
+	}
+	commit.bitCommits = make([]bitProofCommit, len(s.bits))
+	for i, _ := range s.bits {
+		list, commit.bitCommits[i] = s.bits[i].generateCommitmentsFromSecrets(g, list, &innerBases, &innerSecrets)
+	}
+	list = s.eqRep.generateCommitmentsFromSecrets(g, list, &innerBases, &innerSecrets)
+
+	return list, commit
+}
+
+func (s *bitDecompositionStructure) buildProof(g group, challenge *big.Int, commit bitDecompositionCommit, secretdata secretLookup) BitDecompositionProof {
+	var proof BitDecompositionProof
+
+	proof.BitCommits = []PedersonProof{}
+	for i, _ := range commit.bitPedersons {
+		proof.BitCommits = append(proof.BitCommits, commit.bitPedersons[i].buildProof(g, challenge))
+	}
+	proof.BitProofs = []BitProof{}
+	for i, _ := range s.bits {
+		proof.BitProofs = append(proof.BitProofs, s.bits[i].buildProof(g, challenge, commit.bitCommits[i]))
+	}
+	proof.EqResult = new(big.Int).Mod(
+		new(big.Int).Sub(
+			commit.eqHiderRandomizer,
+			new(big.Int).Mul(
+				challenge,
+				commit.eqHider)),
+		g.order)
+
+	return proof
+}
+
+func (s *bitDecompositionStructure) fakeProof(g group) BitDecompositionProof {
+	var proof BitDecompositionProof
+
+	proof.BitCommits = []PedersonProof{}
+	proof.BitProofs = []BitProof{}
+	for i, _ := range s.bits {
+		proof.BitCommits = append(proof.BitCommits, newPedersonFakeProof(g))
+		proof.BitProofs = append(proof.BitProofs, s.bits[i].fakeProof(g))
+	}
+	proof.EqResult = common.RandomBigInt(g.order)
+
+	return proof
+}
+
+func (s *bitDecompositionStructure) verifyProofStructure(proof BitDecompositionProof) bool {
+	if proof.EqResult == nil {
+		return false
+	}
+	if len(proof.BitCommits) != int(s.bitlen) || len(proof.BitProofs) != int(s.bitlen) {
+		return false
+	}
+	for i, _ := range s.bits {
+		if !proof.BitCommits[i].verifyStructure() {
+			return false
+		}
+		if !s.bits[i].verifyProofStructure(proof.BitProofs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *bitDecompositionStructure) generateCommitmentsFromProof(g group, list []*big.Int, challenge *big.Int, bases baseLookup, proof BitDecompositionProof) []*big.Int {
+	// Inner bases and proofs
+	proof.nameEqHider = strings.Join([]string{s.myname, "eqhider"}, "_")
+	baseList := []baseLookup{}
+	proofList := []proofLookup{&proof}
+	for i, _ := range proof.BitCommits {
+		proof.BitCommits[i].setName(strings.Join([]string{s.myname, "bit", fmt.Sprintf("%v", i)}, "_"))
+		baseList = append(baseList, &proof.BitCommits[i])
+		proofList = append(proofList, &proof.BitCommits[i])
+	}
+	baseList = append(baseList, bases)
+	innerBases := newBaseMerge(baseList...)
+	innerProofs := newProofMerge(proofList...)
+
+	// Regenerate commitments
+	for i, _ := range proof.BitCommits {
+		list = proof.BitCommits[i].generateCommitments(list)
+	}
+	for i, _ := range s.bits {
+		list = s.bits[i].generateCommitmentsFromProof(g, list, challenge, &innerBases, proof.BitProofs[i])
+	}
+	list = s.eqRep.generateCommitmentsFromProof(g, list, challenge, &innerBases, &innerProofs)
+
+	return list
+}
+
+func (c *bitDecompositionCommit) getSecret(name string) *big.Int {
+	if name == c.nameEqHider {
+		return c.eqHider
+	}
+	return nil
+}
+
+func (c *bitDecompositionCommit) getRandomizer(name string) *big.Int {
+	if name == c.nameEqHider {
+		return c.eqHiderRandomizer
+	}
+	return nil
+}
+
+func (c *bitDecompositionCommit) secretNames() []string {
+	return []string{c.nameEqHider}
+}
+
+func (p *BitDecompositionProof) getResult(name string) *big.Int {
+	if name == p.nameEqHider {
+		return p.EqResult
+	}
+	return nil
+}
+
+func newBitProofStructure(bitname string) bitProofStructure {
+	var structure bitProofStructure
+	structure.bitname = bitname
+	structure.zeroRep = representationProofStructure{
+		[]lhsContribution{
+			lhsContribution{bitname, big.NewInt(1)},
+		},
+		[]rhsContribution{
+			rhsContribution{"h", strings.Join([]string{bitname, "zerohider"}, "_"), 1},
+		},
+	}
+	structure.oneRep = representationProofStructure{
+		[]lhsContribution{
+			lhsContribution{bitname, big.NewInt(1)},
+			lhsContribution{"g", big.NewInt(-1)},
+		},
+		[]rhsContribution{
+			rhsContribution{"h", strings.Join([]string{bitname, "onehider"}, "_"), 1},
+		},
+	}
+	return structure
+}
+
+func (s *bitProofStructure) numCommitments() int {
+	return s.zeroRep.numCommitments() + s.oneRep.numCommitments()
+}
+
+// A commit carrying only the names of its branches, as if proving a zero bit
+func (s *bitProofStructure) newCommit() bitProofCommit {
+	var commit bitProofCommit
+	commit.nameValid = strings.Join([]string{s.bitname, "zerohider"}, "_")
+	commit.nameInvalid = strings.Join([]string{s.bitname, "onehider"}, "_")
+	return commit
+}
+
+func (s *bitProofStructure) collectNames(t *nameTracker) {
+	commit := s.newCommit()
+	t.provideSecrets(&commit)
+	t.usePedersonSecret(s.bitname)
+	s.zeroRep.collectNames(t)
+	s.oneRep.collectNames(t)
+}
+
+func (s *bitProofStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, bitProofCommit) {
+	commit := s.newCommit()
+
+	// Prove the branch that holds, simulate the other
+	commit.isOne = secretdata.getSecret(s.bitname).Cmp(big.NewInt(1)) == 0
+	if commit.isOne {
+		commit.nameValid, commit.nameInvalid = commit.nameInvalid, commit.nameValid
+	}
+	commit.hider = secretdata.getSecret(strings.Join([]string{s.bitname, "hider"}, "_"))
+	commit.hiderRandomizer = common.RandomBigInt(g.order)
+	commit.fakeChallenge = common.RandomBigInt(new(big.Int).Lsh(big.NewInt(1), 256))
+	commit.fakeResult = common.RandomBigInt(g.order)
+
+	if commit.isOne {
+		list = s.zeroRep.generateCommitmentsFromProof(g, list, commit.fakeChallenge, bases, &commit)
+		list = s.oneRep.generateCommitmentsFromSecrets(g, list, bases, &commit)
+	} else {
+		list = s.zeroRep.generateCommitmentsFromSecrets(g, list, bases, &commit)
+		list = s.oneRep.generateCommitmentsFromProof(g, list, commit.fakeChallenge, bases, &commit)
+	}
+
+	return list, commit
+}
+
+func (s *bitProofStructure) buildProof(g group, challenge *big.Int, commit bitProofCommit) BitProof {
+	var proof BitProof
+
+	validChallenge := new(big.Int).Xor(challenge, commit.fakeChallenge)
+	validResult := new(big.Int).Mod(
+		new(big.Int).Sub(
+			commit.hiderRandomizer,
+			new(big.Int).Mul(
+				validChallenge,
+				commit.hider)),
+		g.order)
+
+	if commit.isOne {
+		proof.ZeroChallenge = commit.fakeChallenge
+		proof.ZeroResult = commit.fakeResult
+		proof.OneResult = validResult
+	} else {
+		proof.ZeroChallenge = validChallenge
+		proof.ZeroResult = validResult
+		proof.OneResult = commit.fakeResult
+	}
+
+	return proof
+}
+
+func (s *bitProofStructure) fakeProof(g group) BitProof {
+	var proof BitProof
+	proof.ZeroChallenge = common.RandomBigInt(new(big.Int).Lsh(big.NewInt(1), 256))
+	proof.ZeroResult = common.RandomBigInt(g.order)
+	proof.OneResult = common.RandomBigInt(g.order)
+	return proof
+}
+
+func (s *bitProofStructure) verifyProofStructure(proof BitProof) bool {
+	return proof.ZeroChallenge != nil && proof.ZeroResult != nil && proof.OneResult != nil
+}
+
+func (s *bitProofStructure) generateCommitmentsFromProof(g group, list []*big.Int, challenge *big.Int, bases baseLookup, proof BitProof) []*big.Int {
+	proof.nameZero = strings.Join([]string{s.bitname, "zerohider"}, "_")
+	proof.nameOne = strings.Join([]string{s.bitname, "onehider"}, "_")
+	list = s.zeroRep.generateCommitmentsFromProof(g, list, proof.ZeroChallenge, bases, &proof)
+	list = s.oneRep.generateCommitmentsFromProof(g, list, new(big.Int).Xor(challenge, proof.ZeroChallenge), bases, &proof)
+	return list
+}
+
+func (s *bitProofStructure) isTrue(secretdata secretLookup) bool {
+	bit := secretdata.getSecret(s.bitname)
+	return bit.Cmp(big.NewInt(0)) == 0 || bit.Cmp(big.NewInt(1)) == 0
+}
+
+func (c *bitProofCommit) getSecret(name string) *big.Int {
+	if name == c.nameValid {
+		return c.hider
+	}
+	return nil
+}
+
+func (c *bitProofCommit) getRandomizer(name string) *big.Int {
+	if name == c.nameValid {
+		return c.hiderRandomizer
+	}
+	return nil
+}
+
+// The invalid branch is provided through its simulated result
+func (c *bitProofCommit) secretNames() []string {
+	return []string{c.nameValid, c.nameInvalid}
+}
+
+func (c *bitProofCommit) getResult(name string) *big.Int {
+	if name == c.nameInvalid {
+		return c.fakeResult
+	}
+	return nil
+}
+
+func (p *BitProof) getResult(name string) *big.Int {
+	if name == p.nameZero {
+		return p.ZeroResult
+	}
+	if name == p.nameOne {
+		return p.OneResult
+	}
+	return nil
+}
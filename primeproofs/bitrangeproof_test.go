@@ -0,0 +1,185 @@
+package primeproofs
+
+import "testing"
+import "encoding/json"
+import "github.com/privacybydesign/gabi/big"
+
+func TestBitRangeProofFlow(t *testing.T) {
+	g, gok := buildGroup(big.NewInt(26903))
+	if !gok {
+		t.Error("Failed to setup group for Bit range proof testing")
+		return
+	}
+
+	Follower.(*TestFollower).count = 0
+
+	s := newPedersonBitRangeProofStructure("x", big.NewInt(5), big.NewInt(20))
+
+	xCommit := newPedersonSecret(g, "x", big.NewInt(12))
+	bases := newBaseMerge(&g, &xCommit)
+
+	if !s.isTrue(&xCommit) {
+		t.Error("Statement incorrectly declared false")
+	}
+
+	listSecrets, commit := s.generateCommitmentsFromSecrets(g, []*big.Int{}, &bases, &xCommit)
+
+	if len(listSecrets) != s.numCommitments() {
+		t.Error("NumCommitments is off")
+	}
+
+	if Follower.(*TestFollower).count != s.numRangeProofs() {
+		t.Error("Logging is off GenerateCommitmentsFromSecrets")
+	}
+	Follower.(*TestFollower).count = 0
+
+	proof := s.buildProof(g, big.NewInt(12345), commit, &xCommit)
+	xProof := xCommit.buildProof(g, big.NewInt(12345))
+	xProof.setName("x")
+	basesProof := newBaseMerge(&g, &xProof)
+
+	if !s.verifyProofStructure(proof) {
+		t.Error("Proof structure rejected")
+		return
+	}
+
+	listProof := s.generateCommitmentsFromProof(g, []*big.Int{}, big.NewInt(12345), &basesProof, proof)
+
+	if Follower.(*TestFollower).count != s.numRangeProofs() {
+		t.Error("Logging is off on GenerateCommitmentsFromProof")
+	}
+
+	if !listCmp(listSecrets, listProof) {
+		t.Error("Commitment lists differ")
+	}
+
+	// The challenges of the two branches of a bit proof are bound to the challenge
+	proof.LowerProof.BitProofs[0].ZeroChallenge = new(big.Int).Xor(proof.LowerProof.BitProofs[0].ZeroChallenge, big.NewInt(1))
+	if listCmp(listSecrets, s.generateCommitmentsFromProof(g, []*big.Int{}, big.NewInt(12345), &basesProof, proof)) {
+		t.Error("Commitment lists equal after changing bit challenge")
+	}
+}
+
+func TestBitRangeProofBounds(t *testing.T) {
+	g, gok := buildGroup(big.NewInt(26903))
+	if !gok {
+		t.Error("Failed to setup group for Bit range proof testing")
+		return
+	}
+
+	s := newPedersonBitRangeProofStructure("x", big.NewInt(5), big.NewInt(20))
+
+	for _, val := range []int64{5, 20} {
+		xCommit := newPedersonSecret(g, "x", big.NewInt(val))
+		bases := newBaseMerge(&g, &xCommit)
+		listSecrets, commit := s.generateCommitmentsFromSecrets(g, []*big.Int{}, &bases, &xCommit)
+		proof := s.buildProof(g, big.NewInt(12345), commit, &xCommit)
+		xProof := xCommit.buildProof(g, big.NewInt(12345))
+		xProof.setName("x")
+		basesProof := newBaseMerge(&g, &xProof)
+		listProof := s.generateCommitmentsFromProof(g, []*big.Int{}, big.NewInt(12345), &basesProof, proof)
+		if !listCmp(listSecrets, listProof) {
+			t.Errorf("Commitment lists differ for %v", val)
+		}
+	}
+
+	for _, val := range []int64{4, 21} {
+		xCommit := newPedersonSecret(g, "x", big.NewInt(val))
+		if s.isTrue(&xCommit) {
+			t.Errorf("Statement incorrectly declared true for %v", val)
+		}
+	}
+}
+
+func TestBitRangeProofOutOfRange(t *testing.T) {
+	g, gok := buildGroup(big.NewInt(26903))
+	if !gok {
+		t.Error("Failed to setup group for Bit range proof testing")
+		return
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Generating proof for value outside of range did not fail")
+		}
+	}()
+
+	s := newPedersonBitRangeProofStructure("x", big.NewInt(5), big.NewInt(20))
+	xCommit := newPedersonSecret(g, "x", big.NewInt(40))
+	bases := newBaseMerge(&g, &xCommit)
+	s.generateCommitmentsFromSecrets(g, []*big.Int{}, &bases, &xCommit)
+}
+
+func TestBitRangeProofValidate(t *testing.T) {
+	s := newPedersonBitRangeProofStructure("x", big.NewInt(5), big.NewInt(20))
+	if report := validateStructure(&s, "x"); !report.Ok() {
+		t.Errorf("Bit range proof structure rejected: %v", report)
+	}
+}
+
+func TestBitRangeProofVerify(t *testing.T) {
+	g, gok := buildGroup(big.NewInt(26903))
+	if !gok {
+		t.Error("Failed to setup group for Bit range proof testing")
+		return
+	}
+
+	s := newPedersonBitRangeProofStructure("x", big.NewInt(5), big.NewInt(20))
+
+	proof := s.fakeProof(g)
+	if !s.verifyProofStructure(proof) {
+		t.Error("Fake proof structure rejected")
+	}
+
+	proof = s.fakeProof(g)
+	proof.LowerProof.EqResult = nil
+	if s.verifyProofStructure(proof) {
+		t.Error("Accepting missing eqresult")
+	}
+
+	proof = s.fakeProof(g)
+	proof.UpperProof.BitCommits = proof.UpperProof.BitCommits[1:]
+	if s.verifyProofStructure(proof) {
+		t.Error("Accepting missing bit commitment")
+	}
+
+	proof = s.fakeProof(g)
+	proof.LowerProof.BitProofs[0].OneResult = nil
+	if s.verifyProofStructure(proof) {
+		t.Error("Accepting missing bit result")
+	}
+
+	proof = s.fakeProof(g)
+	proof.UpperProof.BitProofs[1].ZeroChallenge = nil
+	if s.verifyProofStructure(proof) {
+		t.Error("Accepting missing bit challenge")
+	}
+}
+
+func TestBitRangeProofJSON(t *testing.T) {
+	g, gok := buildGroup(big.NewInt(26903))
+	if !gok {
+		t.Error("Failed to setup group for Bit range proof testing")
+		return
+	}
+
+	s := newPedersonBitRangeProofStructure("x", big.NewInt(5), big.NewInt(20))
+
+	proofBefore := s.fakeProof(g)
+	proofJSON, err := json.Marshal(proofBefore)
+	if err != nil {
+		t.Errorf("error during json marshal: %s", err.Error())
+		return
+	}
+
+	var proofAfter BitRangeProof
+	err = json.Unmarshal(proofJSON, &proofAfter)
+	if err != nil {
+		t.Errorf("error during json unmarshal: %s", err.Error())
+		return
+	}
+
+	if !s.verifyProofStructure(proofAfter) {
+		t.Error("json'ed proof structure rejected")
+	}
+}
@@ -0,0 +1,120 @@
+package primeproofs
+
+import "github.com/privacybydesign/keyproof/common"
+import "github.com/privacybydesign/gabi/big"
+
+// Direct proof that the bases are squares, working in Z_N* instead of the
+// Pederson group. Each iteration proves knowledge of square roots of all
+// bases at once: the prover commits to a = t^2 for a random unit t, and for
+// challenge bits e_j responds with z = t * prod_j r_j^e_j, which the verifier
+// checks by z^2 = a * prod_j x_j^e_j.
+//
+// If some x_j is not a square, then for any a at most half of the challenge
+// vectors e make a * prod_j x_j^e_j a square, so every iteration has an error
+// prob of 1/2 independent of the number of bases. This needs a and z to be
+// units, otherwise a prover knowing the factorization could pass the check
+// modulo one of the primes trivially. For a random t, z is a random unit, so
+// the proof is perfectly zero-knowledge.
+
+// Index separating the challenge bits from other numbers derived from the
+// challenge, the quasi safe prime product proofs use 0 to 3
+var directSquareProofIndex = big.NewInt(4)
+
+// Challenge bits of the given iteration, bit j belonging to base j
+func (s *isSquareProofStructure) directChallenge(challenge *big.Int, i int) *big.Int {
+	return common.GetHashNumber(challenge, directSquareProofIndex, i, uint(len(s.squares)))
+}
+
+func isUnit(x *big.Int, N *big.Int) bool {
+	return x != nil && x.Sign() > 0 && x.Cmp(N) < 0 &&
+		new(big.Int).GCD(nil, nil, x, N).Cmp(big.NewInt(1)) == 0
+}
+
+func (s *isSquareProofStructure) generateDirectCommitmentsFromSecrets(list []*big.Int, P *big.Int, Q *big.Int) ([]*big.Int, isSquareProofCommit) {
+	var commit isSquareProofCommit
+
+	commit.directRoots = make([]*big.Int, len(s.squares))
+	for i, val := range s.squares {
+		root, ok := common.ModSqrt(val, []*big.Int{P, Q})
+		if !ok {
+			panic("Incorrect key")
+		}
+		commit.directRoots[i] = root
+	}
+
+	list = append(list, s.n)
+	for _, val := range s.squares {
+		list = append(list, val)
+	}
+	commit.directRandomizers = make([]*big.Int, s.params.DirectSquareProofIters)
+	for i := range commit.directRandomizers {
+		t := common.RandomBigInt(s.n)
+		for !isUnit(t, s.n) {
+			t = common.RandomBigInt(s.n)
+		}
+		commit.directRandomizers[i] = t
+		list = append(list, new(big.Int).Exp(t, big.NewInt(2), s.n))
+	}
+
+	return list, commit
+}
+
+func (s *isSquareProofStructure) buildDirectProof(challenge *big.Int, commit isSquareProofCommit) IsSquareProof {
+	var proof IsSquareProof
+	proof.Responses = make([]*big.Int, s.params.DirectSquareProofIters)
+	for i, t := range commit.directRandomizers {
+		e := s.directChallenge(challenge, i)
+		response := new(big.Int).Set(t)
+		for j, root := range commit.directRoots {
+			if e.Bit(j) == 1 {
+				response.Mul(response, root)
+				response.Mod(response, s.n)
+			}
+		}
+		proof.Responses[i] = response
+	}
+	return proof
+}
+
+func (s *isSquareProofStructure) verifyDirectProofStructure(proof IsSquareProof) bool {
+	// Needed for the inverses when recomputing the commitments
+	for _, val := range s.squares {
+		if !isUnit(val, s.n) {
+			return false
+		}
+	}
+
+	if len(proof.Responses) != s.params.DirectSquareProofIters {
+		return false
+	}
+	for _, response := range proof.Responses {
+		if !isUnit(response, s.n) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *isSquareProofStructure) generateDirectCommitmentsFromProof(list []*big.Int, challenge *big.Int, proof IsSquareProof) []*big.Int {
+	inverses := make([]*big.Int, len(s.squares))
+	for i, val := range s.squares {
+		inverses[i] = new(big.Int).ModInverse(val, s.n)
+	}
+
+	list = append(list, s.n)
+	for _, val := range s.squares {
+		list = append(list, val)
+	}
+	for i, response := range proof.Responses {
+		e := s.directChallenge(challenge, i)
+		commitment := new(big.Int).Exp(response, big.NewInt(2), s.n)
+		for j, inverse := range inverses {
+			if e.Bit(j) == 1 {
+				commitment.Mul(commitment, inverse)
+				commitment.Mod(commitment, s.n)
+			}
+		}
+		list = append(list, commitment)
+	}
+	return list
+}
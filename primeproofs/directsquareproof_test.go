@@ -0,0 +1,161 @@
+package primeproofs
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/privacybydesign/gabi/big"
+	"github.com/privacybydesign/keyproof/common"
+)
+
+var directSquareTestParams = SecurityParams{
+	Name:                   "direct-square-test",
+	RangeProofIters:        rangeProofIters,
+	RangeProofEpsilon:      rangeProofEpsilon,
+	DirectSquareProofs:     true,
+	DirectSquareProofIters: 16,
+}
+
+func TestDirectSquareProof(t *testing.T) {
+	const p = 7
+	const q = 11
+
+	s := newIsSquareProofStructure(big.NewInt(p*q), []*big.Int{big.NewInt(36), big.NewInt(4), big.NewInt(9)}, &directSquareTestParams)
+	if s.numRangeProofs() != 0 {
+		t.Error("Direct square proof uses range proofs")
+	}
+	if report := validateStructure(&s); !report.Ok() {
+		t.Errorf("Direct square proof structure invalid: %v", report)
+	}
+
+	listSecret, commit := s.generateCommitmentsFromSecrets(group{}, []*big.Int{}, big.NewInt(p), big.NewInt(q))
+	if len(listSecret) != s.numCommitments() {
+		t.Errorf("NumCommitments is off %v %v", len(listSecret), s.numCommitments())
+	}
+
+	proof := s.buildProof(group{}, big.NewInt(12345), commit)
+	if !s.verifyProofStructure(proof) {
+		t.Error("Proof structure rejected")
+		return
+	}
+
+	listProof := s.generateCommitmentsFromProof(group{}, []*big.Int{}, big.NewInt(12345), proof)
+	if !listCmp(listSecret, listProof) {
+		t.Error("Commitment lists disagree")
+	}
+
+	// 6 is not a square modulo 7, so the proof can't hold for it
+	other := newIsSquareProofStructure(big.NewInt(p*q), []*big.Int{big.NewInt(36), big.NewInt(6), big.NewInt(9)}, &directSquareTestParams)
+	if !other.verifyProofStructure(proof) {
+		t.Error("Proof structure rejected")
+		return
+	}
+	if listCmp(listSecret, other.generateCommitmentsFromProof(group{}, []*big.Int{}, big.NewInt(12345), proof)) {
+		t.Error("Proof accepted for non-square")
+	}
+}
+
+func TestDirectSquareProofStructure(t *testing.T) {
+	const p = 7
+	const q = 11
+
+	s := newIsSquareProofStructure(big.NewInt(p*q), []*big.Int{big.NewInt(36), big.NewInt(4)}, &directSquareTestParams)
+	_, commit := s.generateCommitmentsFromSecrets(group{}, []*big.Int{}, big.NewInt(p), big.NewInt(q))
+	proof := s.buildProof(group{}, big.NewInt(12345), commit)
+
+	backup := proof.Responses[3]
+	proof.Responses[3] = nil
+	if s.verifyProofStructure(proof) {
+		t.Error("Accepting missing response")
+	}
+	proof.Responses[3] = big.NewInt(14)
+	if s.verifyProofStructure(proof) {
+		t.Error("Accepting response outside Z_N*")
+	}
+	proof.Responses[3] = big.NewInt(p*q + 1)
+	if s.verifyProofStructure(proof) {
+		t.Error("Accepting response larger than N")
+	}
+	proof.Responses[3] = backup
+
+	proof.Responses = proof.Responses[1:]
+	if s.verifyProofStructure(proof) {
+		t.Error("Accepting proof with missing responses")
+	}
+
+	nonUnit := newIsSquareProofStructure(big.NewInt(p*q), []*big.Int{big.NewInt(36), big.NewInt(4)}, &directSquareTestParams)
+	nonUnit.squares[1] = big.NewInt(22)
+	if nonUnit.verifyProofStructure(proof) {
+		t.Error("Accepting base outside Z_N*")
+	}
+
+	// The Pederson group proof shouldn't accept direct responses
+	legacy := newIsSquareProofStructure(big.NewInt(p*q), []*big.Int{big.NewInt(36), big.NewInt(4)}, &LegacySecurityParams)
+	if legacy.verifyProofStructure(proof) {
+		t.Error("Accepting direct proof as Pederson group proof")
+	}
+}
+
+func TestValidKeyProofDirectSquares(t *testing.T) {
+	const p = 26903
+	const q = 27803
+
+	s := NewValidKeyProofStructure(big.NewInt(p*q), big.NewInt(36), big.NewInt(49), []*big.Int{big.NewInt(64)}, WithSecurityParams("128-bit-direct-squares"))
+	proof := s.BuildProof(big.NewInt((p-1)/2), big.NewInt((q-1)/2))
+	if !s.VerifyProof(proof) {
+		t.Error("Proof rejected")
+	}
+	if len(proof.BasesValidProof.RootsRangeProof) != 0 {
+		t.Error("Direct square proof contains range proofs")
+	}
+
+	proof.BasesValidProof.Responses[0] = new(big.Int).Mod(new(big.Int).Add(proof.BasesValidProof.Responses[0], big.NewInt(1)), big.NewInt(p*q))
+	if s.VerifyProof(proof) {
+		t.Error("Proof with incorrect response accepted")
+	}
+}
+
+// Compare the Pederson group proof with the direct one for a 2048 bit modulus
+func benchmarkIsSquareProof(b *testing.B, numBases int, params *SecurityParams) {
+	P, err := rand.Prime(rand.Reader, 1024)
+	if err != nil {
+		b.Fatal(err)
+	}
+	Q, err := rand.Prime(rand.Reader, 1024)
+	if err != nil {
+		b.Fatal(err)
+	}
+	N := new(big.Int).Mul(big.Convert(P), big.Convert(Q))
+
+	squares := []*big.Int{}
+	for i := 0; i < numBases; i++ {
+		root := common.RandomBigInt(N)
+		squares = append(squares, new(big.Int).Exp(root, big.NewInt(2), N))
+	}
+
+	g, gok := buildGroup(findSafePrime(params.groupPrimeSize(N.BitLen())))
+	if !gok {
+		b.Fatal("Failed to setup group")
+	}
+	s := newIsSquareProofStructure(N, squares, params)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		list, commit := s.generateCommitmentsFromSecrets(g, []*big.Int{}, big.Convert(P), big.Convert(Q))
+		challenge := common.HashCommit(list)
+		proof := s.buildProof(g, challenge, commit)
+		if !s.verifyProofStructure(proof) {
+			b.Fatal("Proof structure rejected")
+		}
+		s.generateCommitmentsFromProof(g, []*big.Int{}, challenge, proof)
+	}
+}
+
+func BenchmarkIsSquareProof10(b *testing.B) { benchmarkIsSquareProof(b, 10, &Strong128SecurityParams) }
+func BenchmarkIsSquareProof30(b *testing.B) { benchmarkIsSquareProof(b, 30, &Strong128SecurityParams) }
+func BenchmarkDirectSquareProof10(b *testing.B) {
+	benchmarkIsSquareProof(b, 10, &Strong128DirectSquaresSecurityParams)
+}
+func BenchmarkDirectSquareProof30(b *testing.B) {
+	benchmarkIsSquareProof(b, 30, &Strong128DirectSquaresSecurityParams)
+}
@@ -0,0 +1,198 @@
+package primeproofs
+
+import "github.com/privacybydesign/keyproof/common"
+import "github.com/privacybydesign/gabi/big"
+import "strings"
+import "fmt"
+
+// Optional proof that S generates QR_N, and knowledge of the discrete logs
+// x_j of Z and the R_i to base S, as assumed by the CL signatures of gabi.
+//
+// When N is a product of safe primes, a square S generates QR_N exactly when
+// gcd(S-1, N) = 1, which the verifier checks directly. Knowledge of the logs
+// is proven with binary challenges over the integers, as the prover knows
+// the order of S: each iteration commits to t = S^r and for challenge bits
+// e_j responds with z = r + sum_j e_j x_j, which the verifier checks by
+// S^z = t * prod_j B_j^e_j. If some B_j is not a power of S with known log,
+// at most half of the challenge vectors can be answered for any t, so every
+// iteration has an error prob of 1/2 independent of the number of bases.
+//
+// gabi's key generation discards the discrete logs, so this needs keys from a
+// generator that keeps them.
+
+type discreteLogProofStructure struct {
+	n      *big.Int
+	s      *big.Int
+	bases  []*big.Int
+	params *SecurityParams
+}
+
+type DiscreteLogProof struct {
+	Responses []*big.Int
+}
+
+type discreteLogProofCommit struct {
+	logs        []*big.Int
+	randomizers []*big.Int
+}
+
+// Index separating the challenge bits from other numbers derived from the
+// challenge, see directSquareProofIndex
+var discreteLogProofIndex = big.NewInt(5)
+
+func newDiscreteLogProofStructure(N *big.Int, S *big.Int, Bases []*big.Int, params *SecurityParams) discreteLogProofStructure {
+	var result discreteLogProofStructure
+	result.n = new(big.Int).Set(N)
+	result.s = new(big.Int).Set(S)
+	result.bases = make([]*big.Int, len(Bases))
+	for i, val := range Bases {
+		result.bases[i] = new(big.Int).Set(val)
+	}
+	result.params = params
+	return result
+}
+
+func (s *discreteLogProofStructure) numCommitments() int {
+	return 2 + len(s.bases) + s.params.DiscreteLogProofIters
+}
+
+// Size of the randomizers, hiding the sum of the logs statistically
+func (s *discreteLogProofStructure) randomizerBits() uint {
+	return uint(s.n.BitLen()+big.NewInt(int64(len(s.bases))).BitLen()) + s.params.RangeProofEpsilon
+}
+
+// Challenge bits of the given iteration, bit j belonging to base j
+func (s *discreteLogProofStructure) challengeBits(challenge *big.Int, i int) *big.Int {
+	return common.GetHashNumber(challenge, discreteLogProofIndex, i, uint(len(s.bases)))
+}
+
+// Whether S generates QR_N, given that it is a square and N a product of safe primes
+func (s *discreteLogProofStructure) generatorValid() bool {
+	return generatesQR(s.s, s.n)
+}
+
+// Check the proof is about the bases the square proof shows to be squares
+// modulo the same N. Its own bases are reported as S and B_j, those of the
+// square proof by the names it gives them.
+func (s *discreteLogProofStructure) validate(squares *isSquareProofStructure) ValidationReport {
+	var report ValidationReport
+	if s.n.Cmp(squares.n) != 0 {
+		report.MissingBases = append(report.MissingBases, "N")
+	}
+	used := make([]bool, len(squares.squares))
+	use := func(name string, base *big.Int) {
+		for i, square := range squares.squares {
+			if square.Cmp(base) == 0 {
+				used[i] = true
+				return
+			}
+		}
+		report.MissingBases = append(report.MissingBases, name)
+	}
+	use("S", s.s)
+	for j, base := range s.bases {
+		use(strings.Join([]string{"B", fmt.Sprintf("%v", j)}, "_"), base)
+	}
+	commit := squares.newCommit()
+	for i := range used {
+		if !used[i] {
+			report.UnusedBases = append(report.UnusedBases, commit.squares[i].name)
+		}
+	}
+	return report
+}
+
+func generatesQR(x *big.Int, N *big.Int) bool {
+	return isUnit(new(big.Int).Sub(x, big.NewInt(1)), N)
+}
+
+func (s *discreteLogProofStructure) generateCommitmentsFromSecrets(list []*big.Int, logs []*big.Int) ([]*big.Int, discreteLogProofCommit) {
+	if !s.generatorValid() {
+		panic("S does not generate QR_N")
+	}
+	if len(logs) != len(s.bases) {
+		panic("Incorrect number of discrete logs")
+	}
+	for i, x := range logs {
+		if x.Sign() < 0 || new(big.Int).Exp(s.s, x, s.n).Cmp(s.bases[i]) != 0 {
+			panic("Incorrect discrete log")
+		}
+	}
+
+	var commit discreteLogProofCommit
+	commit.logs = logs
+	commit.randomizers = make([]*big.Int, s.params.DiscreteLogProofIters)
+
+	list = append(list, s.n)
+	list = append(list, s.s)
+	list = append(list, s.bases...)
+	for i := range commit.randomizers {
+		commit.randomizers[i] = common.RandomBigInt(new(big.Int).Lsh(big.NewInt(1), s.randomizerBits()))
+		list = append(list, new(big.Int).Exp(s.s, commit.randomizers[i], s.n))
+	}
+
+	return list, commit
+}
+
+func (s *discreteLogProofStructure) buildProof(challenge *big.Int, commit discreteLogProofCommit) DiscreteLogProof {
+	var proof DiscreteLogProof
+	proof.Responses = make([]*big.Int, s.params.DiscreteLogProofIters)
+	for i, r := range commit.randomizers {
+		e := s.challengeBits(challenge, i)
+		response := new(big.Int).Set(r)
+		for j, x := range commit.logs {
+			if e.Bit(j) == 1 {
+				response.Add(response, x)
+			}
+		}
+		proof.Responses[i] = response
+	}
+	return proof
+}
+
+func (s *discreteLogProofStructure) verifyProofStructure(proof DiscreteLogProof) bool {
+	if !s.generatorValid() || !isUnit(s.s, s.n) {
+		return false
+	}
+	// Needed for the inverses when recomputing the commitments
+	for _, val := range s.bases {
+		if !isUnit(val, s.n) {
+			return false
+		}
+	}
+
+	if len(proof.Responses) != s.params.DiscreteLogProofIters {
+		return false
+	}
+	// Honest responses are below 2^(randomizerBits+1), larger ones would only
+	// make verification expensive
+	for _, response := range proof.Responses {
+		if response == nil || response.Sign() < 0 || response.BitLen() > int(s.randomizerBits())+1 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *discreteLogProofStructure) generateCommitmentsFromProof(list []*big.Int, challenge *big.Int, proof DiscreteLogProof) []*big.Int {
+	inverses := make([]*big.Int, len(s.bases))
+	for i, val := range s.bases {
+		inverses[i] = new(big.Int).ModInverse(val, s.n)
+	}
+
+	list = append(list, s.n)
+	list = append(list, s.s)
+	list = append(list, s.bases...)
+	for i, response := range proof.Responses {
+		e := s.challengeBits(challenge, i)
+		commitment := new(big.Int).Exp(s.s, response, s.n)
+		for j, inverse := range inverses {
+			if e.Bit(j) == 1 {
+				commitment.Mul(commitment, inverse)
+				commitment.Mod(commitment, s.n)
+			}
+		}
+		list = append(list, commitment)
+	}
+	return list
+}
@@ -0,0 +1,145 @@
+package primeproofs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/privacybydesign/gabi/big"
+)
+
+const discreteLogTestP = 26903
+const discreteLogTestQ = 27803
+
+// Z and R as powers of S = 49 modulo N
+func discreteLogTestKey() (N *big.Int, S *big.Int, bases []*big.Int, logs []*big.Int) {
+	N = big.NewInt(discreteLogTestP * discreteLogTestQ)
+	S = big.NewInt(49)
+	logs = []*big.Int{big.NewInt(123456), big.NewInt(654321)}
+	for _, x := range logs {
+		bases = append(bases, new(big.Int).Exp(S, x, N))
+	}
+	return
+}
+
+func TestDiscreteLogProof(t *testing.T) {
+	N, S, bases, logs := discreteLogTestKey()
+	s := newDiscreteLogProofStructure(N, S, bases, &LegacySecurityParams)
+
+	listSecret, commit := s.generateCommitmentsFromSecrets([]*big.Int{}, logs)
+	if len(listSecret) != s.numCommitments() {
+		t.Errorf("NumCommitments is off %v %v", len(listSecret), s.numCommitments())
+	}
+
+	proof := s.buildProof(big.NewInt(12345), commit)
+	if !s.verifyProofStructure(proof) {
+		t.Error("Proof structure rejected")
+		return
+	}
+
+	listProof := s.generateCommitmentsFromProof([]*big.Int{}, big.NewInt(12345), proof)
+	if !listCmp(listSecret, listProof) {
+		t.Error("Commitment lists disagree")
+	}
+
+	// A base with a different log
+	other := newDiscreteLogProofStructure(N, S, []*big.Int{bases[0], big.NewInt(36)}, &LegacySecurityParams)
+	if listCmp(listSecret, other.generateCommitmentsFromProof([]*big.Int{}, big.NewInt(12345), proof)) {
+		t.Error("Proof accepted for base with unknown log")
+	}
+}
+
+func TestDiscreteLogProofStructure(t *testing.T) {
+	N, S, bases, logs := discreteLogTestKey()
+	s := newDiscreteLogProofStructure(N, S, bases, &LegacySecurityParams)
+	_, commit := s.generateCommitmentsFromSecrets([]*big.Int{}, logs)
+	proof := s.buildProof(big.NewInt(12345), commit)
+
+	backup := proof.Responses[3]
+	proof.Responses[3] = nil
+	if s.verifyProofStructure(proof) {
+		t.Error("Accepting missing response")
+	}
+	proof.Responses[3] = big.NewInt(-1)
+	if s.verifyProofStructure(proof) {
+		t.Error("Accepting negative response")
+	}
+	proof.Responses[3] = new(big.Int).Lsh(big.NewInt(1), s.randomizerBits()+1)
+	if s.verifyProofStructure(proof) {
+		t.Error("Accepting too large response")
+	}
+	proof.Responses[3] = backup
+
+	proof.Responses = proof.Responses[1:]
+	if s.verifyProofStructure(proof) {
+		t.Error("Accepting proof with missing responses")
+	}
+}
+
+func TestDiscreteLogProofGenerator(t *testing.T) {
+	N, S, bases, _ := discreteLogTestKey()
+	s := newDiscreteLogProofStructure(N, S, bases, &LegacySecurityParams)
+	if !s.generatorValid() {
+		t.Error("Rejected generator of QR_N")
+	}
+
+	// Generates only the squares modulo Q
+	s.s = big.NewInt(discreteLogTestP + 1)
+	if s.generatorValid() {
+		t.Error("Accepted S = 1 modulo P")
+	}
+	s.s = big.NewInt(1)
+	if s.generatorValid() {
+		t.Error("Accepted S = 1")
+	}
+}
+
+func TestValidKeyProofDiscreteLogs(t *testing.T) {
+	N, S, bases, logs := discreteLogTestKey()
+	s := NewValidKeyProofStructure(N, bases[0], S, bases[1:], WithSecurityParams("fast-test"))
+	proofBefore := s.BuildProofWithDiscreteLogs(big.NewInt((discreteLogTestP-1)/2), big.NewInt((discreteLogTestQ-1)/2), logs)
+
+	proofJSON, err := json.Marshal(proofBefore)
+	if err != nil {
+		t.Errorf("error during json marshal: %s", err.Error())
+		return
+	}
+	var proof ValidKeyProof
+	if err := json.Unmarshal(proofJSON, &proof); err != nil {
+		t.Errorf("error during json unmarshal: %s", err.Error())
+		return
+	}
+
+	if !s.VerifyProof(proof) {
+		t.Error("Proof with discrete logs rejected")
+	}
+	required := NewValidKeyProofStructure(N, bases[0], S, bases[1:], WithSecurityParams("fast-test"),
+		WithVerifierPolicy(VerifierPolicy{RequireDiscreteLogProof: true}))
+	if !required.VerifyProof(proof) {
+		t.Error("Proof with discrete logs rejected when required")
+	}
+
+	// Leaving out the extension changes the challenge
+	logProof := proof.BasesLogProof
+	proof.BasesLogProof = nil
+	if s.VerifyProof(proof) {
+		t.Error("Proof with discrete logs removed accepted")
+	}
+
+	proof.BasesLogProof = logProof
+	proof.BasesLogProof.Responses[0] = new(big.Int).Add(proof.BasesLogProof.Responses[0], big.NewInt(1))
+	if s.VerifyProof(proof) {
+		t.Error("Proof with incorrect discrete log response accepted")
+	}
+
+	// Proofs without discrete logs when they are required
+	proof = s.BuildProof(big.NewInt((discreteLogTestP-1)/2), big.NewInt((discreteLogTestQ-1)/2))
+	if proof.BasesLogProof != nil {
+		t.Error("Proof without discrete logs has discrete log proof")
+	}
+	if !s.VerifyProof(proof) {
+		t.Error("Proof without discrete logs rejected")
+	}
+	if required.VerifyProof(proof) {
+		t.Error("Proof without discrete logs accepted when required")
+	}
+}
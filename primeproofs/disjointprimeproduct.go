@@ -7,7 +7,7 @@ type DisjointPrimeProductProof struct {
 	Responses []*big.Int
 }
 
-func disjointPrimeProductBuildProof(P *big.Int, Q *big.Int, challenge *big.Int, index *big.Int) DisjointPrimeProductProof {
+func disjointPrimeProductBuildProof(P *big.Int, Q *big.Int, challenge *big.Int, index *big.Int, params *SecurityParams) DisjointPrimeProductProof {
 	// Precalculate values for response
 	N := new(big.Int).Mul(P, Q)
 	phiN := new(big.Int).Mul(new(big.Int).Sub(P, big.NewInt(1)), new(big.Int).Sub(Q, big.NewInt(1)))
@@ -23,7 +23,7 @@ func disjointPrimeProductBuildProof(P *big.Int, Q *big.Int, challenge *big.Int,
 	// Generate the challenges and responses
 	var proof DisjointPrimeProductProof
 	proof.Responses = []*big.Int{}
-	for i := 0; i < squareFreeIters; i++ {
+	for i := 0; i < params.DisjointPrimeProductIters; i++ {
 		// Generate the challenge
 		curc := common.GetHashNumber(challenge, index, i, uint(N.BitLen()))
 		curc.Mod(curc, N)
@@ -39,8 +39,8 @@ func disjointPrimeProductBuildProof(P *big.Int, Q *big.Int, challenge *big.Int,
 	return proof
 }
 
-func disjointPrimeProductVerifyStructure(proof DisjointPrimeProductProof) bool {
-	if proof.Responses == nil || len(proof.Responses) != disjointPrimeProductIters {
+func disjointPrimeProductVerifyStructure(proof DisjointPrimeProductProof, params *SecurityParams) bool {
+	if proof.Responses == nil || len(proof.Responses) != params.DisjointPrimeProductIters {
 		return false
 	}
 
@@ -53,7 +53,7 @@ func disjointPrimeProductVerifyStructure(proof DisjointPrimeProductProof) bool {
 	return true
 }
 
-func disjointPrimeProductVerifyProof(N *big.Int, challenge *big.Int, index *big.Int, proof DisjointPrimeProductProof) bool {
+func disjointPrimeProductVerifyProof(N *big.Int, challenge *big.Int, index *big.Int, proof DisjointPrimeProductProof, params *SecurityParams) bool {
 	// Check that N is not a fermat prime
 	if N.ProbablyPrime(80) {
 		return false
@@ -66,7 +66,7 @@ func disjointPrimeProductVerifyProof(N *big.Int, challenge *big.Int, index *big.
 	}
 
 	// Generate the challenges and verify responses
-	for i := 0; i < squareFreeIters; i++ {
+	for i := 0; i < params.DisjointPrimeProductIters; i++ {
 		// Generate the challenge
 		curc := common.GetHashNumber(challenge, index, i, uint(N.BitLen()))
 		curc.Mod(curc, N)
@@ -6,12 +6,12 @@ import "github.com/privacybydesign/gabi/big"
 func TestDisjointPrimeProductCycle(t *testing.T) {
 	const p = 2063
 	const q = 1187
-	proof := disjointPrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(2))
-	if !disjointPrimeProductVerifyStructure(proof) {
+	proof := disjointPrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(2), &LegacySecurityParams)
+	if !disjointPrimeProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Proof structure rejected")
 		return
 	}
-	ok := disjointPrimeProductVerifyProof(big.NewInt(p*q), big.NewInt(12345), big.NewInt(2), proof)
+	ok := disjointPrimeProductVerifyProof(big.NewInt(p*q), big.NewInt(12345), big.NewInt(2), proof, &LegacySecurityParams)
 	if !ok {
 		t.Error("DisjointPrimeProductProof rejected.")
 	}
@@ -20,9 +20,9 @@ func TestDisjointPrimeProductCycle(t *testing.T) {
 func TestDisjointPrimeProductCycleIncorrect(t *testing.T) {
 	const p = 2063
 	const q = 1187
-	proof := disjointPrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(2))
+	proof := disjointPrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(2), &LegacySecurityParams)
 	proof.Responses[0].Add(proof.Responses[0], big.NewInt(1))
-	ok := disjointPrimeProductVerifyProof(big.NewInt(p*q), big.NewInt(12345), big.NewInt(2), proof)
+	ok := disjointPrimeProductVerifyProof(big.NewInt(p*q), big.NewInt(12345), big.NewInt(2), proof, &LegacySecurityParams)
 	if ok {
 		t.Error("Incorrect DisjointPrimeProductProof accepted.")
 	}
@@ -31,8 +31,8 @@ func TestDisjointPrimeProductCycleIncorrect(t *testing.T) {
 func TestDisjointPrimeProductWrongChallenge(t *testing.T) {
 	const p = 2063
 	const q = 1187
-	proof := disjointPrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(2))
-	ok := disjointPrimeProductVerifyProof(big.NewInt(p*q), big.NewInt(12346), big.NewInt(2), proof)
+	proof := disjointPrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(2), &LegacySecurityParams)
+	ok := disjointPrimeProductVerifyProof(big.NewInt(p*q), big.NewInt(12346), big.NewInt(2), proof, &LegacySecurityParams)
 	if ok {
 		t.Error("Incorrect DisjointPrimeProductProof accepted.")
 	}
@@ -41,8 +41,8 @@ func TestDisjointPrimeProductWrongChallenge(t *testing.T) {
 func TestDisjointPrimeProductWrongIndex(t *testing.T) {
 	const p = 2063
 	const q = 1187
-	proof := disjointPrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(2))
-	ok := disjointPrimeProductVerifyProof(big.NewInt(p*q), big.NewInt(12345), big.NewInt(3), proof)
+	proof := disjointPrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(2), &LegacySecurityParams)
+	ok := disjointPrimeProductVerifyProof(big.NewInt(p*q), big.NewInt(12345), big.NewInt(3), proof, &LegacySecurityParams)
 	if ok {
 		t.Error("Incorrect DisjointPrimeProductProof accepted.")
 	}
@@ -51,23 +51,23 @@ func TestDisjointPrimeProductWrongIndex(t *testing.T) {
 func TestDisjointPrimeProductVerifyStructure(t *testing.T) {
 	const p = 2063
 	const q = 1187
-	proof := disjointPrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(2))
+	proof := disjointPrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), big.NewInt(2), &LegacySecurityParams)
 
 	listBackup := proof.Responses
 	proof.Responses = proof.Responses[:len(proof.Responses)-1]
-	if disjointPrimeProductVerifyStructure(proof) {
+	if disjointPrimeProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Accepting too short responses")
 	}
 	proof.Responses = listBackup
 
 	valBackup := proof.Responses[2]
 	proof.Responses[2] = nil
-	if disjointPrimeProductVerifyStructure(proof) {
+	if disjointPrimeProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Accepting missing response")
 	}
 	proof.Responses[2] = valBackup
 
-	if !disjointPrimeProductVerifyStructure(proof) {
+	if !disjointPrimeProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Testcase corrupted testdata")
 	}
 }
@@ -0,0 +1,147 @@
+package primeproofs
+
+import (
+	"fmt"
+	"math"
+)
+
+// A SecurityEstimate gives the soundness error and statistical zero-knowledge
+// distance of a proof as bits, e.g. SoundnessBits = 80 means a cheating
+// prover succeeds with probability at most 2^-80 per attempt. The errors of
+// the individual sub-proofs are combined with a union bound.
+type SecurityEstimate struct {
+	SoundnessBits     float64
+	ZeroKnowledgeBits float64
+	Components        []SecurityComponent
+}
+
+// Soundness error and zero-knowledge distance of a single kind of sub-proof
+type SecurityComponent struct {
+	Name              string
+	Count             int
+	SoundnessBits     float64
+	ZeroKnowledgeBits float64 // +Inf for perfect zero-knowledge
+}
+
+// Meets checks whether the estimate reaches the given security levels in bits
+func (e SecurityEstimate) Meets(soundnessBits float64, zeroKnowledgeBits float64) bool {
+	return e.SoundnessBits >= soundnessBits && e.ZeroKnowledgeBits >= zeroKnowledgeBits
+}
+
+func (e SecurityEstimate) String() string {
+	return fmt.Sprintf("2^-%.0f soundness, 2^-%.0f zero-knowledge distance", e.SoundnessBits, e.ZeroKnowledgeBits)
+}
+
+// Error bits of count independent sub-proofs with the given error bits each
+func unionBits(bits float64, count int) float64 {
+	if count == 0 {
+		return math.Inf(1)
+	}
+	return bits - math.Log2(float64(count))
+}
+
+// Error bits of the sum of the errors of all components
+func combineBits(bits []float64) float64 {
+	// Scale relative to the largest error to avoid underflow
+	min := math.Inf(1)
+	for _, b := range bits {
+		min = math.Min(min, b)
+	}
+	if math.IsInf(min, 1) {
+		return min
+	}
+	sum := 0.0
+	for _, b := range bits {
+		sum += math.Exp2(min - b)
+	}
+	return min - math.Log2(sum)
+}
+
+// Security of a single range proof with the given parameters. Exact range
+// proofs only replace some of them and are sounder, so they count the same.
+func (p *SecurityParams) rangeProofSecurity() (soundness float64, zeroKnowledge float64) {
+	if p.LargeChallengeRangeProofs {
+		// Soundness relies on the strong RSA assumption for the integer
+		// commitment, both results hide with statistical distance 2^-epsilon
+		return largeRangeProofChallengeSize, unionBits(float64(p.RangeProofEpsilon), 2)
+	}
+	return float64(p.RangeProofIters), unionBits(float64(p.RangeProofEpsilon), p.RangeProofIters)
+}
+
+// Security of the quasi safe prime product proof components
+func (p *SecurityParams) quasiSafePrimeProductSecurity() []SecurityComponent {
+	factorBits := math.Log2(float64(p.MinimumFactor))
+	return []SecurityComponent{
+		{"square free", p.SquareFreeIters, factorBits * float64(p.SquareFreeIters), math.Inf(1)},
+		{"prime power product", p.PrimePowerProductIters, float64(p.PrimePowerProductIters), math.Inf(1)},
+		{"disjoint prime product", p.DisjointPrimeProductIters, factorBits * float64(p.DisjointPrimeProductIters), math.Inf(1)},
+		{"almost safe prime product", p.AlmostSafePrimeProductIters, -math.Log2(4.0/5.0) * float64(p.AlmostSafePrimeProductIters), math.Inf(1)},
+	}
+}
+
+// Estimate the soundness error and zero-knowledge distance of proofs built
+// with this structure.
+func (s *ValidKeyProofStructure) SecurityEstimate() SecurityEstimate {
+	var estimate SecurityEstimate
+
+	rangeSoundness, rangeZeroKnowledge := s.params.rangeProofSecurity()
+	numRange := s.numRangeProofs()
+	estimate.Components = append(estimate.Components, SecurityComponent{
+		"range proofs",
+		numRange,
+		unionBits(rangeSoundness, numRange),
+		unionBits(rangeZeroKnowledge, numRange),
+	})
+
+	if s.params.BatchedRangeProofs && !s.params.LargeChallengeRangeProofs {
+		estimate.Components = append(estimate.Components, SecurityComponent{
+			"range proof batch",
+			1,
+			float64(s.params.RangeProofBatchBits),
+			math.Inf(1),
+		})
+	}
+
+	if s.params.DirectSquareProofs {
+		estimate.Components = append(estimate.Components, SecurityComponent{
+			"square roots",
+			1,
+			float64(s.params.DirectSquareProofIters),
+			math.Inf(1),
+		})
+	}
+
+	if s.policy.RequireDiscreteLogProof {
+		estimate.Components = append(estimate.Components, SecurityComponent{
+			"discrete logs",
+			1,
+			float64(s.params.DiscreteLogProofIters),
+			unionBits(float64(s.params.RangeProofEpsilon), s.params.DiscreteLogProofIters),
+		})
+	}
+
+	// The quasi safe prime product proof leaves P' = r^k for a prime r. For
+	// k > 1, a base passes the prime proof only if its order is coprime to r,
+	// which happens with probability 1/r^(k-1) <= 1/sqrt(P').
+	primeBits := float64(s.pprimeIsPrime.bitlen-2) / 2
+
+	// The bases of all rounds derive from a single hash, so a prover can't
+	// retry the rounds one at a time and the errors of the rounds multiply
+	estimate.Components = append(estimate.Components, SecurityComponent{
+		"prime proofs",
+		2,
+		unionBits(primeBits*float64(s.params.primeProofRounds()), 2),
+		math.Inf(1),
+	})
+
+	estimate.Components = append(estimate.Components, s.params.quasiSafePrimeProductSecurity()...)
+
+	var soundness, zeroKnowledge []float64
+	for _, component := range estimate.Components {
+		soundness = append(soundness, component.SoundnessBits)
+		zeroKnowledge = append(zeroKnowledge, component.ZeroKnowledgeBits)
+	}
+	estimate.SoundnessBits = combineBits(soundness)
+	estimate.ZeroKnowledgeBits = combineBits(zeroKnowledge)
+	return estimate
+}
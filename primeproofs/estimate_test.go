@@ -0,0 +1,103 @@
+package primeproofs
+
+import "testing"
+import "math"
+import "strings"
+import "github.com/privacybydesign/gabi/big"
+
+func TestCombineBits(t *testing.T) {
+	if math.Abs(combineBits([]float64{80, 80})-79) > 1e-9 {
+		t.Error("Incorrect combination of equal errors")
+	}
+	if math.Abs(combineBits([]float64{80, math.Inf(1)})-80) > 1e-9 {
+		t.Error("Perfect component changed combined error")
+	}
+	if math.Abs(combineBits([]float64{1000, 1001})-(1000-math.Log2(1.5))) > 1e-9 {
+		t.Error("Incorrect combination of small errors")
+	}
+	if !math.IsInf(combineBits(nil), 1) {
+		t.Error("Empty combination not perfect")
+	}
+}
+
+func TestSecurityEstimate(t *testing.T) {
+	N := new(big.Int).Lsh(big.NewInt(1), 2047)
+	N.Add(N, big.NewInt(5))
+	bases := []*big.Int{big.NewInt(64)}
+
+	legacyStructure := NewValidKeyProofStructure(N, big.NewInt(36), big.NewInt(49), bases)
+	strongStructure := NewValidKeyProofStructure(N, big.NewInt(36), big.NewInt(49), bases, WithSecurityParams("128-bit"))
+	fastStructure := NewValidKeyProofStructure(N, big.NewInt(36), big.NewInt(49), bases, WithSecurityParams("fast-test"))
+	legacy := legacyStructure.SecurityEstimate()
+	strong := strongStructure.SecurityEstimate()
+	fast := fastStructure.SecurityEstimate()
+
+	if legacy.SoundnessBits < 60 || legacy.SoundnessBits > 80 {
+		t.Errorf("Unexpected legacy soundness %v", legacy.SoundnessBits)
+	}
+	if strong.SoundnessBits <= legacy.SoundnessBits {
+		t.Error("128-bit parameters not stronger than legacy")
+	}
+	if fast.SoundnessBits > 10 {
+		t.Errorf("Unexpected fast-test soundness %v", fast.SoundnessBits)
+	}
+	if fast.ZeroKnowledgeBits >= legacy.ZeroKnowledgeBits {
+		t.Error("Smaller epsilon didn't reduce zero-knowledge")
+	}
+
+	if !legacy.Meets(60, 200) {
+		t.Error("Legacy doesn't meet low policy")
+	}
+	if legacy.Meets(128, 0) {
+		t.Error("Legacy meets 128 bit policy")
+	}
+	if fast.Meets(40, 0) {
+		t.Error("Fast-test meets 40 bit policy")
+	}
+}
+
+func TestSecurityEstimateStrong128(t *testing.T) {
+	N := new(big.Int).Lsh(big.NewInt(1), 2047)
+	N.Add(N, big.NewInt(5))
+	var bases []*big.Int
+	for i := 0; i < 30; i++ {
+		bases = append(bases, big.NewInt(64))
+	}
+
+	for name := range securityParamSets {
+		if !strings.HasPrefix(name, "128-bit") {
+			continue
+		}
+		for _, numBases := range []int{1, len(bases)} {
+			s := NewValidKeyProofStructure(N, big.NewInt(36), big.NewInt(49), bases[:numBases], WithSecurityParams(name),
+				WithVerifierPolicy(VerifierPolicy{RequireDiscreteLogProof: true}))
+			estimate := s.SecurityEstimate()
+			if !estimate.Meets(128, 128) {
+				t.Errorf("Parameter set %v gives only %v for %v bases", name, estimate, numBases)
+			}
+		}
+	}
+}
+
+func TestSecurityEstimatePrimeProofRounds(t *testing.T) {
+	N := big.NewInt(26903 * 27803)
+
+	params := Strong128SecurityParams
+	params.Name = "128-bit-rounds-test"
+	params.PrimeProofRounds = 2
+
+	primeProofBits := func(opts ...ValidKeyProofOption) float64 {
+		s := NewValidKeyProofStructure(N, big.NewInt(36), big.NewInt(49), nil, opts...)
+		for _, component := range s.SecurityEstimate().Components {
+			if component.Name == "prime proofs" {
+				return component.SoundnessBits
+			}
+		}
+		t.Error("No prime proof component in estimate")
+		return 0
+	}
+
+	if primeProofBits(withTestSecurityParams(&params)) <= primeProofBits(WithSecurityParams("128-bit")) {
+		t.Error("More rounds didn't increase prime proof soundness")
+	}
+}
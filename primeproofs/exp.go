@@ -83,6 +83,26 @@ func (c *expProofCommit) getRandomizer(name string) *big.Int {
 	return nil
 }
 
+func (c *expProofCommit) secretNames() []string {
+	return []string{c.nameBitEqHider}
+}
+
+// The pederson commitments of the commit, all provided as bases and secrets
+func (c *expProofCommit) pedersons() []*pedersonSecret {
+	result := []*pedersonSecret{}
+	for i, _ := range c.expBitPederson {
+		result = append(result, &c.expBitPederson[i])
+	}
+	for i, _ := range c.basePowPederson {
+		result = append(result, &c.basePowPederson[i])
+	}
+	result = append(result, &c.startPederson)
+	for i, _ := range c.interResPederson {
+		result = append(result, &c.interResPederson[i])
+	}
+	return result
+}
+
 func (p *ExpProof) getResult(name string) *big.Int {
 	if name == p.nameBitEqHider {
 		return p.ExpBitEqResult
@@ -90,7 +110,7 @@ func (p *ExpProof) getResult(name string) *big.Int {
 	return nil
 }
 
-func newExpProofStructure(base, exponent, mod, result string, bitlen uint) expProofStructure {
+func newExpProofStructure(base, exponent, mod, result string, bitlen uint, params *SecurityParams) expProofStructure {
 	var structure expProofStructure
 
 	structure.base = base
@@ -139,7 +159,7 @@ func newExpProofStructure(base, exponent, mod, result string, bitlen uint) expPr
 	for i := uint(0); i < bitlen; i++ {
 		structure.basePowRange = append(
 			structure.basePowRange,
-			newPedersonRangeProofStructure(strings.Join([]string{structure.myname, "base", fmt.Sprintf("%v", i)}, "_"), 0, bitlen))
+			newPedersonRangeProofStructure(strings.Join([]string{structure.myname, "base", fmt.Sprintf("%v", i)}, "_"), 0, bitlen, params))
 	}
 
 	// Base relations proofs
@@ -154,7 +174,8 @@ func newExpProofStructure(base, exponent, mod, result string, bitlen uint) expPr
 					base,
 					mod,
 					strings.Join([]string{structure.myname, "base", fmt.Sprintf("%v", i)}, "_"),
-					bitlen))
+					bitlen,
+					params))
 		} else {
 			structure.basePowRels = append(
 				structure.basePowRels,
@@ -163,7 +184,8 @@ func newExpProofStructure(base, exponent, mod, result string, bitlen uint) expPr
 					strings.Join([]string{structure.myname, "base", fmt.Sprintf("%v", i-1)}, "_"),
 					mod,
 					strings.Join([]string{structure.myname, "base", fmt.Sprintf("%v", i)}, "_"),
-					bitlen))
+					bitlen,
+					params))
 		}
 	}
 
@@ -191,7 +213,7 @@ func newExpProofStructure(base, exponent, mod, result string, bitlen uint) expPr
 	for i := uint(0); i < bitlen-1; i++ {
 		structure.interResRange = append(
 			structure.interResRange,
-			newPedersonRangeProofStructure(strings.Join([]string{structure.myname, "inter", fmt.Sprintf("%v", i)}, "_"), 0, bitlen))
+			newPedersonRangeProofStructure(strings.Join([]string{structure.myname, "inter", fmt.Sprintf("%v", i)}, "_"), 0, bitlen, params))
 	}
 
 	// step proofs
@@ -207,7 +229,8 @@ func newExpProofStructure(base, exponent, mod, result string, bitlen uint) expPr
 					strings.Join([]string{structure.myname, "inter", fmt.Sprintf("%v", i)}, "_"),
 					strings.Join([]string{structure.myname, "base", fmt.Sprintf("%v", i)}, "_"),
 					mod,
-					bitlen))
+					bitlen,
+					params))
 		} else if i == bitlen-1 {
 			// special case for end
 			structure.interSteps = append(
@@ -218,7 +241,8 @@ func newExpProofStructure(base, exponent, mod, result string, bitlen uint) expPr
 					result,
 					strings.Join([]string{structure.myname, "base", fmt.Sprintf("%v", i)}, "_"),
 					mod,
-					bitlen))
+					bitlen,
+					params))
 		} else {
 			structure.interSteps = append(
 				structure.interSteps,
@@ -228,7 +252,8 @@ func newExpProofStructure(base, exponent, mod, result string, bitlen uint) expPr
 					strings.Join([]string{structure.myname, "inter", fmt.Sprintf("%v", i)}, "_"),
 					strings.Join([]string{structure.myname, "base", fmt.Sprintf("%v", i)}, "_"),
 					mod,
-					bitlen))
+					bitlen,
+					params))
 		}
 	}
 
@@ -278,25 +303,78 @@ func (s *expProofStructure) numCommitments() int {
 	return res
 }
 
-func (s *expProofStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, expProofCommit) {
+// A commit carrying only the names of its secrets and pederson commitments
+func (s *expProofStructure) newCommit() expProofCommit {
 	var commit expProofCommit
+	commit.nameBitEqHider = strings.Join([]string{s.myname, "biteqhider"}, "_")
+	commit.expBitPederson = []pedersonSecret{}
+	commit.basePowPederson = []pedersonSecret{}
+	for i := uint(0); i < s.bitlen; i++ {
+		commit.expBitPederson = append(
+			commit.expBitPederson,
+			newPedersonName(strings.Join([]string{s.myname, "bit", fmt.Sprintf("%v", i)}, "_")))
+		commit.basePowPederson = append(
+			commit.basePowPederson,
+			newPedersonName(strings.Join([]string{s.myname, "base", fmt.Sprintf("%v", i)}, "_")))
+	}
+	commit.startPederson = newPedersonName(strings.Join([]string{s.myname, "start"}, "_"))
+	for i := uint(0); i < s.bitlen-1; i++ {
+		commit.interResPederson = append(
+			commit.interResPederson,
+			newPedersonName(strings.Join([]string{s.myname, "inter", fmt.Sprintf("%v", i)}, "_")))
+	}
+	return commit
+}
+
+func (s *expProofStructure) collectNames(t *nameTracker) {
+	commit := s.newCommit()
+	for _, p := range commit.pedersons() {
+		t.providePederson(p)
+	}
+	t.provideSecrets(&commit)
+	t.usePedersonSecret(s.exponent)
+	t.useSecret(s.base, s.mod, s.result)
+
+	for i, _ := range s.expBitRep {
+		s.expBitRep[i].collectNames(t)
+	}
+	s.expBitEq.collectNames(t)
+	for i, _ := range s.basePowRep {
+		s.basePowRep[i].collectNames(t)
+	}
+	for i, _ := range s.basePowRange {
+		s.basePowRange[i].collectNames(t)
+	}
+	for i, _ := range s.basePowRels {
+		s.basePowRels[i].collectNames(t)
+	}
+	s.startRep.collectNames(t)
+	for i, _ := range s.interResRep {
+		s.interResRep[i].collectNames(t)
+	}
+	for i, _ := range s.interResRange {
+		s.interResRange[i].collectNames(t)
+	}
+	for i, _ := range s.interSteps {
+		s.interSteps[i].collectNames(t)
+	}
+}
+
+func (s *expProofStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, expProofCommit) {
+	commit := s.newCommit()
 	var todo []func([]*big.Int)
 	todoOffset := new(uint32)
 
 	// Build up commit structure
 
 	// exponent bits
-	commit.nameBitEqHider = strings.Join([]string{s.myname, "biteqhider"}, "_")
 	commit.expBitEqHider = new(big.Int).Neg(secretdata.getSecret(strings.Join([]string{s.exponent, "hider"}, "_")))
 	commit.expBitEqHiderRandomizer = common.RandomBigInt(g.order)
-	commit.expBitPederson = []pedersonSecret{}
 	for i := uint(0); i < s.bitlen; i++ {
-		commit.expBitPederson = append(
-			commit.expBitPederson,
-			newPedersonSecret(
-				g,
-				strings.Join([]string{s.myname, "bit", fmt.Sprintf("%v", i)}, "_"),
-				big.NewInt(int64(secretdata.getSecret(s.exponent).Bit(int(i))))))
+		commit.expBitPederson[i] = newPedersonSecret(
+			g,
+			commit.expBitPederson[i].name,
+			big.NewInt(int64(secretdata.getSecret(s.exponent).Bit(int(i)))))
 		commit.expBitEqHider.Add(
 			commit.expBitEqHider,
 			new(big.Int).Lsh(commit.expBitPederson[i].hider, i))
@@ -304,28 +382,24 @@ func (s *expProofStructure) generateCommitmentsFromSecrets(g group, list []*big.
 	commit.expBitEqHider.Mod(commit.expBitEqHider, g.order)
 
 	// base powers
-	commit.basePowPederson = []pedersonSecret{}
 	for i := uint(0); i < s.bitlen; i++ {
-		commit.basePowPederson = append(
-			commit.basePowPederson,
-			newPedersonSecret(
-				g,
-				strings.Join([]string{s.myname, "base", fmt.Sprintf("%v", i)}, "_"),
-				new(big.Int).Exp(
-					secretdata.getSecret(s.base),
-					new(big.Int).Lsh(big.NewInt(1), i),
-					secretdata.getSecret(s.mod))))
+		commit.basePowPederson[i] = newPedersonSecret(
+			g,
+			commit.basePowPederson[i].name,
+			new(big.Int).Exp(
+				secretdata.getSecret(s.base),
+				new(big.Int).Lsh(big.NewInt(1), i),
+				secretdata.getSecret(s.mod)))
 	}
 
 	// Start pederson
 	commit.startPederson = newPedersonSecret(
 		g,
-		strings.Join([]string{s.myname, "start"}, "_"),
+		commit.startPederson.name,
 		big.NewInt(1))
 
 	// intermediate results
 	curInterRes := big.NewInt(1)
-	commit.interResPederson = []pedersonSecret{}
 	for i := uint(0); i < s.bitlen-1; i++ {
 		if secretdata.getSecret(s.exponent).Bit(int(i)) == 1 {
 			curInterRes.Mod(
@@ -340,30 +414,18 @@ func (s *expProofStructure) generateCommitmentsFromSecrets(g group, list []*big.
 				curInterRes.SetInt64(-1) // ugly(ish) hack to make comparisons to -1 work
 			}
 		}
-		commit.interResPederson = append(
-			commit.interResPederson,
-			newPedersonSecret(
-				g,
-				strings.Join([]string{s.myname, "inter", fmt.Sprintf("%v", i)}, "_"),
-				curInterRes))
+		commit.interResPederson[i] = newPedersonSecret(
+			g,
+			commit.interResPederson[i].name,
+			curInterRes)
 	}
 
 	// inner bases and secrets (this is ugly code, hopefully go2 will make this better someday)
 	baseList := []baseLookup{}
 	secretList := []secretLookup{}
-	for i, _ := range commit.expBitPederson {
-		baseList = append(baseList, &commit.expBitPederson[i])
-		secretList = append(secretList, &commit.expBitPederson[i])
-	}
-	for i, _ := range commit.basePowPederson {
-		baseList = append(baseList, &commit.basePowPederson[i])
-		secretList = append(secretList, &commit.basePowPederson[i])
-	}
-	baseList = append(baseList, &commit.startPederson)
-	secretList = append(secretList, &commit.startPederson)
-	for i, _ := range commit.interResPederson {
-		baseList = append(baseList, &commit.interResPederson[i])
-		secretList = append(secretList, &commit.interResPederson[i])
+	for _, p := range commit.pedersons() {
+		baseList = append(baseList, p)
+		secretList = append(secretList, p)
 	}
 	baseList = append(baseList, bases)
 	secretList = append(secretList, secretdata)
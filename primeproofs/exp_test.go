@@ -26,7 +26,7 @@ func TestExpProofFlow(t *testing.T) {
 	bases := newBaseMerge(&g, &aPederson, &bPederson, &nPederson, &rPederson)
 	secrets := newSecretMerge(&aPederson, &bPederson, &nPederson, &rPederson)
 
-	s := newExpProofStructure("a", "b", "n", "r", 4)
+	s := newExpProofStructure("a", "b", "n", "r", 4, &LegacySecurityParams)
 
 	if !s.isTrue(&secrets) {
 		t.Error("proof premise deemed false")
@@ -80,7 +80,7 @@ func TestExpProofFake(t *testing.T) {
 		return
 	}
 
-	s := newExpProofStructure("a", "b", "n", "r", 4)
+	s := newExpProofStructure("a", "b", "n", "r", 4, &LegacySecurityParams)
 
 	proof := s.fakeProof(g, big.NewInt(12345))
 	if !s.verifyProofStructure(big.NewInt(12345), proof) {
@@ -95,7 +95,7 @@ func TestExpProofJSON(t *testing.T) {
 		return
 	}
 
-	s := newExpProofStructure("a", "b", "n", "r", 4)
+	s := newExpProofStructure("a", "b", "n", "r", 4, &LegacySecurityParams)
 
 	proofBefore := s.fakeProof(g, big.NewInt(12345))
 	proofJSON, err := json.Marshal(proofBefore)
@@ -123,7 +123,7 @@ func TestExpProofVerifyStructure(t *testing.T) {
 		return
 	}
 
-	s := newExpProofStructure("a", "b", "n", "r", 4)
+	s := newExpProofStructure("a", "b", "n", "r", 4, &LegacySecurityParams)
 
 	proof := s.fakeProof(g, big.NewInt(12345))
 	proof.ExpBitEqResult = nil
@@ -29,11 +29,11 @@ type ExpStepProof struct {
 	Bproof     ExpStepBProof
 }
 
-func newExpStepStructure(bitname, prename, postname, mulname, modname string, bitlen uint) expStepStructure {
+func newExpStepStructure(bitname, prename, postname, mulname, modname string, bitlen uint, params *SecurityParams) expStepStructure {
 	var structure expStepStructure
 	structure.bitname = bitname
 	structure.stepa = newExpStepAStructure(bitname, prename, postname)
-	structure.stepb = newExpStepBStructure(bitname, prename, postname, mulname, modname, bitlen)
+	structure.stepb = newExpStepBStructure(bitname, prename, postname, mulname, modname, bitlen, params)
 	return structure
 }
 
@@ -45,6 +45,12 @@ func (s *expStepStructure) numCommitments() int {
 	return s.stepa.numCommitments() + s.stepb.numCommitments()
 }
 
+func (s *expStepStructure) collectNames(t *nameTracker) {
+	t.useSecret(s.bitname)
+	s.stepa.collectNames(t)
+	s.stepb.collectNames(t)
+}
+
 func (s *expStepStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, expStepCommit) {
 	var commit expStepCommit
 
@@ -22,7 +22,7 @@ func TestExpStepFlowA(t *testing.T) {
 	bases := newBaseMerge(&g, &bitPederson, &prePederson, &postPederson, &mulPederson, &modPederson)
 	secrets := newSecretMerge(&bitPederson, &prePederson, &postPederson, &mulPederson, &modPederson)
 
-	s := newExpStepStructure("bit", "pre", "post", "mul", "mod", 4)
+	s := newExpStepStructure("bit", "pre", "post", "mul", "mod", 4, &LegacySecurityParams)
 
 	if !s.isTrue(&secrets) {
 		t.Error("Proof premise rejected")
@@ -85,7 +85,7 @@ func TestExpStepFlowB(t *testing.T) {
 	bases := newBaseMerge(&g, &bitPederson, &prePederson, &postPederson, &mulPederson, &modPederson)
 	secrets := newSecretMerge(&bitPederson, &prePederson, &postPederson, &mulPederson, &modPederson)
 
-	s := newExpStepStructure("bit", "pre", "post", "mul", "mod", 4)
+	s := newExpStepStructure("bit", "pre", "post", "mul", "mod", 4, &LegacySecurityParams)
 
 	if !s.isTrue(&secrets) {
 		t.Error("Proof premise rejected")
@@ -126,7 +126,7 @@ func TestExpStepFake(t *testing.T) {
 		return
 	}
 
-	s := newExpStepStructure("bit", "pre", "post", "mul", "mod", 4)
+	s := newExpStepStructure("bit", "pre", "post", "mul", "mod", 4, &LegacySecurityParams)
 
 	proof := s.fakeProof(g, big.NewInt(12345))
 
@@ -142,7 +142,7 @@ func TestExpStepJSON(t *testing.T) {
 		return
 	}
 
-	s := newExpStepStructure("bit", "pre", "post", "mul", "mod", 4)
+	s := newExpStepStructure("bit", "pre", "post", "mul", "mod", 4, &LegacySecurityParams)
 
 	proofBefore := s.fakeProof(g, big.NewInt(12345))
 	proofJSON, err := json.Marshal(proofBefore)
@@ -170,7 +170,7 @@ func TestExpStepVerifyProofStructure(t *testing.T) {
 		return
 	}
 
-	s := newExpStepStructure("bit", "pre", "post", "mul", "mod", 4)
+	s := newExpStepStructure("bit", "pre", "post", "mul", "mod", 4, &LegacySecurityParams)
 
 	proof := s.fakeProof(g, big.NewInt(12345))
 	proof.Achallenge = nil
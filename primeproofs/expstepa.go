@@ -55,6 +55,10 @@ func (c *expStepACommit) getRandomizer(name string) *big.Int {
 	return nil
 }
 
+func (c *expStepACommit) secretNames() []string {
+	return []string{c.nameBit, c.nameEquality}
+}
+
 func newExpStepAStructure(bitname, prename, postname string) expStepAStructure {
 	var structure expStepAStructure
 	structure.bitname = bitname
@@ -89,12 +93,27 @@ func (s *expStepAStructure) numCommitments() int {
 	return s.bitRep.numCommitments() + s.equalityRep.numCommitments()
 }
 
-func (s *expStepAStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, expStepACommit) {
+// A commit carrying only the names of its secrets
+func (s *expStepAStructure) newCommit() expStepACommit {
 	var commit expStepACommit
-
-	// Build commit structure
 	commit.nameBit = strings.Join([]string{s.bitname, "hider"}, "_")
 	commit.nameEquality = strings.Join([]string{s.myname, "eqhider"}, "_")
+	return commit
+}
+
+func (s *expStepAStructure) collectNames(t *nameTracker) {
+	commit := s.newCommit()
+	t.provideSecrets(&commit)
+	t.useSecret(s.bitname)
+	t.usePedersonSecret(s.prename, s.postname)
+	s.bitRep.collectNames(t)
+	s.equalityRep.collectNames(t)
+}
+
+func (s *expStepAStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, expStepACommit) {
+	commit := s.newCommit()
+
+	// Build commit structure
 	commit.bitHiderRandomizer = common.RandomBigInt(g.order)
 	commit.equalityHider = new(big.Int).Mod(
 		new(big.Int).Sub(
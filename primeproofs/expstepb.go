@@ -63,7 +63,11 @@ func (c *expStepBCommit) getRandomizer(name string) *big.Int {
 	return nil
 }
 
-func newExpStepBStructure(bitname, prename, postname, mulname, modname string, bitlen uint) expStepBStructure {
+func (c *expStepBCommit) secretNames() []string {
+	return []string{c.bitname, c.mulname, c.mulhidername}
+}
+
+func newExpStepBStructure(bitname, prename, postname, mulname, modname string, bitlen uint, params *SecurityParams) expStepBStructure {
 	var structure expStepBStructure
 	structure.bitname = bitname
 	structure.mulname = mulname
@@ -78,7 +82,7 @@ func newExpStepBStructure(bitname, prename, postname, mulname, modname string, b
 		},
 	}
 	structure.mulRep = newPedersonRepresentationProofStructure(mulname)
-	structure.prePostMul = newMultiplicationProofStructure(mulname, prename, modname, postname, bitlen)
+	structure.prePostMul = newMultiplicationProofStructure(mulname, prename, modname, postname, bitlen, params)
 	return structure
 }
 
@@ -90,13 +94,29 @@ func (s *expStepBStructure) numCommitments() int {
 	return s.bitRep.numCommitments() + s.mulRep.numCommitments() + s.prePostMul.numCommitments()
 }
 
-func (s *expStepBStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, expStepBCommit) {
+// A commit carrying only the names of its secrets
+func (s *expStepBStructure) newCommit() expStepBCommit {
 	var commit expStepBCommit
-
-	// build up commit structure
 	commit.bitname = strings.Join([]string{s.bitname, "hider"}, "_")
 	commit.mulname = s.mulname
 	commit.mulhidername = strings.Join([]string{s.mulname, "hider"}, "_")
+	return commit
+}
+
+func (s *expStepBStructure) collectNames(t *nameTracker) {
+	commit := s.newCommit()
+	t.provideSecrets(&commit)
+	t.useSecret(s.bitname)
+	t.usePedersonSecret(s.mulname)
+	s.bitRep.collectNames(t)
+	s.mulRep.collectNames(t)
+	s.prePostMul.collectNames(t)
+}
+
+func (s *expStepBStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, expStepBCommit) {
+	commit := s.newCommit()
+
+	// build up commit structure
 	commit.mulRandomizer = common.RandomBigInt(g.order)
 	commit.mulHiderRandomizer = common.RandomBigInt(g.order)
 	commit.bitHiderRandomizer = common.RandomBigInt(g.order)
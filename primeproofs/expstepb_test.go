@@ -22,7 +22,7 @@ func TestExpStepBFlow(t *testing.T) {
 	bases := newBaseMerge(&g, &bitPederson, &prePederson, &postPederson, &mulPederson, &modPederson)
 	secrets := newSecretMerge(&bitPederson, &prePederson, &postPederson, &mulPederson, &modPederson)
 
-	s := newExpStepBStructure("bit", "pre", "post", "mul", "mod", 4)
+	s := newExpStepBStructure("bit", "pre", "post", "mul", "mod", 4, &LegacySecurityParams)
 
 	if !s.isTrue(&secrets) {
 		t.Error("Proof premis rejected")
@@ -77,7 +77,7 @@ func TestExpStepBFake(t *testing.T) {
 		return
 	}
 
-	s := newExpStepBStructure("bit", "pre", "post", "mul", "mod", 4)
+	s := newExpStepBStructure("bit", "pre", "post", "mul", "mod", 4, &LegacySecurityParams)
 
 	proof := s.fakeProof(g)
 	if !s.verifyProofStructure(proof) {
@@ -92,7 +92,7 @@ func TestExpStepBJSON(t *testing.T) {
 		return
 	}
 
-	s := newExpStepBStructure("bit", "pre", "post", "mul", "mod", 4)
+	s := newExpStepBStructure("bit", "pre", "post", "mul", "mod", 4, &LegacySecurityParams)
 
 	proofBefore := s.fakeProof(g)
 	proofJSON, err := json.Marshal(proofBefore)
@@ -121,7 +121,7 @@ func TestExpStepBVerifyStructure(t *testing.T) {
 		return
 	}
 
-	s := newExpStepBStructure("bit", "pre", "post", "mul", "mod", 4)
+	s := newExpStepBStructure("bit", "pre", "post", "mul", "mod", 4, &LegacySecurityParams)
 
 	proof := s.fakeProof(g)
 	proof.MulResult = nil
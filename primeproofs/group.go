@@ -4,8 +4,6 @@ import (
 	"github.com/privacybydesign/gabi/big"
 
 	"github.com/privacybydesign/keyproof/common"
-
-	"github.com/bwesterb/go-exptable"
 )
 
 type group struct {
@@ -14,14 +12,37 @@ type group struct {
 	g     *big.Int
 	h     *big.Int
 
-	gTable exptable.Table
-	hTable exptable.Table
+	gTable common.ExpTable
+	hTable common.ExpTable
 
 	pMod     common.FastMod
 	orderMod common.FastMod
+
+	// Collects batched range proof equations during verification
+	batch *rangeProofBatch
 }
 
+// Group versions, determining how the generators g and h are chosen
+const (
+	groupVersionLegacy = 0 // Fixed constants, independent of the prime
+	groupVersionHashed = 1 // Hashed to the group from a labeled seed and the prime
+)
+
+var groupHashedLabelG = new(big.Int).SetBytes([]byte("keyproof pederson generator g"))
+var groupHashedLabelH = new(big.Int).SetBytes([]byte("keyproof pederson generator h"))
+
 func buildGroup(prime *big.Int) (group, bool) {
+	return buildGroupVersion(prime, groupVersionLegacy)
+}
+
+// Window width of the exponentiation tables for g and h
+const groupTableWindow = 7
+
+func buildGroupVersion(prime *big.Int, version int) (group, bool) {
+	if cached, ok := lookupGroupCache(prime, version); ok {
+		return cached, true
+	}
+
 	var result group
 
 	if !prime.ProbablyPrime(80) {
@@ -35,14 +56,65 @@ func buildGroup(prime *big.Int) (group, bool) {
 		return result, false
 	}
 
-	result.g = new(big.Int).Exp(big.NewInt(0x41424344), big.NewInt(0x45464748), result.p)
-	result.h = new(big.Int).Exp(big.NewInt(0x494A4B4C), big.NewInt(0x4D4E4F50), result.p)
+	var ok bool
+	result.g, result.h, ok = groupGenerators(result.p, result.order, version)
+	if !ok {
+		return result, false
+	}
 
-	result.gTable.Compute(result.g.Value(), result.p.Value(), 7)
-	result.hTable.Compute(result.h.Value(), result.p.Value(), 7)
+	result.gTable.Compute(result.g, result.p, groupTableWindow)
+	result.hTable.Compute(result.h, result.p, groupTableWindow)
 
 	result.pMod.Set(result.p)
 	result.orderMod.Set(result.order)
 
+	// The prime may be chosen by a prover, so only groups for the convenient
+	// primes are cached, keeping the cache bounded by the prime tables
+	if isConvenientPrime(prime) {
+		storeGroupCache(result, version)
+	}
 	return result, true
 }
+
+// Determine the generators g and h for the given version, which should both
+// generate the order q subgroup
+func groupGenerators(p *big.Int, order *big.Int, version int) (*big.Int, *big.Int, bool) {
+	var g, h *big.Int
+	switch version {
+	case groupVersionLegacy:
+		g = new(big.Int).Exp(big.NewInt(0x41424344), big.NewInt(0x45464748), p)
+		h = new(big.Int).Exp(big.NewInt(0x494A4B4C), big.NewInt(0x4D4E4F50), p)
+	case groupVersionHashed:
+		g = hashToGroup(groupHashedLabelG, p)
+		h = hashToGroup(groupHashedLabelH, p)
+	default:
+		return nil, nil, false
+	}
+
+	if !hasGroupOrder(g, p, order) || !hasGroupOrder(h, p, order) {
+		return nil, nil, false
+	}
+	return g, h, true
+}
+
+// Deterministically derive an element of the quadratic residues mod p from
+// the label, by squaring a hash of the label and p. Hashes for which this
+// would give 0 or 1 are skipped.
+func hashToGroup(label *big.Int, p *big.Int) *big.Int {
+	for i := 0; ; i++ {
+		x := common.GetHashNumber(label, p, i, uint(p.BitLen()+rangeProofEpsilon))
+		x.Mod(x, p)
+		x.Exp(x, big.NewInt(2), p)
+		if x.Cmp(big.NewInt(1)) > 0 {
+			return x
+		}
+	}
+}
+
+// Check x is not 1 and x^order = 1 mod p, which for prime order means x has exactly that order
+func hasGroupOrder(x *big.Int, p *big.Int, order *big.Int) bool {
+	if x.Cmp(big.NewInt(1)) <= 0 || x.Cmp(p) >= 0 {
+		return false
+	}
+	return new(big.Int).Exp(x, order, p).Cmp(big.NewInt(1)) == 0
+}
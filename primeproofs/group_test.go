@@ -1,7 +1,10 @@
 package primeproofs
 
 import "testing"
+import "bytes"
+import "encoding/gob"
 import "github.com/privacybydesign/gabi/big"
+import "github.com/privacybydesign/keyproof/common"
 
 func TestGroupWithSafePrime(t *testing.T) {
 	group, ok := buildGroup(big.NewInt(26903))
@@ -36,3 +39,151 @@ func TestNonPrime(t *testing.T) {
 		t.Error("Failed to recognize non-prime")
 	}
 }
+
+func TestHashedGroup(t *testing.T) {
+	group, ok := buildGroupVersion(big.NewInt(26903), groupVersionHashed)
+	if !ok {
+		t.Error("Failed to build hashed group")
+		return
+	}
+	legacy, _ := buildGroup(big.NewInt(26903))
+	if group.g.Cmp(legacy.g) == 0 || group.h.Cmp(legacy.h) == 0 {
+		t.Error("Hashed generators equal to legacy generators")
+	}
+	if group.g.Cmp(group.h) == 0 {
+		t.Error("Hashed generators g and h are equal")
+	}
+	if !hasGroupOrder(group.g, group.p, group.order) || !hasGroupOrder(group.h, group.p, group.order) {
+		t.Error("Hashed generators have incorrect order")
+	}
+
+	again, _ := buildGroupVersion(big.NewInt(26903), groupVersionHashed)
+	if group.g.Cmp(again.g) != 0 || group.h.Cmp(again.h) != 0 {
+		t.Error("Hashed generators not deterministic")
+	}
+}
+
+func TestUnknownGroupVersion(t *testing.T) {
+	_, ok := buildGroupVersion(big.NewInt(26903), 42)
+	if ok {
+		t.Error("Accepted unknown group version")
+	}
+}
+
+func TestGroupOrder(t *testing.T) {
+	p := big.NewInt(26903)
+	order := big.NewInt(13451)
+	if hasGroupOrder(big.NewInt(1), p, order) {
+		t.Error("Accepted 1 as generator")
+	}
+	if hasGroupOrder(big.NewInt(0), p, order) {
+		t.Error("Accepted 0 as generator")
+	}
+	if hasGroupOrder(new(big.Int).Sub(p, big.NewInt(1)), p, order) {
+		t.Error("Accepted element of order 2 as generator")
+	}
+	if !hasGroupOrder(big.NewInt(4), p, order) {
+		t.Error("Rejected square as generator")
+	}
+}
+
+func TestGroupCache(t *testing.T) {
+	ClearGroupCache()
+	prime := findSafePrime(1024)
+	group, ok := buildGroupVersion(prime, groupVersionHashed)
+	if !ok {
+		t.Fatal("Failed to build group")
+	}
+	if _, ok := lookupGroupCache(prime, groupVersionHashed); !ok {
+		t.Error("Group not cached")
+	}
+	if _, ok := lookupGroupCache(prime, groupVersionLegacy); ok {
+		t.Error("Group cached for wrong version")
+	}
+
+	var buf bytes.Buffer
+	if err := WriteGroupCache(&buf); err != nil {
+		t.Fatal(err.Error())
+	}
+	ClearGroupCache()
+	if _, ok := lookupGroupCache(prime, groupVersionHashed); ok {
+		t.Error("Group cache not cleared")
+	}
+	if err := ReadGroupCache(&buf); err != nil {
+		t.Fatal(err.Error())
+	}
+	loaded, ok := lookupGroupCache(prime, groupVersionHashed)
+	if !ok {
+		t.Fatal("Group not loaded")
+	}
+	if loaded.g.Cmp(group.g) != 0 || loaded.h.Cmp(group.h) != 0 || loaded.order.Cmp(group.order) != 0 {
+		t.Error("Loaded group differs")
+	}
+
+	exp := common.RandomBigInt(group.order)
+	var r1, r2 big.Int
+	group.exp(&r1, "h", exp, group.p)
+	loaded.exp(&r2, "h", exp, loaded.p)
+	if r1.Cmp(&r2) != 0 {
+		t.Error("Loaded table gives different result")
+	}
+}
+
+func TestGroupCacheCorrupted(t *testing.T) {
+	ClearGroupCache()
+	prime := findSafePrime(1024)
+	buildGroupVersion(prime, groupVersionHashed)
+	var buf bytes.Buffer
+	if err := WriteGroupCache(&buf); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var entries []groupCacheEntry
+	if err := gob.NewDecoder(&buf).Decode(&entries); err != nil {
+		t.Fatal(err.Error())
+	}
+	entry := entries[0]
+	if _, err := entry.load(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Corruption is detected by the digest
+	entry.GTable = append([]byte{}, entries[0].GTable...)
+	entry.GTable[len(entry.GTable)-1] ^= 1
+	if _, err := entry.load(); err == nil {
+		t.Error("Accepted corrupted table")
+	}
+
+	// And an incorrect table with matching digest by the spot checks
+	entry.Digest = entry.digest()
+	if _, err := entry.load(); err == nil {
+		t.Error("Accepted incorrect table")
+	}
+	entry.GTable = entries[0].GTable
+
+	// Incorrect generators
+	entry.Version = groupVersionLegacy
+	entry.Digest = entry.digest()
+	if _, err := entry.load(); err == nil {
+		t.Error("Accepted incorrect generators")
+	}
+	entry.Version = groupVersionHashed
+
+	// Not a safe prime
+	entry.P = new(big.Int).Add(prime, big.NewInt(2)).Bytes()
+	entry.Digest = entry.digest()
+	if _, err := entry.load(); err == nil {
+		t.Error("Accepted non safe prime")
+	}
+}
+
+func TestGroupCacheOnlyConvenient(t *testing.T) {
+	ClearGroupCache()
+	prime := big.NewInt(26903)
+	if _, ok := buildGroupVersion(prime, groupVersionHashed); !ok {
+		t.Fatal("Failed to build group")
+	}
+	if _, ok := lookupGroupCache(prime, groupVersionHashed); ok {
+		t.Error("Cached group for prime that is not convenient")
+	}
+}
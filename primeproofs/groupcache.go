@@ -0,0 +1,198 @@
+package primeproofs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/privacybydesign/gabi/big"
+	"github.com/privacybydesign/keyproof/common"
+)
+
+// Building a group requires primality tests and computing the exponentiation
+// tables for g and h. Groups for the convenient safe primes are cached
+// process wide, keyed by prime and version, so repeated proving and
+// verification reuse them. Other primes may be chosen by a prover and are not
+// cached, which keeps the cache bounded. The cache can be written to disk and
+// loaded again later.
+
+type groupCacheKey struct {
+	prime   string
+	version int
+}
+
+var groupCache = map[groupCacheKey]group{}
+var groupCacheLock sync.RWMutex
+
+// Number of random table entries to recompute when loading a cached group
+const groupCacheSpotChecks = 8
+
+func lookupGroupCache(prime *big.Int, version int) (group, bool) {
+	groupCacheLock.RLock()
+	defer groupCacheLock.RUnlock()
+	g, ok := groupCache[groupCacheKey{prime.Text(16), version}]
+	return g, ok
+}
+
+func storeGroupCache(g group, version int) {
+	groupCacheLock.Lock()
+	defer groupCacheLock.Unlock()
+	groupCache[groupCacheKey{g.p.Text(16), version}] = g
+}
+
+// Remove all groups from the process wide group cache
+func ClearGroupCache() {
+	groupCacheLock.Lock()
+	defer groupCacheLock.Unlock()
+	groupCache = map[groupCacheKey]group{}
+}
+
+type groupCacheEntry struct {
+	Version int
+	P       []byte
+	G       []byte
+	H       []byte
+	GTable  []byte
+	HTable  []byte
+	Digest  []byte
+}
+
+func (e *groupCacheEntry) digest() []byte {
+	hash := sha256.New()
+	var version [8]byte
+	binary.BigEndian.PutUint64(version[:], uint64(e.Version))
+	hash.Write(version[:])
+	for _, field := range [][]byte{e.P, e.G, e.H, e.GTable, e.HTable} {
+		var length [8]byte
+		binary.BigEndian.PutUint64(length[:], uint64(len(field)))
+		hash.Write(length[:])
+		hash.Write(field)
+	}
+	return hash.Sum(nil)
+}
+
+// Write all groups in the process wide cache, including their tables
+func WriteGroupCache(w io.Writer) error {
+	groupCacheLock.RLock()
+	keys := make([]groupCacheKey, 0, len(groupCache))
+	for key := range groupCache {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].prime != keys[j].prime {
+			return keys[i].prime < keys[j].prime
+		}
+		return keys[i].version < keys[j].version
+	})
+
+	entries := []groupCacheEntry{}
+	for _, key := range keys {
+		g := groupCache[key]
+		gTable, err := g.gTable.MarshalBinary()
+		if err != nil {
+			groupCacheLock.RUnlock()
+			return err
+		}
+		hTable, err := g.hTable.MarshalBinary()
+		if err != nil {
+			groupCacheLock.RUnlock()
+			return err
+		}
+		entry := groupCacheEntry{
+			Version: key.version,
+			P:       g.p.Bytes(),
+			G:       g.g.Bytes(),
+			H:       g.h.Bytes(),
+			GTable:  gTable,
+			HTable:  hTable,
+		}
+		entry.Digest = entry.digest()
+		entries = append(entries, entry)
+	}
+	groupCacheLock.RUnlock()
+
+	return gob.NewEncoder(w).Encode(entries)
+}
+
+// Load groups written by WriteGroupCache into the process wide cache. All
+// groups are checked before any is added. The digests only protect against
+// corruption, so the file should come from a trusted source, such as an
+// earlier run. As a safeguard, the primes are still checked with a
+// Baillie-PSW test, the generators are rederived and some random table
+// entries are recomputed. Groups for primes that are not convenient are
+// skipped, as they would not have been cached either.
+func ReadGroupCache(r io.Reader) error {
+	var entries []groupCacheEntry
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+
+	groups := []group{}
+	for _, entry := range entries {
+		g, err := entry.load()
+		if err != nil {
+			return err
+		}
+		groups = append(groups, g)
+	}
+
+	for i, g := range groups {
+		if isConvenientPrime(g.p) {
+			storeGroupCache(g, entries[i].Version)
+		}
+	}
+	return nil
+}
+
+func (e *groupCacheEntry) load() (group, error) {
+	var result group
+
+	if !bytes.Equal(e.Digest, e.digest()) {
+		return result, errors.New("group cache entry corrupted")
+	}
+
+	result.p = new(big.Int).SetBytes(e.P)
+	result.order = new(big.Int).Rsh(result.p, 1)
+	if !result.p.ProbablyPrime(0) || !result.order.ProbablyPrime(0) {
+		return result, errors.New("group cache entry has no safe prime")
+	}
+
+	var ok bool
+	result.g, result.h, ok = groupGenerators(result.p, result.order, e.Version)
+	if !ok || result.g.Cmp(new(big.Int).SetBytes(e.G)) != 0 || result.h.Cmp(new(big.Int).SetBytes(e.H)) != 0 {
+		return result, errors.New("group cache entry has incorrect generators")
+	}
+
+	if !loadGroupTable(&result.gTable, e.GTable, result.g, result.p) ||
+		!loadGroupTable(&result.hTable, e.HTable, result.h, result.p) {
+		return result, errors.New("group cache entry has incorrect table")
+	}
+
+	result.pMod.Set(result.p)
+	result.orderMod.Set(result.order)
+	return result, nil
+}
+
+func loadGroupTable(table *common.ExpTable, data []byte, base *big.Int, p *big.Int) bool {
+	if table.UnmarshalBinary(data) != nil {
+		return false
+	}
+	if table.Window() != groupTableWindow || table.Modulus().Cmp(p) != 0 {
+		return false
+	}
+	if !table.CheckEntry(base, 0) || !table.CheckEntry(base, table.Len()-1) {
+		return false
+	}
+	for i := 0; i < groupCacheSpotChecks; i++ {
+		index := int(common.RandomBigInt(big.NewInt(int64(table.Len()))).Int64())
+		if !table.CheckEntry(base, index) {
+			return false
+		}
+	}
+	return true
+}
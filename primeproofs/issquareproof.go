@@ -8,6 +8,7 @@ import "fmt"
 type isSquareProofStructure struct {
 	n       *big.Int
 	squares []*big.Int
+	params  *SecurityParams
 
 	nRep representationProofStructure
 
@@ -23,6 +24,9 @@ type IsSquareProof struct {
 	RootsProof      []PedersonProof
 	RootsRangeProof []RangeProof
 	RootsValidProof []MultiplicationProof
+
+	// Only for DirectSquareProofs, see directsquareproof.go
+	Responses []*big.Int `json:",omitempty"`
 }
 
 type isSquareProofCommit struct {
@@ -32,9 +36,25 @@ type isSquareProofCommit struct {
 
 	rootRangeCommit []rangeCommit
 	rootValidCommit []multiplicationProofCommit
+
+	directRoots       []*big.Int
+	directRandomizers []*big.Int
+}
+
+// The pederson commitments of the commit, all provided as bases and secrets
+func (c *isSquareProofCommit) pedersons() []*pedersonSecret {
+	result := []*pedersonSecret{}
+	for i, _ := range c.squares {
+		result = append(result, &c.squares[i])
+	}
+	for i, _ := range c.roots {
+		result = append(result, &c.roots[i])
+	}
+	result = append(result, &c.n)
+	return result
 }
 
-func newIsSquareProofStructure(N *big.Int, Squares []*big.Int) isSquareProofStructure {
+func newIsSquareProofStructure(N *big.Int, Squares []*big.Int, params *SecurityParams) isSquareProofStructure {
 	var result isSquareProofStructure
 
 	// Copy over primary values
@@ -43,6 +63,10 @@ func newIsSquareProofStructure(N *big.Int, Squares []*big.Int) isSquareProofStru
 	for i, val := range Squares {
 		result.squares[i] = new(big.Int).Set(val)
 	}
+	result.params = params
+	if params.DirectSquareProofs {
+		return result
+	}
 
 	// Setup representation proof of N
 	result.nRep = representationProofStructure{
@@ -82,7 +106,8 @@ func newIsSquareProofStructure(N *big.Int, Squares []*big.Int) isSquareProofStru
 		result.rootsRange[i] = newPedersonRangeProofStructure(
 			strings.Join([]string{"r", fmt.Sprintf("%v", i)}, "_"),
 			0,
-			uint(N.BitLen()))
+			uint(N.BitLen()),
+			params)
 	}
 
 	// Setup proofs that the roots are roots
@@ -93,7 +118,8 @@ func newIsSquareProofStructure(N *big.Int, Squares []*big.Int) isSquareProofStru
 			strings.Join([]string{"r", fmt.Sprintf("%v", i)}, "_"),
 			"N",
 			strings.Join([]string{"s", fmt.Sprintf("%v", i)}, "_"),
-			uint(N.BitLen()))
+			uint(N.BitLen()),
+			params)
 	}
 
 	return result
@@ -109,6 +135,10 @@ func (s *isSquareProofStructure) numRangeProofs() int {
 }
 
 func (s *isSquareProofStructure) numCommitments() int {
+	if s.params.DirectSquareProofs {
+		return 1 + len(s.squares) + s.params.DirectSquareProofIters
+	}
+
 	// Constants
 	res := 1 + len(s.squares)
 	// Pedersons
@@ -129,37 +159,72 @@ func (s *isSquareProofStructure) numCommitments() int {
 	return res
 }
 
-func (s *isSquareProofStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, P *big.Int, Q *big.Int) ([]*big.Int, isSquareProofCommit) {
+// A commit carrying only the names of its pederson commitments
+func (s *isSquareProofStructure) newCommit() isSquareProofCommit {
 	var commit isSquareProofCommit
+	commit.squares = make([]pedersonSecret, len(s.squares))
+	commit.roots = make([]pedersonSecret, len(s.squares))
+	for i, _ := range s.squares {
+		commit.squares[i] = newPedersonName(strings.Join([]string{"s", fmt.Sprintf("%v", i)}, "_"))
+		commit.roots[i] = newPedersonName(strings.Join([]string{"r", fmt.Sprintf("%v", i)}, "_"))
+	}
+	commit.n = newPedersonName("N")
+	return commit
+}
+
+func (s *isSquareProofStructure) collectNames(t *nameTracker) {
+	if s.params.DirectSquareProofs {
+		// Doesn't use the Pederson group
+		return
+	}
+
+	commit := s.newCommit()
+	for _, p := range commit.pedersons() {
+		t.providePederson(p)
+	}
+
+	s.nRep.collectNames(t)
+	for i, _ := range s.squaresRep {
+		s.squaresRep[i].collectNames(t)
+	}
+	for i, _ := range s.rootsRep {
+		s.rootsRep[i].collectNames(t)
+	}
+	for i, _ := range s.rootsRange {
+		s.rootsRange[i].collectNames(t)
+	}
+	for i, _ := range s.rootsValid {
+		s.rootsValid[i].collectNames(t)
+	}
+}
+
+func (s *isSquareProofStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, P *big.Int, Q *big.Int) ([]*big.Int, isSquareProofCommit) {
+	if s.params.DirectSquareProofs {
+		return s.generateDirectCommitmentsFromSecrets(list, P, Q)
+	}
+
+	commit := s.newCommit()
 
 	// Build up the secrets
-	commit.squares = make([]pedersonSecret, len(s.squares))
 	for i, val := range s.squares {
-		commit.squares[i] = newPedersonSecret(g, strings.Join([]string{"s", fmt.Sprintf("%v", i)}, "_"), val)
+		commit.squares[i] = newPedersonSecret(g, commit.squares[i].name, val)
 	}
-	commit.roots = make([]pedersonSecret, len(s.squares))
 	for i, val := range s.squares {
 		root, ok := common.ModSqrt(val, []*big.Int{P, Q})
 		if !ok {
 			panic("Incorrect key")
 		}
-		commit.roots[i] = newPedersonSecret(g, strings.Join([]string{"r", fmt.Sprintf("%v", i)}, "_"), root)
+		commit.roots[i] = newPedersonSecret(g, commit.roots[i].name, root)
 	}
-	commit.n = newPedersonSecret(g, "N", s.n)
+	commit.n = newPedersonSecret(g, commit.n.name, s.n)
 
 	// Build up bases and secrets (this is ugly code, hopefully go2 will make this better someday)
 	var baseList = []baseLookup{}
 	var secretList = []secretLookup{}
-	for i, _ := range commit.squares {
-		baseList = append(baseList, &commit.squares[i])
-		secretList = append(secretList, &commit.squares[i])
-	}
-	for i, _ := range commit.roots {
-		baseList = append(baseList, &commit.roots[i])
-		secretList = append(secretList, &commit.roots[i])
+	for _, p := range commit.pedersons() {
+		baseList = append(baseList, p)
+		secretList = append(secretList, p)
 	}
-	baseList = append(baseList, &commit.n)
-	secretList = append(secretList, &commit.n)
 	baseList = append(baseList, &g)
 	bases := newBaseMerge(baseList...)
 	secrets := newSecretMerge(secretList...)
@@ -196,6 +261,10 @@ func (s *isSquareProofStructure) generateCommitmentsFromSecrets(g group, list []
 }
 
 func (s *isSquareProofStructure) buildProof(g group, challenge *big.Int, commit isSquareProofCommit) IsSquareProof {
+	if s.params.DirectSquareProofs {
+		return s.buildDirectProof(challenge, commit)
+	}
+
 	// Build up secrets (this is ugly code, hopefully go2 will make this better someday)
 	var secretList = []secretLookup{}
 	for i, _ := range commit.squares {
@@ -231,6 +300,12 @@ func (s *isSquareProofStructure) buildProof(g group, challenge *big.Int, commit
 }
 
 func (s *isSquareProofStructure) verifyProofStructure(proof IsSquareProof) bool {
+	if s.params.DirectSquareProofs {
+		return s.verifyDirectProofStructure(proof)
+	}
+	if proof.Responses != nil {
+		return false
+	}
 	if !proof.NProof.verifyStructure() {
 		return false
 	}
@@ -256,6 +331,10 @@ func (s *isSquareProofStructure) verifyProofStructure(proof IsSquareProof) bool
 }
 
 func (s *isSquareProofStructure) generateCommitmentsFromProof(g group, list []*big.Int, challenge *big.Int, proof IsSquareProof) []*big.Int {
+	if s.params.DirectSquareProofs {
+		return s.generateDirectCommitmentsFromProof(list, challenge, proof)
+	}
+
 	// Setup names in pederson proofs
 	proof.NProof.setName("N")
 	for i, _ := range s.squares {
@@ -17,7 +17,7 @@ func TestIsSquareProof(t *testing.T) {
 
 	Follower.(*TestFollower).count = 0
 
-	s := newIsSquareProofStructure(big.NewInt(p*q), []*big.Int{big.NewInt(a), big.NewInt(b)})
+	s := newIsSquareProofStructure(big.NewInt(p*q), []*big.Int{big.NewInt(a), big.NewInt(b)}, &LegacySecurityParams)
 
 	listSecret, commit := s.generateCommitmentsFromSecrets(g, []*big.Int{}, big.NewInt(p), big.NewInt(q))
 
@@ -60,7 +60,7 @@ func TestIsSquareProofStructure(t *testing.T) {
 		return
 	}
 
-	s := newIsSquareProofStructure(big.NewInt(p*q), []*big.Int{big.NewInt(a), big.NewInt(b)})
+	s := newIsSquareProofStructure(big.NewInt(p*q), []*big.Int{big.NewInt(a), big.NewInt(b)}, &LegacySecurityParams)
 	_, commit := s.generateCommitmentsFromSecrets(g, []*big.Int{}, big.NewInt(p), big.NewInt(q))
 	proof := s.buildProof(g, big.NewInt(12345), commit)
 
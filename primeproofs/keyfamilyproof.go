@@ -0,0 +1,250 @@
+package primeproofs
+
+import "github.com/privacybydesign/keyproof/common"
+import "github.com/privacybydesign/gabi/big"
+import "fmt"
+import "math"
+
+// Proof that a sequence of keys, e.g. the successive counters of an issuer,
+// are all valid. The keys share a single group and Fiat-Shamir challenge, so
+// the group only needs to be set up once, and when verifying all range proofs
+// are checked in a single batch.
+//
+// A cheating prover only needs to get a single invalid key through, so the
+// soundness is that of the weakest key, see SecurityEstimate.
+
+type KeyFamilyProofStructure struct {
+	keys   []ValidKeyProofStructure
+	params *SecurityParams
+	domain *big.Int
+}
+
+type KeyFamilyProof struct {
+	Challenge  *big.Int
+	GroupPrime *big.Int
+
+	// Determines how the group generators are chosen
+	GroupVersion int
+
+	// Name of the security parameter set used, absent for legacy-80
+	SecurityParams string `json:",omitempty"`
+
+	// Proofs for the individual keys, without group and challenge
+	Keys []ValidKeyProof
+}
+
+// Domain separating key family proofs from proofs for a single key
+var keyFamilyProofDomain = new(big.Int).SetBytes([]byte("key-family"))
+
+// Combine the structures of the keys in the family, which should all use the
+// same security parameters. The verifier policies of the individual keys are
+// all applied.
+func NewKeyFamilyProofStructure(keys ...ValidKeyProofStructure) KeyFamilyProofStructure {
+	if len(keys) == 0 {
+		panic("Key family without keys")
+	}
+
+	var structure KeyFamilyProofStructure
+	structure.keys = keys
+	structure.params = keys[0].params
+	structure.domain = keyFamilyProofDomain
+	for _, key := range keys {
+		if key.params != structure.params {
+			panic("Keys in family use different security parameters")
+		}
+	}
+	return structure
+}
+
+func (s *KeyFamilyProofStructure) numRangeProofs() int {
+	result := 0
+	for i := range s.keys {
+		result += s.keys[i].numRangeProofs()
+	}
+	return result
+}
+
+// The group prime should be large enough for every key in the family
+func (s *KeyFamilyProofStructure) groupPrimeSize() int {
+	result := 0
+	for _, key := range s.keys {
+		if size := s.params.groupPrimeSize(key.n.BitLen()); size > result {
+			result = size
+		}
+	}
+	return result
+}
+
+// Check a convenient safe prime is available as group prime, see
+// FindConvenientSafePrime
+func (s *KeyFamilyProofStructure) CheckGroupPrime() error {
+	_, err := FindConvenientSafePrime(s.groupPrimeSize())
+	return err
+}
+
+func (s *KeyFamilyProofStructure) BuildProof(Pprimes []*big.Int, Qprimes []*big.Int) KeyFamilyProof {
+	return s.BuildProofWithDiscreteLogs(Pprimes, Qprimes, nil)
+}
+
+// Build the proof, also proving the discrete logs for the keys for which they
+// are given, see ValidKeyProofStructure.BuildProofWithDiscreteLogs. Logs may
+// be nil, or contain nil for the keys without them.
+func (s *KeyFamilyProofStructure) BuildProofWithDiscreteLogs(Pprimes []*big.Int, Qprimes []*big.Int, logs [][]*big.Int) KeyFamilyProof {
+	if len(Pprimes) != len(s.keys) || len(Qprimes) != len(s.keys) {
+		panic("Incorrect number of secret keys")
+	}
+	if logs != nil && len(logs) != len(s.keys) {
+		panic("Incorrect number of discrete logs")
+	}
+	for i := range s.keys {
+		if report := s.keys[i].Validate(); !report.Ok() {
+			panic(fmt.Sprintf("Invalid proof structure: %v", report))
+		}
+	}
+
+	// Generate proof group
+	Follower.StepStart("Generating group prime", 0)
+	GroupPrime := findSafePrime(s.groupPrimeSize())
+	g, gok := buildGroupVersion(GroupPrime, groupVersionHashed)
+	if !gok {
+		panic("Safe prime generated by gabi was not a safe prime!?")
+	}
+	Follower.StepDone()
+
+	Follower.StepStart("Generating commitments", s.numRangeProofs())
+
+	// Build up commitment list
+	var list []*big.Int
+	commits := make([]validKeyProofCommit, len(s.keys))
+	list = append(list, s.domain)
+	list = append(list, GroupPrime)
+	list = appendGroupVersion(list, groupVersionHashed)
+	list = appendSecurityParams(list, s.params)
+	list = append(list, big.NewInt(int64(len(s.keys))))
+	for i := range s.keys {
+		var keyLogs []*big.Int
+		if logs != nil {
+			keyLogs = logs[i]
+		}
+		list, commits[i] = s.keys[i].generateCommitmentsFromSecrets(g, list, Pprimes[i], Qprimes[i], keyLogs)
+	}
+	Follower.StepDone()
+
+	Follower.StepStart("Generating proof", 0)
+	// Calculate challenge
+	challenge := common.HashCommit(list)
+
+	// Calculate proofs
+	var proof KeyFamilyProof
+	proof.Challenge = challenge
+	proof.GroupPrime = GroupPrime
+	proof.GroupVersion = groupVersionHashed
+	if s.params != &LegacySecurityParams {
+		proof.SecurityParams = s.params.Name
+	}
+	proof.Keys = make([]ValidKeyProof, len(s.keys))
+	for i := range s.keys {
+		proof.Keys[i] = s.keys[i].buildProof(g, challenge, commits[i])
+	}
+	Follower.StepDone()
+
+	return proof
+}
+
+func (s *KeyFamilyProofStructure) VerifyProof(proof KeyFamilyProof) bool {
+	// Check proof structure
+	Follower.StepStart("Verifying structure", 0)
+	defer Follower.StepDone()
+	minPrimeSize := s.groupPrimeSize()
+	if proof.GroupPrime == nil || proof.GroupPrime.BitLen() < minPrimeSize {
+		return false
+	}
+	if proof.GroupVersion != groupVersionHashed {
+		return false
+	}
+	recordedParams := proof.SecurityParams
+	if recordedParams == "" {
+		recordedParams = LegacySecurityParams.Name
+	}
+	if recordedParams != s.params.Name {
+		return false
+	}
+	if proof.Challenge == nil || len(proof.Keys) != len(s.keys) {
+		return false
+	}
+	for i := range s.keys {
+		if !s.keys[i].policy.allowsGroupPrime(proof.GroupPrime, minPrimeSize) {
+			return false
+		}
+		if !s.keys[i].verifyProofStructure(proof.Challenge, proof.Keys[i]) {
+			return false
+		}
+	}
+	if !proof.GroupPrime.ProbablyPrime(80) || !new(big.Int).Rsh(proof.GroupPrime, 1).ProbablyPrime(80) {
+		return false
+	}
+	Follower.StepDone()
+
+	Follower.StepStart("Rebuilding commitments", s.numRangeProofs())
+
+	// Rebuild group
+	g, gok := buildGroupVersion(proof.GroupPrime, proof.GroupVersion)
+	if !gok {
+		return false
+	}
+	if s.params.BatchedRangeProofs && !s.params.LargeChallengeRangeProofs {
+		g.batch = newRangeProofBatch(s.params.RangeProofBatchBits)
+	}
+
+	// Build up commitment list
+	var list []*big.Int
+	list = append(list, s.domain)
+	list = append(list, proof.GroupPrime)
+	list = appendGroupVersion(list, proof.GroupVersion)
+	list = appendSecurityParams(list, s.params)
+	list = append(list, big.NewInt(int64(len(s.keys))))
+	for i := range s.keys {
+		list = s.keys[i].generateCommitmentsFromProof(g, list, proof.Challenge, proof.Keys[i])
+	}
+
+	Follower.StepDone()
+
+	Follower.StepStart("Verifying proof", 0)
+
+	// Check challenge
+	if proof.Challenge.Cmp(common.HashCommit(list)) != 0 {
+		return false
+	}
+
+	// And all batched range proofs, of all keys at once
+	if g.batch != nil && !g.batch.verify(&g) {
+		return false
+	}
+
+	// And the QSPP proofs
+	for i := range s.keys {
+		if !quasiSafePrimeProductVerifyProof(s.keys[i].n, proof.Challenge, proof.Keys[i].QSPPproof, s.params) {
+			return false
+		}
+	}
+	return true
+}
+
+// The soundness is that of the weakest key, as a cheating prover needs to
+// get only one invalid key accepted, while the zero-knowledge distances of all
+// keys add up.
+func (s *KeyFamilyProofStructure) SecurityEstimate() SecurityEstimate {
+	var estimate SecurityEstimate
+	estimate.SoundnessBits = math.Inf(1)
+	var zeroKnowledge []float64
+	for i := range s.keys {
+		keyEstimate := s.keys[i].SecurityEstimate()
+		if keyEstimate.SoundnessBits < estimate.SoundnessBits {
+			estimate.SoundnessBits = keyEstimate.SoundnessBits
+			estimate.Components = keyEstimate.Components
+		}
+		zeroKnowledge = append(zeroKnowledge, keyEstimate.ZeroKnowledgeBits)
+	}
+	estimate.ZeroKnowledgeBits = combineBits(zeroKnowledge)
+	return estimate
+}
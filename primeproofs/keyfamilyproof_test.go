@@ -0,0 +1,99 @@
+package primeproofs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/privacybydesign/gabi/big"
+)
+
+// Two keys, the second with an extra base
+func keyFamilyTestStructures(opts ...ValidKeyProofOption) []ValidKeyProofStructure {
+	const n1 = 26903 * 27803
+	const n2 = 23039 * 25307
+	return []ValidKeyProofStructure{
+		NewValidKeyProofStructure(big.NewInt(n1), big.NewInt(36), big.NewInt(49), []*big.Int{big.NewInt(64)}, opts...),
+		NewValidKeyProofStructure(big.NewInt(n2), big.NewInt(36), big.NewInt(49), []*big.Int{big.NewInt(64), big.NewInt(81)}, opts...),
+	}
+}
+
+func TestKeyFamilyProof(t *testing.T) {
+	keys := keyFamilyTestStructures(WithSecurityParams("fast-test"))
+	s := NewKeyFamilyProofStructure(keys...)
+
+	Follower.(*TestFollower).count = 0
+	proofBefore := s.BuildProof([]*big.Int{big.NewInt(13451), big.NewInt(11519)}, []*big.Int{big.NewInt(13901), big.NewInt(12653)})
+	if Follower.(*TestFollower).count != s.numRangeProofs() {
+		t.Error("Logging is off GenerateCommitmentsFromSecrets")
+	}
+
+	proofJSON, err := json.Marshal(proofBefore)
+	if err != nil {
+		t.Errorf("error during json marshal: %s", err.Error())
+		return
+	}
+	var proof KeyFamilyProof
+	err = json.Unmarshal(proofJSON, &proof)
+	if err != nil {
+		t.Errorf("error during json unmarshal: %s", err.Error())
+		return
+	}
+
+	Follower.(*TestFollower).count = 0
+	if !s.VerifyProof(proof) {
+		t.Error("Key family proof rejected")
+	}
+	if Follower.(*TestFollower).count != s.numRangeProofs() {
+		t.Error("Logging is off on GenerateCommitmentsFromProof")
+	}
+
+	// The keys are bound in order
+	swapped := NewKeyFamilyProofStructure(keys[1], keys[0])
+	if swapped.VerifyProof(proof) {
+		t.Error("Key family proof accepted for keys in different order")
+	}
+	single := NewKeyFamilyProofStructure(keys[0])
+	if single.VerifyProof(proof) {
+		t.Error("Key family proof accepted for part of the keys")
+	}
+
+	// The proofs of individual keys are not standalone proofs
+	keyProof := proof.Keys[0]
+	keyProof.Challenge = proof.Challenge
+	keyProof.GroupPrime = proof.GroupPrime
+	keyProof.GroupVersion = proof.GroupVersion
+	keyProof.SecurityParams = proof.SecurityParams
+	if keys[0].VerifyProof(keyProof) {
+		t.Error("Proof of key in family accepted as standalone proof")
+	}
+
+	proof.Keys[1].PQNRel = new(big.Int).Add(proof.Keys[1].PQNRel, big.NewInt(1))
+	if s.VerifyProof(proof) {
+		t.Error("Key family proof with incorrect key proof accepted")
+	}
+}
+
+func TestKeyFamilyProofSecurityParams(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Key family with different security parameters accepted")
+		}
+	}()
+	keys := keyFamilyTestStructures(WithSecurityParams("fast-test"))
+	NewKeyFamilyProofStructure(keys[0], keyFamilyTestStructures()[1])
+}
+
+func TestKeyFamilySecurityEstimate(t *testing.T) {
+	keys := keyFamilyTestStructures()
+	s := NewKeyFamilyProofStructure(keys...)
+	estimate := s.SecurityEstimate()
+
+	// The key with more bases has more range proofs, so the weaker estimate
+	weakest := keys[1].SecurityEstimate()
+	if estimate.SoundnessBits != weakest.SoundnessBits {
+		t.Errorf("Key family soundness %v differs from weakest key %v", estimate.SoundnessBits, weakest.SoundnessBits)
+	}
+	if estimate.ZeroKnowledgeBits >= keys[0].SecurityEstimate().ZeroKnowledgeBits {
+		t.Error("Key family zero-knowledge not below that of single key")
+	}
+}
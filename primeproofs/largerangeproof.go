@@ -0,0 +1,195 @@
+package primeproofs
+
+import "github.com/privacybydesign/keyproof/common"
+import "github.com/privacybydesign/gabi/big"
+
+// Large challenge range proofs
+//
+// Instead of RangeProofIters iterations with a binary challenge, these use a
+// single iteration with the full (256 bit) challenge. With a large challenge
+// the prime order of the pederson group no longer bounds the extracted
+// witness, so the range secret is additionally committed to as an integer in
+// a group of hidden order, with the same (unreduced) response used in both
+// groups. Under the strong RSA assumption this shows the range secret x
+// satisfies |x| < 2^(l2+RangeProofEpsilon+largeRangeProofChallengeSize+1).
+//
+// The hidden order group is Z_N* for the RSA-2048 factoring challenge
+// modulus, whose factorization is not known to anyone.
+//
+// As big.Int json marshalling does not support negative numbers, the integer
+// results are stored with a fixed offset added.
+
+const largeRangeProofChallengeSize = 256
+
+var integerCommitModulus, _ = new(big.Int).SetString(
+	"25195908475657893494027183240048398571429282126204032027777137836043662020707595556264018525880784406918290641249515082189298559149176184502808489120072844992687392807287776735971418347270261896375014971824691165077613379859095700097330459748808428401797429100642458691817195118746121515172654632282216869987549182422433637259085141865462043576798423387184774447920739934236584823824281198163815010674810451660377306056201619676256133844143603833904414952634432190114657544454178424020924616515723350778707749817125772467962926386356373289912154831438167899885040445364023527381951378636564391212010397122822120720357",
+	10)
+
+// Bases for the integer commitments, squares of hashes so nobody knows their relation
+var integerCommitG = integerCommitBase(0x494e5447)
+var integerCommitH = integerCommitBase(0x494e5448)
+
+func integerCommitBase(label int) *big.Int {
+	base := common.GetHashNumber(big.NewInt(int64(label)), nil, 0, uint(integerCommitModulus.BitLen()+rangeProofEpsilon))
+	base.Mod(base, integerCommitModulus)
+	return base.Exp(base, big.NewInt(2), integerCommitModulus)
+}
+
+// Size of the range secret used for hiding. Secrets derived from hash numbers
+// always have at least 256 bits, even when l2 is smaller for small moduli.
+func (s *rangeProofStructure) largeSecretBits() uint {
+	if s.l2 < 256 {
+		return 256
+	}
+	return s.l2
+}
+
+// Offset added to the range secret result, which satisfies |result| < offset
+func (s *rangeProofStructure) largeResultOffset() *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), s.largeSecretBits()+s.params.RangeProofEpsilon+largeRangeProofChallengeSize+1)
+}
+
+// Offset added to the integer hider result
+func (s *rangeProofStructure) integerHiderResultOffset() *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(integerCommitModulus.BitLen())+s.params.RangeProofEpsilon+largeRangeProofChallengeSize)
+}
+
+// Computes G^x H^r mod the integer commitment modulus, for possibly negative x and r
+func integerCommit(x, r *big.Int) *big.Int {
+	gPart := new(big.Int).Exp(integerCommitG, x, integerCommitModulus)
+	hPart := new(big.Int).Exp(integerCommitH, r, integerCommitModulus)
+	return gPart.Mod(gPart.Mul(gPart, hPart), integerCommitModulus)
+}
+
+func (s *rangeProofStructure) generateLargeCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, rangeCommit) {
+	var commit rangeCommitSecretLookup
+
+	// Randomizers for the range secret are chosen large enough to statistically hide
+	// challenge*secret, others uniformly modulo the group order
+	genLimit := new(big.Int).Lsh(big.NewInt(1), s.largeSecretBits()+s.params.RangeProofEpsilon+largeRangeProofChallengeSize+1)
+	genOffset := new(big.Int).Lsh(big.NewInt(1), s.largeSecretBits()+s.params.RangeProofEpsilon+largeRangeProofChallengeSize)
+	commit.commits = map[string][]*big.Int{}
+	for _, curRhs := range s.rhs {
+		var rval *big.Int
+		if curRhs.secret == s.rangeSecret {
+			rval = common.RandomBigInt(genLimit)
+			rval.Sub(rval, genOffset)
+		} else {
+			rval = common.RandomBigInt(g.order)
+		}
+		commit.commits[curRhs.secret] = []*big.Int{rval}
+	}
+
+	// Integer commitment to the range secret
+	hiderSize := uint(integerCommitModulus.BitLen()) + s.params.RangeProofEpsilon
+	commit.integerHider = common.RandomBigInt(new(big.Int).Lsh(big.NewInt(1), hiderSize))
+	commit.integerHiderRandomizer = common.RandomBigInt(new(big.Int).Lsh(big.NewInt(1), hiderSize+s.params.RangeProofEpsilon+largeRangeProofChallengeSize))
+	commit.integerCommit = integerCommit(secretdata.getSecret(s.rangeSecret), commit.integerHider)
+
+	// Construct the commitments
+	secretMerge := newSecretMerge(&commit, secretdata)
+	list = s.representationProofStructure.generateCommitmentsFromSecrets(g, list, bases, &secretMerge)
+	list = append(list, commit.integerCommit)
+	list = append(list, integerCommit(commit.commits[s.rangeSecret][0], commit.integerHiderRandomizer))
+
+	Follower.Tick()
+
+	return list, commit.rangeCommit
+}
+
+func (s *rangeProofStructure) buildLargeProof(g group, challenge *big.Int, commit rangeCommit, secretdata secretLookup) RangeProof {
+	proof := RangeProof{Results: map[string][]*big.Int{}}
+	for name, clist := range commit.commits {
+		res := new(big.Int).Sub(clist[0], new(big.Int).Mul(challenge, secretdata.getSecret(name)))
+		if name == s.rangeSecret {
+			res.Add(res, s.largeResultOffset())
+		} else {
+			res.Mod(res, g.order)
+		}
+		proof.Results[name] = []*big.Int{res}
+	}
+
+	proof.IntegerCommit = commit.integerCommit
+	proof.IntegerHiderResult = new(big.Int).Add(
+		new(big.Int).Sub(
+			commit.integerHiderRandomizer,
+			new(big.Int).Mul(challenge, commit.integerHider)),
+		s.integerHiderResultOffset())
+
+	return proof
+}
+
+func (s *rangeProofStructure) fakeLargeProof(g group) RangeProof {
+	genLimit := new(big.Int).Lsh(s.largeResultOffset(), 1)
+
+	proof := RangeProof{Results: map[string][]*big.Int{}}
+	for _, curRhs := range s.rhs {
+		if curRhs.secret == s.rangeSecret {
+			proof.Results[curRhs.secret] = []*big.Int{common.RandomBigInt(genLimit)}
+		} else {
+			proof.Results[curRhs.secret] = []*big.Int{common.RandomBigInt(g.order)}
+		}
+	}
+
+	proof.IntegerCommit = integerCommit(common.RandomBigInt(genLimit), common.RandomBigInt(integerCommitModulus))
+	proof.IntegerHiderResult = common.RandomBigInt(new(big.Int).Lsh(s.integerHiderResultOffset(), 1))
+
+	return proof
+}
+
+func (s *rangeProofStructure) verifyLargeProofStructure(proof RangeProof) bool {
+	// Validate presence of all values
+	if proof.Results == nil || proof.IntegerCommit == nil || proof.IntegerHiderResult == nil {
+		return false
+	}
+	for _, curRhs := range s.rhs {
+		rlist, ok := proof.Results[curRhs.secret]
+		if !ok || len(rlist) != 1 || rlist[0] == nil {
+			return false
+		}
+	}
+
+	// The integer commitment should be a unit
+	if proof.IntegerCommit.Sign() <= 0 || proof.IntegerCommit.Cmp(integerCommitModulus) >= 0 {
+		return false
+	}
+	if new(big.Int).GCD(nil, nil, proof.IntegerCommit, integerCommitModulus).Cmp(big.NewInt(1)) != 0 {
+		return false
+	}
+
+	// Validate size of the secret result, taking into account the offset
+	rangeResult := proof.Results[s.rangeSecret][0]
+	if rangeResult.Sign() < 0 || rangeResult.Cmp(new(big.Int).Lsh(s.largeResultOffset(), 1)) >= 0 {
+		return false
+	}
+	if proof.IntegerHiderResult.Sign() < 0 {
+		return false
+	}
+
+	return true
+}
+
+func (s *rangeProofStructure) generateLargeCommitmentsFromProof(g group, list []*big.Int, challenge *big.Int, bases baseLookup, proof RangeProof) []*big.Int {
+	// Build resultLookup
+	resultLookup := rangeProofResultLookup{map[string]*big.Int{}}
+	for name, rlist := range proof.Results {
+		resultLookup.Results[name] = rlist[0]
+	}
+	rangeResult := new(big.Int).Sub(proof.Results[s.rangeSecret][0], s.largeResultOffset())
+	resultLookup.Results[s.rangeSecret] = rangeResult
+	hiderResult := new(big.Int).Sub(proof.IntegerHiderResult, s.integerHiderResultOffset())
+
+	// Regenerate the pederson group commitment
+	list = s.representationProofStructure.generateCommitmentsFromProof(g, list, challenge, bases, &resultLookup)
+
+	// And the integer commitment
+	icCommit := new(big.Int).Exp(proof.IntegerCommit, challenge, integerCommitModulus)
+	icCommit.Mul(icCommit, integerCommit(rangeResult, hiderResult))
+	icCommit.Mod(icCommit, integerCommitModulus)
+	list = append(list, proof.IntegerCommit)
+	list = append(list, icCommit)
+
+	Follower.Tick()
+
+	return list
+}
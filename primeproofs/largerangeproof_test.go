@@ -0,0 +1,179 @@
+package primeproofs
+
+import "testing"
+import "encoding/json"
+import "github.com/privacybydesign/gabi/big"
+
+func largeRangeTestSetup(t *testing.T, x int64) (group, rangeProofStructure, RangeTestSecret, RangeTestCommit) {
+	g, gok := buildGroup(big.NewInt(26903))
+	if !gok {
+		t.Fatal("Failed to setup group for Range proof testing")
+	}
+
+	var s rangeProofStructure
+	s.lhs = []lhsContribution{
+		lhsContribution{"c", big.NewInt(1)},
+	}
+	s.rhs = []rhsContribution{
+		rhsContribution{"g", "x", 1},
+		rhsContribution{"h", "xh", 1},
+	}
+	s.rangeSecret = "x"
+	s.l1 = 3
+	s.l2 = 3
+	s.params = &FastTestSecurityParams
+
+	var secret RangeTestSecret
+	secret.secrets = map[string]*big.Int{
+		"x":  big.NewInt(x),
+		"xh": big.NewInt(21),
+	}
+	secret.randomizers = map[string]*big.Int{}
+
+	var commit RangeTestCommit
+	commit.commits = map[string]*big.Int{
+		"c": new(big.Int).Mod(
+			new(big.Int).Mul(
+				new(big.Int).Exp(g.g, big.NewInt(x), g.p),
+				new(big.Int).Exp(g.h, big.NewInt(21), g.p)),
+			g.p),
+	}
+
+	return g, s, secret, commit
+}
+
+func TestLargeRangeProofFlow(t *testing.T) {
+	g, s, secret, commit := largeRangeTestSetup(t, 5)
+	bases := newBaseMerge(&g, &commit)
+	challenge := new(big.Int).Lsh(big.NewInt(12345), 240)
+
+	Follower.(*TestFollower).count = 0
+
+	listSecret, rpcommit := s.generateCommitmentsFromSecrets(g, []*big.Int{}, &bases, &secret)
+
+	if len(listSecret) != s.numCommitments() {
+		t.Error("NumCommitments is off")
+	}
+	if Follower.(*TestFollower).count != s.numRangeProofs() {
+		t.Error("Logging is off GenerateCommitmentsFromSecrets")
+	}
+	Follower.(*TestFollower).count = 0
+
+	proof := s.buildProof(g, challenge, rpcommit, &secret)
+
+	if !s.verifyProofStructure(proof) {
+		t.Error("Proof structure rejected")
+		return
+	}
+
+	listProof := s.generateCommitmentsFromProof(g, []*big.Int{}, challenge, &bases, proof)
+
+	if Follower.(*TestFollower).count != s.numRangeProofs() {
+		t.Error("Logging is off on GenerateCommitmentsFromProof")
+	}
+
+	if !listCmp(listSecret, listProof) {
+		t.Error("Commitment lists disagree")
+	}
+}
+
+func TestLargeRangeProofWrongChallenge(t *testing.T) {
+	g, s, secret, commit := largeRangeTestSetup(t, 5)
+	bases := newBaseMerge(&g, &commit)
+
+	listSecret, rpcommit := s.generateCommitmentsFromSecrets(g, []*big.Int{}, &bases, &secret)
+	proof := s.buildProof(g, big.NewInt(12345), rpcommit, &secret)
+	listProof := s.generateCommitmentsFromProof(g, []*big.Int{}, big.NewInt(12346), &bases, proof)
+
+	if listCmp(listSecret, listProof) {
+		t.Error("Commitment lists agree on wrong challenge")
+	}
+}
+
+func TestLargeRangeProofFake(t *testing.T) {
+	g, s, _, _ := largeRangeTestSetup(t, 5)
+
+	proof := s.fakeProof(g)
+	if !s.verifyProofStructure(proof) {
+		t.Error("Fake proof structure rejected")
+	}
+}
+
+func TestLargeRangeProofJSON(t *testing.T) {
+	g, s, secret, commit := largeRangeTestSetup(t, 5)
+	bases := newBaseMerge(&g, &commit)
+
+	listSecret, rpcommit := s.generateCommitmentsFromSecrets(g, []*big.Int{}, &bases, &secret)
+	proofBefore := s.buildProof(g, big.NewInt(12345), rpcommit, &secret)
+	proofJSON, err := json.Marshal(proofBefore)
+	if err != nil {
+		t.Errorf("error during json marshal: %s", err.Error())
+		return
+	}
+
+	var proofAfter RangeProof
+	err = json.Unmarshal(proofJSON, &proofAfter)
+	if err != nil {
+		t.Errorf("error during json unmarshal: %s", err.Error())
+		return
+	}
+
+	if !s.verifyProofStructure(proofAfter) {
+		t.Error("json'ed proof structure rejected")
+		return
+	}
+
+	listProof := s.generateCommitmentsFromProof(g, []*big.Int{}, big.NewInt(12345), &bases, proofAfter)
+	if !listCmp(listSecret, listProof) {
+		t.Error("json'ed proof incorrect")
+	}
+}
+
+func TestLargeRangeProofVerifyStructure(t *testing.T) {
+	g, s, secret, commit := largeRangeTestSetup(t, 5)
+	bases := newBaseMerge(&g, &commit)
+
+	_, rpcommit := s.generateCommitmentsFromSecrets(g, []*big.Int{}, &bases, &secret)
+	proof := s.buildProof(g, big.NewInt(12345), rpcommit, &secret)
+
+	var empty RangeProof
+	if s.verifyProofStructure(empty) {
+		t.Error("Accepting empty proof")
+	}
+
+	missingCommit := proof
+	missingCommit.IntegerCommit = nil
+	if s.verifyProofStructure(missingCommit) {
+		t.Error("Accepting proof without integer commitment")
+	}
+
+	missingHider := proof
+	missingHider.IntegerHiderResult = nil
+	if s.verifyProofStructure(missingHider) {
+		t.Error("Accepting proof without integer hider result")
+	}
+
+	badCommit := proof
+	badCommit.IntegerCommit = big.NewInt(0)
+	if s.verifyProofStructure(badCommit) {
+		t.Error("Accepting zero integer commitment")
+	}
+
+	tooLarge := proof
+	tooLarge.Results = map[string][]*big.Int{
+		"x":  []*big.Int{new(big.Int).Lsh(s.largeResultOffset(), 1)},
+		"xh": proof.Results["xh"],
+	}
+	if s.verifyProofStructure(tooLarge) {
+		t.Error("Accepting too large range result")
+	}
+
+	tooMany := proof
+	tooMany.Results = map[string][]*big.Int{
+		"x":  []*big.Int{proof.Results["x"][0], proof.Results["x"][0]},
+		"xh": proof.Results["xh"],
+	}
+	if s.verifyProofStructure(tooMany) {
+		t.Error("Accepting too many results")
+	}
+}
@@ -1,8 +1,8 @@
 package primeproofs
 
 import (
-	"github.com/bwesterb/go-exptable"
 	"github.com/privacybydesign/gabi/big"
+	"github.com/privacybydesign/keyproof/common"
 
 	"fmt"
 )
@@ -23,7 +23,7 @@ type proofLookup interface {
 }
 
 func (g *group) exp(ret *big.Int, name string, exp, P *big.Int) bool {
-	var table *exptable.Table
+	var table *common.ExpTable
 	if name == "g" {
 		table = &g.gTable
 	} else if name == "h" {
@@ -40,10 +40,48 @@ func (g *group) exp(ret *big.Int, name string, exp, P *big.Int) bool {
 		panic(fmt.Sprintf("scalar out of bounds: %v %v", exp, g.order))
 	}
 	// exp2.Mod(exp, g.order)
-	table.Exp(ret.Value(), exp.Value())
+	table.Exp(ret, exp)
 	return true
 }
 
+// Set ret to the product of the named bases raised to the given exponents.
+// The generators g and h use their exponentiation tables, all other bases are
+// combined in a single multi-exponentiation.
+func (g *group) expProduct(ret *big.Int, bases baseLookup, names []string, exps []*big.Int) *big.Int {
+	var fixed, contribution, tmp big.Int
+	fixed.SetUint64(1)
+	varBases := []*big.Int{}
+	varExps := []*big.Int{}
+	for i, name := range names {
+		exp := exps[i]
+		base := bases.getBase(name)
+		if name == "g" || name == "h" || base == nil {
+			var reduced big.Int
+			g.orderMod.Mod(&reduced, exp)
+			if !bases.exp(&contribution, name, &reduced, g.p) {
+				continue
+			}
+			tmp.Mul(&fixed, &contribution)
+			g.pMod.Mod(&fixed, &tmp)
+			continue
+		}
+
+		if exp.Sign() < 0 {
+			base = new(big.Int).ModInverse(base, g.p)
+			if base == nil {
+				return ret.SetUint64(0)
+			}
+			exp = new(big.Int).Neg(exp)
+		}
+		varBases = append(varBases, base)
+		varExps = append(varExps, exp)
+	}
+
+	common.MultiExp(ret, varBases, varExps, &g.pMod)
+	tmp.Mul(ret, &fixed)
+	return g.pMod.Mod(ret, &tmp)
+}
+
 func (g *group) names() []string {
 	return []string{"g", "h"}
 }
@@ -144,3 +144,34 @@ func TestProofMerge(t *testing.T) {
 		t.Error("Incorrectly got result for lookup of n3")
 	}
 }
+
+func TestGroupExpProduct(t *testing.T) {
+	for _, prime := range []*big.Int{big.NewInt(26903), findSafePrime(1100)} {
+		g, ok := buildGroup(prime)
+		if !ok {
+			t.Error("Problem generating group")
+			return
+		}
+
+		var commits RangeTestCommit
+		commits.commits = map[string]*big.Int{
+			"x": new(big.Int).Exp(g.g, big.NewInt(12345), g.p),
+			"y": new(big.Int).Exp(g.h, big.NewInt(54321), g.p),
+			"z": new(big.Int).Exp(g.g, big.NewInt(77), g.p),
+		}
+		bases := newBaseMerge(&g, &commits)
+
+		names := []string{"g", "x", "h", "y", "z", "x"}
+		exps := []*big.Int{big.NewInt(-3), big.NewInt(5), big.NewInt(7), new(big.Int).Lsh(big.NewInt(1), 300), big.NewInt(-11), big.NewInt(0)}
+
+		expected := big.NewInt(1)
+		for i, name := range names {
+			contribution := new(big.Int).Exp(bases.getBase(name), exps[i], g.p)
+			expected.Mod(expected.Mul(expected, contribution), g.p)
+		}
+
+		if g.expProduct(new(big.Int), &bases, names, exps).Cmp(expected) != 0 {
+			t.Error("Incorrect product of powers")
+		}
+	}
+}
@@ -51,8 +51,12 @@ func (c *multiplicationProofCommit) getRandomizer(name string) *big.Int {
 	return nil
 }
 
+func (c *multiplicationProofCommit) secretNames() []string {
+	return []string{c.nameHider}
+}
+
 // Note, m1, m2, mod and result should be names of pederson commitments
-func newMultiplicationProofStructure(m1, m2, mod, result string, l uint) multiplicationProofStructure {
+func newMultiplicationProofStructure(m1, m2, mod, result string, l uint, params *SecurityParams) multiplicationProofStructure {
 	var structure multiplicationProofStructure
 	structure.m1 = m1
 	structure.m2 = m2
@@ -70,7 +74,7 @@ func newMultiplicationProofStructure(m1, m2, mod, result string, l uint) multipl
 		},
 	}
 	structure.modMultRepresentation = newPedersonRepresentationProofStructure(strings.Join([]string{structure.myname, "mod"}, "_"))
-	structure.modMultRange = newPedersonRangeProofStructure(strings.Join([]string{structure.myname, "mod"}, "_"), 0, l)
+	structure.modMultRange = newPedersonRangeProofStructure(strings.Join([]string{structure.myname, "mod"}, "_"), 0, l, params)
 	return structure
 }
 
@@ -85,14 +89,32 @@ func (s *multiplicationProofStructure) numCommitments() int {
 		1
 }
 
-func (s *multiplicationProofStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, multiplicationProofCommit) {
+// A commit carrying only the names of its secrets and pederson commitment
+func (s *multiplicationProofStructure) newCommit() multiplicationProofCommit {
 	var commit multiplicationProofCommit
+	commit.nameHider = strings.Join([]string{s.myname, "hider"}, "_")
+	commit.modMultPederson = newPedersonName(strings.Join([]string{s.myname, "mod"}, "_"))
+	return commit
+}
+
+func (s *multiplicationProofStructure) collectNames(t *nameTracker) {
+	commit := s.newCommit()
+	t.providePederson(&commit.modMultPederson)
+	t.provideSecrets(&commit)
+	t.useSecret(s.m1)
+	t.usePedersonSecret(s.m2, s.mod, s.result)
+	s.multRepresentation.collectNames(t)
+	s.modMultRepresentation.collectNames(t)
+	s.modMultRange.collectNames(t)
+}
+
+func (s *multiplicationProofStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, multiplicationProofCommit) {
+	commit := s.newCommit()
 
 	// Generate the neccesary commit data for our parts of the proof
-	commit.nameHider = strings.Join([]string{s.myname, "hider"}, "_")
 	commit.modMultPederson = newPedersonSecret(
 		g,
-		strings.Join([]string{s.myname, "mod"}, "_"),
+		commit.modMultPederson.name,
 		new(big.Int).Div(
 			new(big.Int).Sub(
 				new(big.Int).Mul(
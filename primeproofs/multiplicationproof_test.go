@@ -26,7 +26,7 @@ func TestMultiplicationProofFlow(t *testing.T) {
 	bases := newBaseMerge(&g, &m1, &m2, &mod, &result)
 	secrets := newSecretMerge(&m1, &m2, &mod, &result)
 
-	s := newMultiplicationProofStructure("m1", "m2", "mod", "result", 3)
+	s := newMultiplicationProofStructure("m1", "m2", "mod", "result", 3, &LegacySecurityParams)
 	if !s.isTrue(&secrets) {
 		t.Error("Incorrectly assessed proof setup as incorrect.")
 	}
@@ -78,7 +78,7 @@ func TestMultiplicationProofFake(t *testing.T) {
 		return
 	}
 
-	s := newMultiplicationProofStructure("m1", "m2", "mod", "result", 3)
+	s := newMultiplicationProofStructure("m1", "m2", "mod", "result", 3, &LegacySecurityParams)
 
 	proof := s.fakeProof(g)
 
@@ -95,7 +95,7 @@ func TestMultiplicationProofVerifyStructure(t *testing.T) {
 	}
 
 	var proof MultiplicationProof
-	s := newMultiplicationProofStructure("m1", "m2", "mod", "result", 3)
+	s := newMultiplicationProofStructure("m1", "m2", "mod", "result", 3, &LegacySecurityParams)
 
 	proof = s.fakeProof(g)
 	proof.ModMultProof.Commit = nil
@@ -123,7 +123,7 @@ func TestMultiplicationProofJSON(t *testing.T) {
 		return
 	}
 
-	s := newMultiplicationProofStructure("m1", "m2", "mod", "result", 3)
+	s := newMultiplicationProofStructure("m1", "m2", "mod", "result", 3, &LegacySecurityParams)
 
 	proofBefore := s.fakeProof(g)
 	proofJSON, err := json.Marshal(proofBefore)
@@ -0,0 +1,157 @@
+package primeproofs
+
+import "github.com/privacybydesign/keyproof/common"
+import "github.com/privacybydesign/gabi/big"
+import "fmt"
+
+// Paillier-Blum modulus proof, showing N = PQ is square free with
+// gcd(N, phi(N)) = 1 and P = Q = 3 mod 4. For every challenge y, the prover
+// gives an N-th root of y, and a fourth root of one of y, -y, W*y, -W*y,
+// where W is a prover chosen number of Jacobi symbol -1.
+
+type PaillierBlumProof struct {
+	W           *big.Int
+	NthRoots    []*big.Int
+	FourthRoots []*big.Int
+}
+
+// Separates the challenges of Paillier-Blum proofs from those of other proofs
+var paillierBlumDomain = new(big.Int).SetBytes([]byte("paillier-blum"))
+
+func paillierBlumChallenge(N *big.Int, W *big.Int) *big.Int {
+	return common.HashCommit([]*big.Int{paillierBlumDomain, N, W})
+}
+
+// Zero iterations selects the default, fewer would give an empty proof
+func paillierBlumIterations(iterations int) (int, error) {
+	if iterations == 0 {
+		return paillierBlumIters, nil
+	}
+	if iterations < 0 {
+		return 0, fmt.Errorf("invalid number of Paillier-Blum iterations %d", iterations)
+	}
+	return iterations, nil
+}
+
+// Build a non-interactive Paillier-Blum proof for N = P*Q with the given
+// number of iterations. Zero iterations selects the default.
+func BuildPaillierBlumProof(P *big.Int, Q *big.Int, iterations int) (PaillierBlumProof, error) {
+	iterations, err := paillierBlumIterations(iterations)
+	if err != nil {
+		return PaillierBlumProof{}, err
+	}
+	if P.Bit(1) != 1 || Q.Bit(1) != 1 {
+		panic("Trying to build Paillier-Blum proof for non-Blum modulus")
+	}
+
+	// Precalculate values for the responses
+	N := new(big.Int).Mul(P, Q)
+	phiN := new(big.Int).Mul(new(big.Int).Sub(P, big.NewInt(1)), new(big.Int).Sub(Q, big.NewInt(1)))
+	M := new(big.Int).ModInverse(N, phiN)
+	if M == nil {
+		panic("Trying to build Paillier-Blum proof for N not coprime to phi(N)")
+	}
+	factors := []*big.Int{P, Q}
+
+	var proof PaillierBlumProof
+	proof.W = twoPrimeProductFindUnit(P, Q, -1, 1)
+	units := []*big.Int{
+		big.NewInt(1),
+		new(big.Int).Sub(N, big.NewInt(1)),
+		proof.W,
+		new(big.Int).Sub(N, proof.W),
+	}
+
+	// Generate the challenges and responses
+	challenge := paillierBlumChallenge(N, proof.W)
+	proof.NthRoots = []*big.Int{}
+	proof.FourthRoots = []*big.Int{}
+	for i := 0; i < iterations; i++ {
+		curc := common.GetHashNumber(challenge, nil, i, uint(N.BitLen()))
+		curc.Mod(curc, N)
+
+		if new(big.Int).GCD(nil, nil, curc, N).Cmp(big.NewInt(1)) != 0 {
+			panic("Generated number not in Z_N")
+		}
+
+		fourthRoot := unitMultipleRoot(curc, N, factors, units, 2)
+		if fourthRoot == nil {
+			panic("None of +-y, +-Wy have a fourth root!")
+		}
+		proof.NthRoots = append(proof.NthRoots, new(big.Int).Exp(curc, M, N))
+		proof.FourthRoots = append(proof.FourthRoots, fourthRoot)
+	}
+
+	return proof, nil
+}
+
+func (proof *PaillierBlumProof) verifyStructure(iterations int) bool {
+	if proof.W == nil || len(proof.NthRoots) != iterations || len(proof.FourthRoots) != iterations {
+		return false
+	}
+	for i := 0; i < iterations; i++ {
+		if proof.NthRoots[i] == nil || proof.FourthRoots[i] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Verify a Paillier-Blum proof for N with the given number of iterations.
+// Zero iterations selects the default. The error is only set for an invalid
+// number of iterations, not for a proof that doesn't verify.
+func VerifyPaillierBlumProof(N *big.Int, iterations int, proof PaillierBlumProof) (bool, error) {
+	iterations, err := paillierBlumIterations(iterations)
+	if err != nil {
+		return false, err
+	}
+	return verifyPaillierBlumProof(N, iterations, proof), nil
+}
+
+func verifyPaillierBlumProof(N *big.Int, iterations int, proof PaillierBlumProof) bool {
+	if N == nil || !proof.verifyStructure(iterations) {
+		return false
+	}
+
+	// N should be odd and composite
+	if N.Bit(0) != 1 || N.Cmp(big.NewInt(1)) <= 0 || N.ProbablyPrime(80) {
+		return false
+	}
+
+	// W should be in Z_N* and have jacobi symbol -1
+	if proof.W.Sign() <= 0 || proof.W.Cmp(N) >= 0 || common.LegendreSymbol(proof.W, N) != -1 {
+		return false
+	}
+
+	units := []*big.Int{
+		big.NewInt(1),
+		new(big.Int).Sub(N, big.NewInt(1)),
+		proof.W,
+		new(big.Int).Sub(N, proof.W),
+	}
+
+	// Regenerate the challenges and verify responses
+	challenge := paillierBlumChallenge(N, proof.W)
+	for i := 0; i < iterations; i++ {
+		curc := common.GetHashNumber(challenge, nil, i, uint(N.BitLen()))
+		curc.Mod(curc, N)
+
+		if new(big.Int).Exp(proof.NthRoots[i], N, N).Cmp(curc) != 0 {
+			return false
+		}
+
+		result := new(big.Int).Exp(proof.FourthRoots[i], big.NewInt(4), N)
+		ok := false
+		for _, unit := range units {
+			if result.Cmp(new(big.Int).Mod(new(big.Int).Mul(unit, curc), N)) == 0 {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,155 @@
+package primeproofs
+
+import "testing"
+import "encoding/json"
+import "github.com/privacybydesign/keyproof/common"
+import "github.com/privacybydesign/gabi/big"
+
+func buildPaillierBlumTestProof(t *testing.T, P *big.Int, Q *big.Int, iterations int) PaillierBlumProof {
+	proof, err := BuildPaillierBlumProof(P, Q, iterations)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return proof
+}
+
+func TestPaillierBlumCycle(t *testing.T) {
+	const p = 2147483059
+	const q = 2147483399
+	proof := buildPaillierBlumTestProof(t, big.NewInt(p), big.NewInt(q), 0)
+	if ok, err := VerifyPaillierBlumProof(big.NewInt(p*q), 0, proof); !ok || err != nil {
+		t.Error("PaillierBlum proof rejected")
+	}
+	if verifyPaillierBlumProof(big.NewInt(2147483059*2147483423), paillierBlumIters, proof) {
+		t.Error("PaillierBlum proof accepted for wrong modulus")
+	}
+}
+
+func TestPaillierBlumIterations(t *testing.T) {
+	const p = 2147483059
+	const q = 2147483399
+	proof := buildPaillierBlumTestProof(t, big.NewInt(p), big.NewInt(q), 13)
+	if len(proof.NthRoots) != 13 || len(proof.FourthRoots) != 13 {
+		t.Error("Incorrect number of responses")
+	}
+	if !verifyPaillierBlumProof(big.NewInt(p*q), 13, proof) {
+		t.Error("PaillierBlum proof rejected")
+	}
+	if verifyPaillierBlumProof(big.NewInt(p*q), paillierBlumIters, proof) {
+		t.Error("PaillierBlum proof with too few iterations accepted")
+	}
+}
+
+func TestPaillierBlumNegativeIterations(t *testing.T) {
+	const p = 2147483059
+	const q = 2147483399
+	if _, err := BuildPaillierBlumProof(big.NewInt(p), big.NewInt(q), -1); err == nil {
+		t.Error("Built PaillierBlum proof with negative iterations")
+	}
+
+	var empty PaillierBlumProof
+	empty.W = big.NewInt(2)
+	if ok, err := VerifyPaillierBlumProof(big.NewInt(p*q), -1, empty); ok || err == nil {
+		t.Error("Verified PaillierBlum proof with negative iterations")
+	}
+}
+
+func TestPaillierBlumDomain(t *testing.T) {
+	const p = 2147483059
+	const q = 2147483399
+	proof := buildPaillierBlumTestProof(t, big.NewInt(p), big.NewInt(q), 0)
+	if paillierBlumChallenge(big.NewInt(p*q), proof.W).Cmp(common.HashCommit([]*big.Int{big.NewInt(p * q), proof.W})) == 0 {
+		t.Error("PaillierBlum challenge not domain separated")
+	}
+}
+
+func TestPaillierBlumIncorrect(t *testing.T) {
+	const p = 2147483059
+	const q = 2147483399
+	proof := buildPaillierBlumTestProof(t, big.NewInt(p), big.NewInt(q), 0)
+
+	backup := proof.FourthRoots[3]
+	proof.FourthRoots[3] = new(big.Int).Add(backup, big.NewInt(1))
+	if verifyPaillierBlumProof(big.NewInt(p*q), paillierBlumIters, proof) {
+		t.Error("Incorrect fourth root accepted")
+	}
+	proof.FourthRoots[3] = backup
+
+	backup = proof.NthRoots[3]
+	proof.NthRoots[3] = new(big.Int).Add(backup, big.NewInt(1))
+	if verifyPaillierBlumProof(big.NewInt(p*q), paillierBlumIters, proof) {
+		t.Error("Incorrect N-th root accepted")
+	}
+	proof.NthRoots[3] = backup
+
+	// W of jacobi symbol 1
+	backup = proof.W
+	proof.W = big.NewInt(4)
+	if verifyPaillierBlumProof(big.NewInt(p*q), paillierBlumIters, proof) {
+		t.Error("Square W accepted")
+	}
+	proof.W = backup
+
+	if !verifyPaillierBlumProof(big.NewInt(p*q), paillierBlumIters, proof) {
+		t.Error("Testcase corrupted testdata")
+	}
+}
+
+func TestPaillierBlumNonBlum(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Building Paillier-Blum proof for non-Blum modulus did not panic")
+		}
+	}()
+	BuildPaillierBlumProof(big.NewInt(2147483033), big.NewInt(2147483399), 0)
+}
+
+func TestPaillierBlumVerifyStructure(t *testing.T) {
+	const p = 2147483059
+	const q = 2147483399
+	proof := buildPaillierBlumTestProof(t, big.NewInt(p), big.NewInt(q), 0)
+
+	var empty PaillierBlumProof
+	if verifyPaillierBlumProof(big.NewInt(p*q), paillierBlumIters, empty) {
+		t.Error("Accepting empty proof")
+	}
+
+	backup := proof.NthRoots[2]
+	proof.NthRoots[2] = nil
+	if proof.verifyStructure(paillierBlumIters) {
+		t.Error("Accepting missing N-th root")
+	}
+	proof.NthRoots[2] = backup
+
+	backup = proof.FourthRoots[2]
+	proof.FourthRoots[2] = nil
+	if proof.verifyStructure(paillierBlumIters) {
+		t.Error("Accepting missing fourth root")
+	}
+	proof.FourthRoots[2] = backup
+
+	if !proof.verifyStructure(paillierBlumIters) {
+		t.Error("Testcase corrupted testdata")
+	}
+}
+
+func TestPaillierBlumJSON(t *testing.T) {
+	const p = 2147483059
+	const q = 2147483399
+	proofBefore := buildPaillierBlumTestProof(t, big.NewInt(p), big.NewInt(q), 0)
+	proofJSON, err := json.Marshal(proofBefore)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	var proofAfter PaillierBlumProof
+	err = json.Unmarshal(proofJSON, &proofAfter)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if !verifyPaillierBlumProof(big.NewInt(p*q), paillierBlumIters, proofAfter) {
+		t.Error("JSON proof rejected")
+	}
+}
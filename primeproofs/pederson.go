@@ -36,7 +36,7 @@ func newPedersonRepresentationProofStructure(name string) representationProofStr
 	return structure
 }
 
-func newPedersonRangeProofStructure(name string, l1 uint, l2 uint) rangeProofStructure {
+func newPedersonRangeProofStructure(name string, l1 uint, l2 uint, params *SecurityParams) rangeProofStructure {
 	var structure rangeProofStructure
 	structure.lhs = []lhsContribution{
 		lhsContribution{name, big.NewInt(1)},
@@ -48,13 +48,32 @@ func newPedersonRangeProofStructure(name string, l1 uint, l2 uint) rangeProofStr
 	structure.rangeSecret = name
 	structure.l1 = l1
 	structure.l2 = l2
+	structure.params = params
+	if params.ExactRangeProofs {
+		structure.makeExact()
+	}
 	return structure
 }
 
-func newPedersonSecret(g group, name string, value *big.Int) pedersonSecret {
+// As newPedersonRangeProofStructure, but always proving the range exactly,
+// whatever the security parameters say
+func newPedersonExactRangeProofStructure(name string, l1 uint, l2 uint, params *SecurityParams) rangeProofStructure {
+	structure := newPedersonRangeProofStructure(name, l1, l2, params)
+	structure.makeExact()
+	return structure
+}
+
+// A pederson commitment carrying only its names, to be given a value with
+// newPedersonSecret, or to list the names it provides
+func newPedersonName(name string) pedersonSecret {
 	var result pedersonSecret
 	result.name = name
 	result.hname = strings.Join([]string{name, "hider"}, "_")
+	return result
+}
+
+func newPedersonSecret(g group, name string, value *big.Int) pedersonSecret {
+	result := newPedersonName(name)
 	result.secret = new(big.Int).Set(value)
 	result.secretRandomizer = common.RandomBigInt(g.order)
 	result.hider = common.RandomBigInt(g.order)
@@ -113,6 +132,10 @@ func (s *pedersonSecret) getRandomizer(name string) *big.Int {
 	}
 	return nil
 }
+
+func (s *pedersonSecret) secretNames() []string {
+	return []string{s.name, s.hname}
+}
 func (c *pedersonSecret) exp(ret *big.Int, name string, exp, P *big.Int) bool {
 	if name != c.name {
 		return false
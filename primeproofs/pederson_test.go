@@ -101,7 +101,7 @@ func TestPedersonRangeFlow(t *testing.T) {
 	secretBases := newBaseMerge(&g, &testSecret)
 	proofBases := newBaseMerge(&g, &testProof)
 
-	s := newPedersonRangeProofStructure("x", 4, 2)
+	s := newPedersonRangeProofStructure("x", 4, 2, &LegacySecurityParams)
 
 	if !s.isTrue(g, &secretBases, &testSecret) {
 		t.Error("Attempted proof is false")
@@ -7,7 +7,24 @@ type PrimePowerProductProof struct {
 	Responses []*big.Int
 }
 
-func primePowerProductBuildProof(P *big.Int, Q *big.Int, challenge *big.Int, index *big.Int) PrimePowerProductProof {
+func primePowerProductBuildProof(P *big.Int, Q *big.Int, challenge *big.Int, index *big.Int, params *SecurityParams) PrimePowerProductProof {
+	N := new(big.Int).Mul(P, Q)
+	return primePowerProductBuildProofWithUnits(P, Q, challenge, index, primePowerProductUnits(N), 1, params)
+}
+
+// The units u for which the response can be a root of u*x, for the standard proof: +-1, +-2
+func primePowerProductUnits(N *big.Int) []*big.Int {
+	return []*big.Int{
+		big.NewInt(1),
+		new(big.Int).Sub(N, big.NewInt(1)),
+		big.NewInt(2),
+		new(big.Int).Sub(N, big.NewInt(2)),
+	}
+}
+
+// Generalized prime power product proof, where each response is a 2^squarings-th
+// root of u*x for one of the given units u.
+func primePowerProductBuildProofWithUnits(P *big.Int, Q *big.Int, challenge *big.Int, index *big.Int, units []*big.Int, squarings int, params *SecurityParams) PrimePowerProductProof {
 	N := new(big.Int).Mul(P, Q)
 
 	// And for response generation
@@ -19,7 +36,7 @@ func primePowerProductBuildProof(P *big.Int, Q *big.Int, challenge *big.Int, ind
 	// Generate the challenges and responses
 	var proof PrimePowerProductProof
 	proof.Responses = []*big.Int{}
-	for i := 0; i < primePowerProductIters; i++ {
+	for i := 0; i < params.PrimePowerProductIters; i++ {
 		// Generate the challenge
 		curc := common.GetHashNumber(challenge, index, i, uint(N.BitLen()))
 		curc.Mod(curc, N)
@@ -28,29 +45,35 @@ func primePowerProductBuildProof(P *big.Int, Q *big.Int, challenge *big.Int, ind
 			panic("Generated number not in Z_N")
 		}
 
-		r1, ok1 := common.ModSqrt(curc, factors)
-		r2, ok2 := common.ModSqrt(new(big.Int).Mod(new(big.Int).Neg(curc), N), factors)
-		r3, ok3 := common.ModSqrt(new(big.Int).Mod(new(big.Int).Lsh(curc, 1), N), factors)
-		r4, ok4 := common.ModSqrt(new(big.Int).Mod(new(big.Int).Lsh(new(big.Int).Mod(new(big.Int).Neg(curc), N), 1), N), factors)
-
-		if ok1 {
-			proof.Responses = append(proof.Responses, r1)
-		} else if ok2 {
-			proof.Responses = append(proof.Responses, r2)
-		} else if ok3 {
-			proof.Responses = append(proof.Responses, r3)
-		} else if ok4 {
-			proof.Responses = append(proof.Responses, r4)
-		} else {
-			panic("None of +-x, +-2x are square!")
+		response := unitMultipleRoot(curc, N, factors, units, squarings)
+		if response == nil {
+			panic("None of the unit multiples of x have a root!")
 		}
+		proof.Responses = append(proof.Responses, response)
 	}
 
 	return proof
 }
 
-func primePowerProductVerifyStructure(proof PrimePowerProductProof) bool {
-	if proof.Responses == nil || len(proof.Responses) != primePowerProductIters {
+// Find a 2^squarings-th root of u*x modulo N for the first unit u for which one exists.
+// Relies on ModSqrt returning a square root that is itself a square whenever the
+// factors are 3 mod 4. Returns nil if there is no such root.
+func unitMultipleRoot(x *big.Int, N *big.Int, factors []*big.Int, units []*big.Int, squarings int) *big.Int {
+	for _, unit := range units {
+		root := new(big.Int).Mod(new(big.Int).Mul(unit, x), N)
+		ok := true
+		for j := 0; j < squarings && ok; j++ {
+			root, ok = common.ModSqrt(root, factors)
+		}
+		if ok {
+			return root
+		}
+	}
+	return nil
+}
+
+func primePowerProductVerifyStructure(proof PrimePowerProductProof, params *SecurityParams) bool {
+	if proof.Responses == nil || len(proof.Responses) != params.PrimePowerProductIters {
 		return false
 	}
 
@@ -63,22 +86,30 @@ func primePowerProductVerifyStructure(proof PrimePowerProductProof) bool {
 	return true
 }
 
-func primePowerProductVerifyProof(N *big.Int, challenge *big.Int, index *big.Int, proof PrimePowerProductProof) bool {
+func primePowerProductVerifyProof(N *big.Int, challenge *big.Int, index *big.Int, proof PrimePowerProductProof, params *SecurityParams) bool {
+	return primePowerProductVerifyProofWithUnits(N, challenge, index, primePowerProductUnits(N), 1, proof, params)
+}
+
+func primePowerProductVerifyProofWithUnits(N *big.Int, challenge *big.Int, index *big.Int, units []*big.Int, squarings int, proof PrimePowerProductProof, params *SecurityParams) bool {
+	exponent := new(big.Int).Lsh(big.NewInt(1), uint(squarings))
+
 	// Generate the challenges and responses
-	for i := 0; i < primePowerProductIters; i++ {
+	for i := 0; i < params.PrimePowerProductIters; i++ {
 		// Generate the challenge
 		curc := common.GetHashNumber(challenge, index, i, uint(N.BitLen()))
 		curc.Mod(curc, N)
 
 		// Process response
-		result := new(big.Int).Exp(proof.Responses[i], big.NewInt(2), N)
-
-		ok1 := (result.Cmp(curc) == 0)
-		ok2 := (result.Cmp(new(big.Int).Mod(new(big.Int).Neg(curc), N)) == 0)
-		ok3 := (result.Cmp(new(big.Int).Mod(new(big.Int).Lsh(curc, 1), N)) == 0)
-		ok4 := (result.Cmp(new(big.Int).Mod(new(big.Int).Neg(new(big.Int).Lsh(curc, 1)), N)) == 0)
-
-		if !ok1 && !ok2 && !ok3 && !ok4 {
+		result := new(big.Int).Exp(proof.Responses[i], exponent, N)
+
+		ok := false
+		for _, unit := range units {
+			if result.Cmp(new(big.Int).Mod(new(big.Int).Mul(unit, curc), N)) == 0 {
+				ok = true
+				break
+			}
+		}
+		if !ok {
 			return false
 		}
 	}
@@ -6,12 +6,12 @@ import "github.com/privacybydesign/gabi/big"
 func TestPrimePowerProductCycle(t *testing.T) {
 	const p = 1031
 	const q = 1061
-	proof := primePowerProductBuildProof(big.NewInt(int64(p)), big.NewInt(int64(q)), big.NewInt(12345), big.NewInt(1))
-	if !primePowerProductVerifyStructure(proof) {
+	proof := primePowerProductBuildProof(big.NewInt(int64(p)), big.NewInt(int64(q)), big.NewInt(12345), big.NewInt(1), &LegacySecurityParams)
+	if !primePowerProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Proof structure rejected")
 		return
 	}
-	ok := primePowerProductVerifyProof(big.NewInt(int64(p*q)), big.NewInt(12345), big.NewInt(1), proof)
+	ok := primePowerProductVerifyProof(big.NewInt(int64(p*q)), big.NewInt(12345), big.NewInt(1), proof, &LegacySecurityParams)
 	if !ok {
 		t.Error("PrimePowerProductProof rejected")
 	}
@@ -20,9 +20,9 @@ func TestPrimePowerProductCycle(t *testing.T) {
 func TestPrimePowerProductCycleIncorrect(t *testing.T) {
 	const p = 1031
 	const q = 1061
-	proof := primePowerProductBuildProof(big.NewInt(int64(p)), big.NewInt(int64(q)), big.NewInt(12345), big.NewInt(1))
+	proof := primePowerProductBuildProof(big.NewInt(int64(p)), big.NewInt(int64(q)), big.NewInt(12345), big.NewInt(1), &LegacySecurityParams)
 	proof.Responses[0].Add(proof.Responses[0], big.NewInt(1))
-	ok := primePowerProductVerifyProof(big.NewInt(int64(p*q)), big.NewInt(12345), big.NewInt(1), proof)
+	ok := primePowerProductVerifyProof(big.NewInt(int64(p*q)), big.NewInt(12345), big.NewInt(1), proof, &LegacySecurityParams)
 	if ok {
 		t.Error("Incorrect PrimePowerProductProof accepted")
 	}
@@ -31,8 +31,8 @@ func TestPrimePowerProductCycleIncorrect(t *testing.T) {
 func TestPrimePowerProductCycleWrongChallenge(t *testing.T) {
 	const p = 1031
 	const q = 1061
-	proof := primePowerProductBuildProof(big.NewInt(int64(p)), big.NewInt(int64(q)), big.NewInt(12345), big.NewInt(1))
-	ok := primePowerProductVerifyProof(big.NewInt(int64(p*q)), big.NewInt(12346), big.NewInt(1), proof)
+	proof := primePowerProductBuildProof(big.NewInt(int64(p)), big.NewInt(int64(q)), big.NewInt(12345), big.NewInt(1), &LegacySecurityParams)
+	ok := primePowerProductVerifyProof(big.NewInt(int64(p*q)), big.NewInt(12346), big.NewInt(1), proof, &LegacySecurityParams)
 	if ok {
 		t.Error("Incorrect PrimePowerProductProof accepted")
 	}
@@ -41,8 +41,8 @@ func TestPrimePowerProductCycleWrongChallenge(t *testing.T) {
 func TestPrimePowerProductCycleWrongIndex(t *testing.T) {
 	const p = 1031
 	const q = 1061
-	proof := primePowerProductBuildProof(big.NewInt(int64(p)), big.NewInt(int64(q)), big.NewInt(12345), big.NewInt(1))
-	ok := primePowerProductVerifyProof(big.NewInt(int64(p*q)), big.NewInt(12345), big.NewInt(2), proof)
+	proof := primePowerProductBuildProof(big.NewInt(int64(p)), big.NewInt(int64(q)), big.NewInt(12345), big.NewInt(1), &LegacySecurityParams)
+	ok := primePowerProductVerifyProof(big.NewInt(int64(p*q)), big.NewInt(12345), big.NewInt(2), proof, &LegacySecurityParams)
 	if ok {
 		t.Error("Incorrect PrimePowerProductProof accepted")
 	}
@@ -51,23 +51,23 @@ func TestPrimePowerProductCycleWrongIndex(t *testing.T) {
 func TestPrimePowerProductVerifyStructure(t *testing.T) {
 	const p = 1031
 	const q = 1061
-	proof := primePowerProductBuildProof(big.NewInt(int64(p)), big.NewInt(int64(q)), big.NewInt(12345), big.NewInt(1))
+	proof := primePowerProductBuildProof(big.NewInt(int64(p)), big.NewInt(int64(q)), big.NewInt(12345), big.NewInt(1), &LegacySecurityParams)
 
 	listBackup := proof.Responses
 	proof.Responses = proof.Responses[:len(proof.Responses)-1]
-	if primePowerProductVerifyStructure(proof) {
+	if primePowerProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Accepting too short responses")
 	}
 	proof.Responses = listBackup
 
 	valBackup := proof.Responses[2]
 	proof.Responses[2] = nil
-	if primePowerProductVerifyStructure(proof) {
+	if primePowerProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Accepting missing response")
 	}
 	proof.Responses[2] = valBackup
 
-	if !primePowerProductVerifyStructure(proof) {
+	if !primePowerProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("testcase corrupted testdata")
 	}
 }
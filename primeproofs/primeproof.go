@@ -2,12 +2,29 @@ package primeproofs
 
 import "github.com/privacybydesign/keyproof/common"
 import "github.com/privacybydesign/gabi/big"
+import "strconv"
 import "strings"
 
+// Proof that a committed p is prime, showing a^((p-1)/2) = +-1 mod p for a
+// base a derived from the challenge, and aneg^((p-1)/2) = -1 for some aneg.
+//
+// Compact proofs leave out aneg, which halves the number of exp proofs. That
+// is only sound together with the quasi safe prime product proof, which
+// leaves p = r^k for a prime r: a random a then passes with probability at
+// most 1/r^(k-1), so aneg adds nothing there.
+//
+// With more than one round, each further round repeats the proof for a fresh
+// base as a compact proof of its own. The prea commitments of all rounds are
+// made first and the bases of all rounds derive from a single hash over them,
+// so a prover retrying commitments to find passing bases redraws all bases at
+// once. The aneg of the first round covers all rounds, and without the quasi
+// safe prime product proof each round halves the chance a composite p passes.
 type primeProofStructure struct {
 	primeName string
 	myname    string
 	bitlen    uint
+	params    *SecurityParams
+	compact   bool
 
 	halfPRep representationProofStructure
 
@@ -28,6 +45,8 @@ type primeProofStructure struct {
 
 	aExp    expProofStructure
 	anegExp expProofStructure
+
+	rounds []primeProofStructure
 }
 
 type PrimeProof struct {
@@ -39,9 +58,9 @@ type PrimeProof struct {
 	HalfPCommit   PedersonProof
 	PreaCommit    PedersonProof
 	ACommit       PedersonProof
-	AnegCommit    PedersonProof
+	AnegCommit    *PedersonProof `json:",omitempty"`
 	AResCommit    PedersonProof
-	AnegResCommit PedersonProof
+	AnegResCommit *PedersonProof `json:",omitempty"`
 
 	PreaModResult   *big.Int
 	PreaHiderResult *big.Int
@@ -53,11 +72,15 @@ type PrimeProof struct {
 
 	PreaRangeProof    RangeProof
 	ARangeProof       RangeProof
-	AnegRangeProof    RangeProof
+	AnegRangeProof    *RangeProof `json:",omitempty"`
 	PreaModRangeProof RangeProof
 
+	// The aneg parts are left out of compact proofs
 	AExpProof    ExpProof
-	AnegExpProof ExpProof
+	AnegExpProof *ExpProof `json:",omitempty"`
+
+	// Further rounds, absent for a single round
+	Rounds []PrimeProof `json:",omitempty"`
 }
 
 type primeProofCommit struct {
@@ -73,6 +96,7 @@ type primeProofCommit struct {
 	aResPederson    pedersonSecret
 	anegResPederson pedersonSecret
 
+	aAdd                *big.Int
 	preaMod             *big.Int
 	preaModRandomizer   *big.Int
 	preaHider           *big.Int
@@ -91,6 +115,8 @@ type primeProofCommit struct {
 
 	aExpCommit    expProofCommit
 	anegExpCommit expProofCommit
+
+	rounds []primeProofCommit
 }
 
 func (p *PrimeProof) getResult(name string) *big.Int {
@@ -135,6 +161,11 @@ func (c *primeProofCommit) getRandomizer(name string) *big.Int {
 	return nil
 }
 
+// The invalid branch is provided through its simulated result
+func (c *primeProofCommit) secretNames() []string {
+	return []string{c.namePreaMod, c.namePreaHider, c.nameAValid, c.nameAInvalid}
+}
+
 func (c *primeProofCommit) getResult(name string) *big.Int {
 	if name == c.nameAInvalid {
 		return c.aInvalidResult
@@ -142,11 +173,23 @@ func (c *primeProofCommit) getResult(name string) *big.Int {
 	return nil
 }
 
-func newPrimeProofStructure(name string, bitlen uint) primeProofStructure {
+func newPrimeProofStructure(name string, bitlen uint, params *SecurityParams) primeProofStructure {
+	structure := newPrimeProofRoundStructure(name, strings.Join([]string{name, "primeproof"}, "_"), bitlen, params)
+	structure.compact = params.CompactPrimeProofs
+	for i := 1; i < params.primeProofRounds(); i++ {
+		round := newPrimeProofRoundStructure(name, strings.Join([]string{structure.myname, "round", strconv.Itoa(i)}, "_"), bitlen, params)
+		round.compact = true
+		structure.rounds = append(structure.rounds, round)
+	}
+	return structure
+}
+
+func newPrimeProofRoundStructure(name string, myname string, bitlen uint, params *SecurityParams) primeProofStructure {
 	var structure primeProofStructure
 	structure.primeName = name
-	structure.myname = strings.Join([]string{name, "primeproof"}, "_")
+	structure.myname = myname
 	structure.bitlen = bitlen
+	structure.params = params
 
 	structure.halfPRep = representationProofStructure{
 		[]lhsContribution{
@@ -161,13 +204,13 @@ func newPrimeProofStructure(name string, bitlen uint) primeProofStructure {
 	}
 
 	structure.preaRep = newPedersonRepresentationProofStructure(strings.Join([]string{structure.myname, "prea"}, "_"))
-	structure.preaRange = newPedersonRangeProofStructure(strings.Join([]string{structure.myname, "prea"}, "_"), 0, bitlen)
+	structure.preaRange = newPedersonRangeProofStructure(strings.Join([]string{structure.myname, "prea"}, "_"), 0, bitlen, params)
 
 	structure.aRep = newPedersonRepresentationProofStructure(strings.Join([]string{structure.myname, "a"}, "_"))
-	structure.aRange = newPedersonRangeProofStructure(strings.Join([]string{structure.myname, "a"}, "_"), 0, bitlen)
+	structure.aRange = newPedersonRangeProofStructure(strings.Join([]string{structure.myname, "a"}, "_"), 0, bitlen, params)
 
 	structure.anegRep = newPedersonRepresentationProofStructure(strings.Join([]string{structure.myname, "aneg"}, "_"))
-	structure.anegRange = newPedersonRangeProofStructure(strings.Join([]string{structure.myname, "aneg"}, "_"), 0, bitlen)
+	structure.anegRange = newPedersonRangeProofStructure(strings.Join([]string{structure.myname, "aneg"}, "_"), 0, bitlen, params)
 
 	structure.aResRep = newPedersonRepresentationProofStructure(strings.Join([]string{structure.myname, "ares"}, "_"))
 	structure.aPlus1ResRep = representationProofStructure{
@@ -204,55 +247,193 @@ func newPrimeProofStructure(name string, bitlen uint) primeProofStructure {
 		strings.Join([]string{structure.myname, "halfp"}, "_"),
 		name,
 		strings.Join([]string{structure.myname, "ares"}, "_"),
-		bitlen)
+		bitlen,
+		params)
 	structure.anegExp = newExpProofStructure(
 		strings.Join([]string{structure.myname, "aneg"}, "_"),
 		strings.Join([]string{structure.myname, "halfp"}, "_"),
 		name,
 		strings.Join([]string{structure.myname, "anegres"}, "_"),
-		bitlen)
+		bitlen,
+		params)
 	return structure
 }
 
 func (s *primeProofStructure) numRangeProofs() int {
-	res := 4
+	res := 3
 	res += s.aExp.numRangeProofs()
-	res += s.anegExp.numRangeProofs()
+	if !s.compact {
+		res += 1
+		res += s.anegExp.numRangeProofs()
+	}
+	for i := range s.rounds {
+		res += s.rounds[i].numRangeProofs()
+	}
 	return res
 }
 
 func (s *primeProofStructure) numCommitments() int {
-	res := 6
+	res := 4
 	res += s.halfPRep.numCommitments()
 	res += s.preaRep.numCommitments()
 	res += s.preaRange.numCommitments()
 	res += s.aRep.numCommitments()
 	res += s.aRange.numCommitments()
-	res += s.anegRep.numCommitments()
-	res += s.anegRange.numCommitments()
 	res += 1
-	res += rangeProofIters
+	res += s.params.rangeProofCommitments()
 	res += s.aResRep.numCommitments()
-	res += s.anegResRep.numCommitments()
 	res += s.aPlus1ResRep.numCommitments()
 	res += s.aMin1ResRep.numCommitments()
 	res += s.aExp.numCommitments()
-	res += s.anegExp.numCommitments()
+	if !s.compact {
+		res += 2
+		res += s.anegRep.numCommitments()
+		res += s.anegRange.numCommitments()
+		res += s.anegResRep.numCommitments()
+		res += s.anegExp.numCommitments()
+	}
+	for i := range s.rounds {
+		res += s.rounds[i].numCommitments()
+	}
 	return res
 }
 
-func (s *primeProofStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, primeProofCommit) {
+// A commit carrying only the names of its secrets and pederson commitments,
+// as if a turned out to be a quadratic residue
+func (s *primeProofStructure) newCommit() primeProofCommit {
 	var commit primeProofCommit
-
-	// basic setup
 	commit.namePreaMod = strings.Join([]string{s.myname, "preamod"}, "_")
 	commit.namePreaHider = strings.Join([]string{s.myname, "preahider"}, "_")
+	commit.nameAValid = strings.Join([]string{s.myname, "aresplus1hider"}, "_")
+	commit.nameAInvalid = strings.Join([]string{s.myname, "aresmin1hider"}, "_")
+	commit.halfPPederson = newPedersonName(strings.Join([]string{s.myname, "halfp"}, "_"))
+	commit.preaPederson = newPedersonName(strings.Join([]string{s.myname, "prea"}, "_"))
+	commit.aPederson = newPedersonName(strings.Join([]string{s.myname, "a"}, "_"))
+	commit.aResPederson = newPedersonName(strings.Join([]string{s.myname, "ares"}, "_"))
+	if !s.compact {
+		commit.anegPederson = newPedersonName(strings.Join([]string{s.myname, "aneg"}, "_"))
+		commit.anegResPederson = newPedersonName(strings.Join([]string{s.myname, "anegres"}, "_"))
+	}
+	return commit
+}
+
+// The pederson commitments of a commit, all provided as bases and secrets
+func (s *primeProofStructure) commitPedersons(commit *primeProofCommit) []*pedersonSecret {
+	result := []*pedersonSecret{&commit.preaPederson, &commit.aPederson, &commit.aResPederson, &commit.halfPPederson}
+	if !s.compact {
+		result = append(result, &commit.anegPederson, &commit.anegResPederson)
+	}
+	return result
+}
+
+func (s *primeProofStructure) collectNames(t *nameTracker) {
+	commit := s.newCommit()
+	for _, p := range s.commitPedersons(&commit) {
+		t.providePederson(p)
+	}
+	t.provideSecrets(&commit)
+	t.usePedersonSecret(s.primeName)
+
+	// The a generation proof is only built during proving, but its offset
+	// doesn't change the names it uses
+	agenrange := s.aGenRangeStructure(big.NewInt(0))
+
+	s.halfPRep.collectNames(t)
+	s.preaRep.collectNames(t)
+	s.preaRange.collectNames(t)
+	s.aRep.collectNames(t)
+	s.aRange.collectNames(t)
+	agenrange.collectNames(t)
+	s.aResRep.collectNames(t)
+	s.aPlus1ResRep.collectNames(t)
+	s.aMin1ResRep.collectNames(t)
+	s.aExp.collectNames(t)
+	if !s.compact {
+		s.anegRep.collectNames(t)
+		s.anegRange.collectNames(t)
+		s.anegResRep.collectNames(t)
+		s.anegExp.collectNames(t)
+	}
+	for i := range s.rounds {
+		s.rounds[i].collectNames(t)
+	}
+}
+
+// The offsets aAdd of the bases a = prea + aAdd mod p of all rounds. A single
+// round hashes just its prea commitment, more rounds all hash the prea
+// commitments of every round together.
+func (s *primeProofStructure) aAdds(preaCommits []*big.Int) []*big.Int {
+	if len(s.rounds) == 0 {
+		return []*big.Int{common.GetHashNumber(preaCommits[0], nil, 0, s.bitlen)}
+	}
+	baseChallenge := common.HashCommit(preaCommits)
+	result := []*big.Int{}
+	for i := range preaCommits {
+		result = append(result, common.GetHashNumber(baseChallenge, nil, i, s.bitlen))
+	}
+	return result
+}
+
+// Proof that preamod = (prea + aAdd - a) / p, over the integers
+func (s *primeProofStructure) aGenRangeStructure(aAdd *big.Int) rangeProofStructure {
+	return rangeProofStructure{
+		representationProofStructure{
+			[]lhsContribution{
+				lhsContribution{strings.Join([]string{s.myname, "prea"}, "_"), big.NewInt(1)},
+				lhsContribution{"g", aAdd},
+				lhsContribution{strings.Join([]string{s.myname, "a"}, "_"), big.NewInt(-1)},
+			},
+			[]rhsContribution{
+				rhsContribution{s.primeName, strings.Join([]string{s.myname, "preamod"}, "_"), 1},
+				rhsContribution{"h", strings.Join([]string{s.myname, "preahider"}, "_"), 1},
+			},
+		},
+		strings.Join([]string{s.myname, "preamod"}, "_"),
+		0,
+		s.bitlen,
+		s.params,
+		nil,
+	}
+}
 
-	// Build prea
-	commit.preaPederson = newPedersonSecret(g, strings.Join([]string{s.myname, "prea"}, "_"), common.RandomBigInt(secretdata.getSecret(s.primeName)))
+func (s *primeProofStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, primeProofCommit) {
+	// Build prea for all rounds, before any base is known
+	preas := []pedersonSecret{}
+	preaCommits := []*big.Int{}
+	for _, round := range s.roundStructures() {
+		name := round.newCommit().preaPederson.name
+		prea := newPedersonSecret(g, name, common.RandomBigInt(secretdata.getSecret(s.primeName)))
+		preas = append(preas, prea)
+		preaCommits = append(preaCommits, prea.commit)
+	}
+	aAdds := s.aAdds(preaCommits)
 
-	// Calculate aAdd, a, and d
-	aAdd := common.GetHashNumber(commit.preaPederson.commit, nil, 0, s.bitlen)
+	list, commit := s.generateRoundCommitmentsFromSecrets(g, list, bases, secretdata, preas[0], aAdds[0])
+	commit.rounds = make([]primeProofCommit, len(s.rounds))
+	for i := range s.rounds {
+		list, commit.rounds[i] = s.rounds[i].generateRoundCommitmentsFromSecrets(g, list, bases, secretdata, preas[i+1], aAdds[i+1])
+	}
+
+	return list, commit
+}
+
+// The first and further rounds
+func (s *primeProofStructure) roundStructures() []*primeProofStructure {
+	result := []*primeProofStructure{s}
+	for i := range s.rounds {
+		result = append(result, &s.rounds[i])
+	}
+	return result
+}
+
+func (s *primeProofStructure) generateRoundCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup, prea pedersonSecret, aAdd *big.Int) ([]*big.Int, primeProofCommit) {
+	commit := s.newCommit()
+
+	// basic setup
+	commit.preaPederson = prea
+	commit.aAdd = aAdd
+
+	// Calculate a and d
 	d, a := new(big.Int).DivMod(
 		new(big.Int).Add(
 			commit.preaPederson.secret,
@@ -266,7 +447,7 @@ func (s *primeProofStructure) generateCommitmentsFromSecrets(g group, list []*bi
 	}
 
 	// Generate a related commitments
-	commit.aPederson = newPedersonSecret(g, strings.Join([]string{s.myname, "a"}, "_"), a)
+	commit.aPederson = newPedersonSecret(g, commit.aPederson.name, a)
 	commit.preaMod = d
 	commit.preaModRandomizer = common.RandomBigInt(g.order)
 	commit.preaHider = new(big.Int).Mod(
@@ -280,84 +461,79 @@ func (s *primeProofStructure) generateCommitmentsFromSecrets(g group, list []*bi
 		g.order)
 	commit.preaHiderRandomizer = common.RandomBigInt(g.order)
 
-	// Find aneg
-	aneg := common.RandomBigInt(secretdata.getSecret(s.primeName))
-	anegPow := new(big.Int).Exp(aneg, new(big.Int).Rsh(secretdata.getSecret(s.primeName), 1), secretdata.getSecret(s.primeName))
-	for anegPow.Cmp(new(big.Int).Sub(secretdata.getSecret(s.primeName), big.NewInt(1))) != 0 {
-		aneg.Set(common.RandomBigInt(secretdata.getSecret(s.primeName)))
-		anegPow.Exp(aneg, new(big.Int).Rsh(secretdata.getSecret(s.primeName), 1), secretdata.getSecret(s.primeName))
-	}
+	if !s.compact {
+		// Find aneg
+		aneg := common.RandomBigInt(secretdata.getSecret(s.primeName))
+		anegPow := new(big.Int).Exp(aneg, new(big.Int).Rsh(secretdata.getSecret(s.primeName), 1), secretdata.getSecret(s.primeName))
+		for anegPow.Cmp(new(big.Int).Sub(secretdata.getSecret(s.primeName), big.NewInt(1))) != 0 {
+			aneg.Set(common.RandomBigInt(secretdata.getSecret(s.primeName)))
+			anegPow.Exp(aneg, new(big.Int).Rsh(secretdata.getSecret(s.primeName), 1), secretdata.getSecret(s.primeName))
+		}
 
-	// And build its pederson commitment
-	commit.anegPederson = newPedersonSecret(g, strings.Join([]string{s.myname, "aneg"}, "_"), aneg)
+		// And build its pederson commitments
+		anegRes := new(big.Int).Sub(anegPow, secretdata.getSecret(s.primeName))
+		commit.anegPederson = newPedersonSecret(g, commit.anegPederson.name, aneg)
+		commit.anegResPederson = newPedersonSecret(g, commit.anegResPederson.name, anegRes)
+	}
 
 	// Generate result pederson commits and proof data
 	aRes := new(big.Int).Exp(a, new(big.Int).Rsh(secretdata.getSecret(s.primeName), 1), secretdata.getSecret(s.primeName))
 	if aRes.Cmp(big.NewInt(1)) != 0 {
 		aRes.Sub(aRes, secretdata.getSecret(s.primeName))
 	}
-	anegRes := new(big.Int).Exp(aneg, new(big.Int).Rsh(secretdata.getSecret(s.primeName), 1), secretdata.getSecret(s.primeName))
-	anegRes.Sub(anegRes, secretdata.getSecret(s.primeName))
-	commit.aResPederson = newPedersonSecret(g, strings.Join([]string{s.myname, "ares"}, "_"), aRes)
-	commit.anegResPederson = newPedersonSecret(g, strings.Join([]string{s.myname, "anegres"}, "_"), anegRes)
+	commit.aResPederson = newPedersonSecret(g, commit.aResPederson.name, aRes)
 	commit.aInvalidResult = common.RandomBigInt(g.order)
 	commit.aInvalidChallenge = common.RandomBigInt(new(big.Int).Lsh(big.NewInt(1), 256))
 	commit.aValid = commit.aResPederson.hider
 	commit.aValidRandomizer = common.RandomBigInt(g.order)
-	if aRes.Cmp(big.NewInt(1)) == 0 {
-		commit.nameAValid = strings.Join([]string{s.myname, "aresplus1hider"}, "_")
-		commit.nameAInvalid = strings.Join([]string{s.myname, "aresmin1hider"}, "_")
-		commit.aPositive = true
-	} else {
-		commit.nameAValid = strings.Join([]string{s.myname, "aresmin1hider"}, "_")
-		commit.nameAInvalid = strings.Join([]string{s.myname, "aresplus1hider"}, "_")
-		commit.aPositive = false
+	commit.aPositive = aRes.Cmp(big.NewInt(1)) == 0
+	if !commit.aPositive {
+		commit.nameAValid, commit.nameAInvalid = commit.nameAInvalid, commit.nameAValid
 	}
 
 	// the half p pederson commit
-	commit.halfPPederson = newPedersonSecret(g, strings.Join([]string{s.myname, "halfp"}, "_"), new(big.Int).Rsh(secretdata.getSecret(s.primeName), 1))
+	commit.halfPPederson = newPedersonSecret(g, commit.halfPPederson.name, new(big.Int).Rsh(secretdata.getSecret(s.primeName), 1))
 
 	// Build structure for the a generation proofs
-	agenproof := representationProofStructure{
-		[]lhsContribution{
-			lhsContribution{commit.preaPederson.name, big.NewInt(1)},
-			lhsContribution{"g", new(big.Int).Mod(aAdd, g.order)},
-			lhsContribution{commit.aPederson.name, big.NewInt(-1)},
-		},
-		[]rhsContribution{
-			rhsContribution{s.primeName, commit.namePreaMod, 1},
-			rhsContribution{"h", commit.namePreaHider, 1},
-		},
-	}
-	agenrange := rangeProofStructure{
-		agenproof,
-		commit.namePreaMod,
-		0,
-		s.bitlen,
-	}
+	agenrange := s.aGenRangeStructure(new(big.Int).Mod(aAdd, g.order))
+	agenproof := agenrange.representationProofStructure
 
 	// Inner secrets and bases structures
-	innerBases := newBaseMerge(&commit.preaPederson, &commit.aPederson, &commit.anegPederson, &commit.aResPederson, &commit.anegResPederson, &commit.halfPPederson, bases)
-	secrets := newSecretMerge(&commit, &commit.preaPederson, &commit.aPederson, &commit.anegPederson, &commit.aResPederson, &commit.anegResPederson, &commit.halfPPederson, secretdata)
+	baseParts := []baseLookup{}
+	secretParts := []secretLookup{&commit}
+	for _, p := range s.commitPedersons(&commit) {
+		baseParts = append(baseParts, p)
+		secretParts = append(secretParts, p)
+	}
+	innerBases := newBaseMerge(append(baseParts, bases)...)
+	secrets := newSecretMerge(append(secretParts, secretdata)...)
 
 	// Build all commitments
 	list = commit.halfPPederson.generateCommitments(list)
 	list = commit.preaPederson.generateCommitments(list)
 	list = commit.aPederson.generateCommitments(list)
-	list = commit.anegPederson.generateCommitments(list)
+	if !s.compact {
+		list = commit.anegPederson.generateCommitments(list)
+	}
 	list = commit.aResPederson.generateCommitments(list)
-	list = commit.anegResPederson.generateCommitments(list)
+	if !s.compact {
+		list = commit.anegResPederson.generateCommitments(list)
+	}
 	list = s.halfPRep.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
 	list = s.preaRep.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
 	list, commit.preaRangeCommit = s.preaRange.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
 	list = s.aRep.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
 	list, commit.aRangeCommit = s.aRange.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
-	list = s.anegRep.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
-	list, commit.anegRangeCommit = s.anegRange.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
+	if !s.compact {
+		list = s.anegRep.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
+		list, commit.anegRangeCommit = s.anegRange.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
+	}
 	list = agenproof.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
 	list, commit.preaModRangeCommit = agenrange.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
 	list = s.aResRep.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
-	list = s.anegResRep.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
+	if !s.compact {
+		list = s.anegResRep.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
+	}
 	if commit.aPositive {
 		list = s.aPlus1ResRep.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
 		list = s.aMin1ResRep.generateCommitmentsFromProof(g, list, commit.aInvalidChallenge, &innerBases, &commit)
@@ -366,7 +542,9 @@ func (s *primeProofStructure) generateCommitmentsFromSecrets(g group, list []*bi
 		list = s.aMin1ResRep.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
 	}
 	list, commit.aExpCommit = s.aExp.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
-	list, commit.anegExpCommit = s.anegExp.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
+	if !s.compact {
+		list, commit.anegExpCommit = s.anegExp.generateCommitmentsFromSecrets(g, list, &innerBases, &secrets)
+	}
 
 	return list, commit
 }
@@ -375,40 +553,24 @@ func (s *primeProofStructure) buildProof(g group, challenge *big.Int, commit pri
 	var proof PrimeProof
 
 	// Rebuild structure for the a generation proofs
-	aAdd := common.GetHashNumber(commit.preaPederson.commit, nil, 0, s.bitlen)
-	agenproof := representationProofStructure{
-		[]lhsContribution{
-			lhsContribution{commit.preaPederson.name, big.NewInt(1)},
-			lhsContribution{"g", new(big.Int).Mod(aAdd, g.order)},
-			lhsContribution{commit.aPederson.name, big.NewInt(-1)},
-		},
-		[]rhsContribution{
-			rhsContribution{s.primeName, commit.namePreaMod, 1},
-			rhsContribution{"h", commit.namePreaHider, 1},
-		},
-	}
-	agenrange := rangeProofStructure{
-		agenproof,
-		commit.namePreaMod,
-		0,
-		s.bitlen,
-	}
+	agenrange := s.aGenRangeStructure(new(big.Int).Mod(commit.aAdd, g.order))
 
 	// Recreate full secrets lookup
-	secrets := newSecretMerge(&commit, &commit.preaPederson, &commit.aPederson, &commit.anegPederson, secretdata)
+	secretParts := []secretLookup{&commit, &commit.preaPederson, &commit.aPederson}
+	if !s.compact {
+		secretParts = append(secretParts, &commit.anegPederson)
+	}
+	secrets := newSecretMerge(append(secretParts, secretdata)...)
 
 	// Generate proofs for the pederson commitments
 	proof.HalfPCommit = commit.halfPPederson.buildProof(g, challenge)
 	proof.PreaCommit = commit.preaPederson.buildProof(g, challenge)
 	proof.ACommit = commit.aPederson.buildProof(g, challenge)
-	proof.AnegCommit = commit.anegPederson.buildProof(g, challenge)
 	proof.AResCommit = commit.aResPederson.buildProof(g, challenge)
-	proof.AnegResCommit = commit.anegResPederson.buildProof(g, challenge)
 
 	// Generate range proofs
 	proof.PreaRangeProof = s.preaRange.buildProof(g, challenge, commit.preaRangeCommit, &secrets)
 	proof.ARangeProof = s.aRange.buildProof(g, challenge, commit.aRangeCommit, &secrets)
-	proof.AnegRangeProof = s.anegRange.buildProof(g, challenge, commit.anegRangeCommit, &secrets)
 	proof.PreaModRangeProof = agenrange.buildProof(g, challenge, commit.preaModRangeCommit, &secrets)
 
 	// And calculate our results
@@ -454,7 +616,21 @@ func (s *primeProofStructure) buildProof(g group, challenge *big.Int, commit pri
 	}
 
 	proof.AExpProof = s.aExp.buildProof(g, challenge, commit.aExpCommit, &secrets)
-	proof.AnegExpProof = s.anegExp.buildProof(g, challenge, commit.anegExpCommit, &secrets)
+
+	if !s.compact {
+		anegCommit := commit.anegPederson.buildProof(g, challenge)
+		anegResCommit := commit.anegResPederson.buildProof(g, challenge)
+		anegRangeProof := s.anegRange.buildProof(g, challenge, commit.anegRangeCommit, &secrets)
+		anegExpProof := s.anegExp.buildProof(g, challenge, commit.anegExpCommit, &secrets)
+		proof.AnegCommit = &anegCommit
+		proof.AnegResCommit = &anegResCommit
+		proof.AnegRangeProof = &anegRangeProof
+		proof.AnegExpProof = &anegExpProof
+	}
+
+	for i := range s.rounds {
+		proof.Rounds = append(proof.Rounds, s.rounds[i].buildProof(g, challenge, commit.rounds[i], secretdata))
+	}
 
 	return proof
 }
@@ -466,34 +642,15 @@ func (s *primeProofStructure) fakeProof(g group, challenge *big.Int) PrimeProof
 	proof.HalfPCommit = newPedersonFakeProof(g)
 	proof.PreaCommit = newPedersonFakeProof(g)
 	proof.ACommit = newPedersonFakeProof(g)
-	proof.AnegCommit = newPedersonFakeProof(g)
 	proof.AResCommit = newPedersonFakeProof(g)
-	proof.AnegResCommit = newPedersonFakeProof(g)
 
-	// Build the fake proof structure for the preaMod rangeproof
-	aAdd := common.GetHashNumber(proof.PreaCommit.Commit, nil, 0, s.bitlen)
-	agenproof := representationProofStructure{
-		[]lhsContribution{
-			lhsContribution{strings.Join([]string{s.myname, "prea"}, "_"), big.NewInt(1)},
-			lhsContribution{"g", new(big.Int).Mod(aAdd, g.order)},
-			lhsContribution{strings.Join([]string{s.myname, "a"}, "_"), big.NewInt(-1)},
-		},
-		[]rhsContribution{
-			rhsContribution{s.primeName, strings.Join([]string{s.myname, "preamod"}, "_"), 1},
-			rhsContribution{"h", strings.Join([]string{s.myname, "preahider"}, "_"), 1},
-		},
-	}
-	agenrange := rangeProofStructure{
-		agenproof,
-		strings.Join([]string{s.myname, "preamod"}, "_"),
-		0,
-		s.bitlen,
-	}
+	// Build the fake proof structure for the preaMod rangeproof, whose shape
+	// does not depend on aAdd
+	agenrange := s.aGenRangeStructure(big.NewInt(0))
 
 	// Fake the range proofs
 	proof.PreaRangeProof = s.preaRange.fakeProof(g)
 	proof.ARangeProof = s.aRange.fakeProof(g)
-	proof.AnegRangeProof = s.anegRange.fakeProof(g)
 	proof.PreaModRangeProof = agenrange.fakeProof(g)
 
 	// And fake our bits
@@ -505,47 +662,58 @@ func (s *primeProofStructure) fakeProof(g group, challenge *big.Int) PrimeProof
 	proof.AMin1Challenge = new(big.Int).Xor(challenge, proof.APlus1Challenge)
 
 	proof.AExpProof = s.aExp.fakeProof(g, challenge)
-	proof.AnegExpProof = s.anegExp.fakeProof(g, challenge)
+
+	if !s.compact {
+		anegCommit := newPedersonFakeProof(g)
+		anegResCommit := newPedersonFakeProof(g)
+		anegRangeProof := s.anegRange.fakeProof(g)
+		anegExpProof := s.anegExp.fakeProof(g, challenge)
+		proof.AnegCommit = &anegCommit
+		proof.AnegResCommit = &anegResCommit
+		proof.AnegRangeProof = &anegRangeProof
+		proof.AnegExpProof = &anegExpProof
+	}
+
+	for i := range s.rounds {
+		proof.Rounds = append(proof.Rounds, s.rounds[i].fakeProof(g, challenge))
+	}
 
 	return proof
 }
 
 func (s *primeProofStructure) verifyProofStructure(challenge *big.Int, proof PrimeProof) bool {
+	// The aneg parts should be present exactly for full proofs
+	if s.compact {
+		if proof.AnegCommit != nil || proof.AnegResCommit != nil || proof.AnegRangeProof != nil || proof.AnegExpProof != nil {
+			return false
+		}
+	} else {
+		if proof.AnegCommit == nil || proof.AnegResCommit == nil || proof.AnegRangeProof == nil || proof.AnegExpProof == nil {
+			return false
+		}
+		if !proof.AnegCommit.verifyStructure() ||
+			!proof.AnegResCommit.verifyStructure() ||
+			!s.anegRange.verifyProofStructure(*proof.AnegRangeProof) ||
+			!s.anegExp.verifyProofStructure(challenge, *proof.AnegExpProof) {
+			return false
+		}
+	}
+
 	// Check pederson commitments
 	if !proof.HalfPCommit.verifyStructure() ||
 		!proof.PreaCommit.verifyStructure() ||
 		!proof.ACommit.verifyStructure() ||
-		!proof.AnegCommit.verifyStructure() ||
-		!proof.AResCommit.verifyStructure() ||
-		!proof.AnegResCommit.verifyStructure() {
+		!proof.AResCommit.verifyStructure() {
 		return false
 	}
 
-	// Build the proof structure for the preaMod rangeproof
-	aAdd := common.GetHashNumber(proof.PreaCommit.Commit, nil, 0, s.bitlen)
-	agenproof := representationProofStructure{
-		[]lhsContribution{
-			lhsContribution{strings.Join([]string{s.myname, "prea"}, "_"), big.NewInt(1)},
-			// LhsContribution{"g", new(big.Int).Mod(aAdd, g.order)},
-			lhsContribution{"g", aAdd},
-			lhsContribution{strings.Join([]string{s.myname, "a"}, "_"), big.NewInt(-1)},
-		},
-		[]rhsContribution{
-			rhsContribution{s.primeName, strings.Join([]string{s.myname, "preamod"}, "_"), 1},
-			rhsContribution{"h", strings.Join([]string{s.myname, "preahider"}, "_"), 1},
-		},
-	}
-	agenrange := rangeProofStructure{
-		agenproof,
-		strings.Join([]string{s.myname, "preamod"}, "_"),
-		0,
-		s.bitlen,
-	}
+	// Build the proof structure for the preaMod rangeproof, whose shape does
+	// not depend on aAdd
+	agenrange := s.aGenRangeStructure(big.NewInt(0))
 
 	// Check the range proofs
 	if !s.preaRange.verifyProofStructure(proof.PreaRangeProof) ||
 		!s.aRange.verifyProofStructure(proof.ARangeProof) ||
-		!s.anegRange.verifyProofStructure(proof.AnegRangeProof) ||
 		!agenrange.verifyProofStructure(proof.PreaModRangeProof) {
 		return false
 	}
@@ -564,15 +732,38 @@ func (s *primeProofStructure) verifyProofStructure(challenge *big.Int, proof Pri
 		return false
 	}
 
-	if !s.aExp.verifyProofStructure(challenge, proof.AExpProof) ||
-		!s.anegExp.verifyProofStructure(challenge, proof.AnegExpProof) {
+	if !s.aExp.verifyProofStructure(challenge, proof.AExpProof) {
 		return false
 	}
 
+	if len(proof.Rounds) != len(s.rounds) {
+		return false
+	}
+	for i := range s.rounds {
+		if !s.rounds[i].verifyProofStructure(challenge, proof.Rounds[i]) {
+			return false
+		}
+	}
+
 	return true
 }
 
 func (s *primeProofStructure) generateCommitmentsFromProof(g group, list []*big.Int, challenge *big.Int, bases baseLookup, proofdata proofLookup, proof PrimeProof) []*big.Int {
+	preaCommits := []*big.Int{proof.PreaCommit.Commit}
+	for i := range proof.Rounds {
+		preaCommits = append(preaCommits, proof.Rounds[i].PreaCommit.Commit)
+	}
+	aAdds := s.aAdds(preaCommits)
+
+	list = s.generateRoundCommitmentsFromProof(g, list, challenge, bases, proofdata, proof, aAdds[0])
+	for i := range s.rounds {
+		list = s.rounds[i].generateRoundCommitmentsFromProof(g, list, challenge, bases, proofdata, proof.Rounds[i], aAdds[i+1])
+	}
+
+	return list
+}
+
+func (s *primeProofStructure) generateRoundCommitmentsFromProof(g group, list []*big.Int, challenge *big.Int, bases baseLookup, proofdata proofLookup, proof PrimeProof, aAdd *big.Int) []*big.Int {
 	// Setup
 	proof.namePreaMod = strings.Join([]string{s.myname, "preamod"}, "_")
 	proof.namePreaHider = strings.Join([]string{s.myname, "preahider"}, "_")
@@ -581,56 +772,59 @@ func (s *primeProofStructure) generateCommitmentsFromProof(g group, list []*big.
 	proof.HalfPCommit.setName(strings.Join([]string{s.myname, "halfp"}, "_"))
 	proof.PreaCommit.setName(strings.Join([]string{s.myname, "prea"}, "_"))
 	proof.ACommit.setName(strings.Join([]string{s.myname, "a"}, "_"))
-	proof.AnegCommit.setName(strings.Join([]string{s.myname, "aneg"}, "_"))
 	proof.AResCommit.setName(strings.Join([]string{s.myname, "ares"}, "_"))
-	proof.AnegResCommit.setName(strings.Join([]string{s.myname, "anegres"}, "_"))
 
-	// Build the proof structure for the preamod proofs
-	aAdd := common.GetHashNumber(proof.PreaCommit.Commit, nil, 0, s.bitlen)
-	agenproof := representationProofStructure{
-		[]lhsContribution{
-			lhsContribution{strings.Join([]string{s.myname, "prea"}, "_"), big.NewInt(1)},
-			lhsContribution{"g", new(big.Int).Mod(aAdd, g.order)},
-			lhsContribution{strings.Join([]string{s.myname, "a"}, "_"), big.NewInt(-1)},
-		},
-		[]rhsContribution{
-			rhsContribution{s.primeName, strings.Join([]string{s.myname, "preamod"}, "_"), 1},
-			rhsContribution{"h", strings.Join([]string{s.myname, "preahider"}, "_"), 1},
-		},
-	}
-	agenrange := rangeProofStructure{
-		agenproof,
-		strings.Join([]string{s.myname, "preamod"}, "_"),
-		0,
-		s.bitlen,
+	if !s.compact {
+		proof.AnegCommit.setName(strings.Join([]string{s.myname, "aneg"}, "_"))
+		proof.AnegResCommit.setName(strings.Join([]string{s.myname, "anegres"}, "_"))
 	}
 
+	// Build the proof structure for the preamod proofs
+	agenrange := s.aGenRangeStructure(new(big.Int).Mod(aAdd, g.order))
+	agenproof := agenrange.representationProofStructure
+
 	// inner bases
-	innerBases := newBaseMerge(&proof.PreaCommit, &proof.ACommit, &proof.AnegCommit, &proof.AResCommit, &proof.AnegResCommit, &proof.HalfPCommit, bases)
-	proofs := newProofMerge(&proof, &proof.PreaCommit, &proof.ACommit, &proof.AnegCommit, &proof.AResCommit, &proof.AnegResCommit, &proof.HalfPCommit, proofdata)
+	baseParts := []baseLookup{&proof.PreaCommit, &proof.ACommit, &proof.AResCommit, &proof.HalfPCommit}
+	proofParts := []proofLookup{&proof, &proof.PreaCommit, &proof.ACommit, &proof.AResCommit, &proof.HalfPCommit}
+	if !s.compact {
+		baseParts = append(baseParts, proof.AnegCommit, proof.AnegResCommit)
+		proofParts = append(proofParts, proof.AnegCommit, proof.AnegResCommit)
+	}
+	innerBases := newBaseMerge(append(baseParts, bases)...)
+	proofs := newProofMerge(append(proofParts, proofdata)...)
 
 	// Build all commitments
 	list = proof.HalfPCommit.generateCommitments(list)
 	list = proof.PreaCommit.generateCommitments(list)
 	list = proof.ACommit.generateCommitments(list)
-	list = proof.AnegCommit.generateCommitments(list)
+	if !s.compact {
+		list = proof.AnegCommit.generateCommitments(list)
+	}
 	list = proof.AResCommit.generateCommitments(list)
-	list = proof.AnegResCommit.generateCommitments(list)
+	if !s.compact {
+		list = proof.AnegResCommit.generateCommitments(list)
+	}
 	list = s.halfPRep.generateCommitmentsFromProof(g, list, challenge, &innerBases, &proofs)
 	list = s.preaRep.generateCommitmentsFromProof(g, list, challenge, &innerBases, &proofs)
 	list = s.preaRange.generateCommitmentsFromProof(g, list, challenge, &innerBases, proof.PreaRangeProof)
 	list = s.aRep.generateCommitmentsFromProof(g, list, challenge, &innerBases, &proofs)
 	list = s.aRange.generateCommitmentsFromProof(g, list, challenge, &innerBases, proof.ARangeProof)
-	list = s.anegRep.generateCommitmentsFromProof(g, list, challenge, &innerBases, &proofs)
-	list = s.anegRange.generateCommitmentsFromProof(g, list, challenge, &innerBases, proof.AnegRangeProof)
+	if !s.compact {
+		list = s.anegRep.generateCommitmentsFromProof(g, list, challenge, &innerBases, &proofs)
+		list = s.anegRange.generateCommitmentsFromProof(g, list, challenge, &innerBases, *proof.AnegRangeProof)
+	}
 	list = agenproof.generateCommitmentsFromProof(g, list, challenge, &innerBases, &proofs)
 	list = agenrange.generateCommitmentsFromProof(g, list, challenge, &innerBases, proof.PreaModRangeProof)
 	list = s.aResRep.generateCommitmentsFromProof(g, list, challenge, &innerBases, &proofs)
-	list = s.anegResRep.generateCommitmentsFromProof(g, list, challenge, &innerBases, &proofs)
+	if !s.compact {
+		list = s.anegResRep.generateCommitmentsFromProof(g, list, challenge, &innerBases, &proofs)
+	}
 	list = s.aPlus1ResRep.generateCommitmentsFromProof(g, list, proof.APlus1Challenge, &innerBases, &proofs)
 	list = s.aMin1ResRep.generateCommitmentsFromProof(g, list, proof.AMin1Challenge, &innerBases, &proofs)
 	list = s.aExp.generateCommitmentsFromProof(g, list, challenge, &innerBases, &proofs, proof.AExpProof)
-	list = s.anegExp.generateCommitmentsFromProof(g, list, challenge, &innerBases, &proofs, proof.AnegExpProof)
+	if !s.compact {
+		list = s.anegExp.generateCommitmentsFromProof(g, list, challenge, &innerBases, &proofs, *proof.AnegExpProof)
+	}
 
 	return list
 }
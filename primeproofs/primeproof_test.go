@@ -1,8 +1,11 @@
 package primeproofs
 
 import "testing"
+import "crypto/rand"
 import "encoding/json"
+import "strings"
 import "github.com/privacybydesign/gabi/big"
+import "github.com/privacybydesign/keyproof/common"
 
 func TestPrimeProofFlow(t *testing.T) {
 	g, gok := buildGroup(big.NewInt(47))
@@ -13,7 +16,7 @@ func TestPrimeProofFlow(t *testing.T) {
 
 	Follower.(*TestFollower).count = 0
 
-	s := newPrimeProofStructure("p", 4)
+	s := newPrimeProofStructure("p", 4, &LegacySecurityParams)
 
 	const p = 11
 	pCommit := newPedersonSecret(g, "p", big.NewInt(p))
@@ -59,7 +62,7 @@ func TestPrimeProofFake(t *testing.T) {
 		return
 	}
 
-	s := newPrimeProofStructure("p", 4)
+	s := newPrimeProofStructure("p", 4, &LegacySecurityParams)
 
 	proof := s.fakeProof(g, big.NewInt(12345))
 
@@ -75,7 +78,7 @@ func TestPrimeProofJSON(t *testing.T) {
 		return
 	}
 
-	s := newPrimeProofStructure("p", 4)
+	s := newPrimeProofStructure("p", 4, &LegacySecurityParams)
 
 	proofBefore := s.fakeProof(g, big.NewInt(12345))
 	proofJSON, err := json.Marshal(proofBefore)
@@ -103,7 +106,7 @@ func TestPrimeProofVerify(t *testing.T) {
 		return
 	}
 
-	s := newPrimeProofStructure("p", 4)
+	s := newPrimeProofStructure("p", 4, &LegacySecurityParams)
 
 	proof := s.fakeProof(g, big.NewInt(12345))
 	proof.PreaCommit.Commit = nil
@@ -219,3 +222,272 @@ func TestPrimeProofVerify(t *testing.T) {
 		t.Error("Accepting wrong anegexpproof")
 	}
 }
+
+var compactPrimeTestParams = SecurityParams{
+	Name:                            "compact-prime-test",
+	RangeProofIters:                 1,
+	RangeProofEpsilon:               64,
+	LargeChallengeRangeProofs:       true,
+	AlmostSafePrimeProductNonceSize: 64,
+	AlmostSafePrimeProductIters:     10,
+	DisjointPrimeProductIters:       2,
+	PrimePowerProductIters:          8,
+	SquareFreeIters:                 2,
+	DiscreteLogProofIters:           8,
+	CompactPrimeProofs:              true,
+	MinimumFactor:                   minimumFactor,
+}
+
+func TestCompactPrimeProofFlow(t *testing.T) {
+	g, gok := buildGroup(big.NewInt(2039))
+	if !gok {
+		t.Error("Failed to setup group for Prime proof testing")
+		return
+	}
+
+	Follower.(*TestFollower).count = 0
+
+	s := newPrimeProofStructure("p", 9, &compactPrimeTestParams)
+	full := newPrimeProofStructure("p", 9, &FastTestSecurityParams)
+	if s.numCommitments() >= full.numCommitments() || s.numRangeProofs() >= full.numRangeProofs() {
+		t.Error("Compact prime proof not smaller than full one")
+	}
+
+	const p = 503
+	pCommit := newPedersonSecret(g, "p", big.NewInt(p))
+	bases := newBaseMerge(&g, &pCommit)
+
+	listSecrets, commit := s.generateCommitmentsFromSecrets(g, []*big.Int{}, &bases, &pCommit)
+	if len(listSecrets) != s.numCommitments() {
+		t.Error("NumCommitments is off")
+	}
+	if Follower.(*TestFollower).count != s.numRangeProofs() {
+		t.Error("Logging is off GenerateCommitmentsFromSecrets")
+	}
+	Follower.(*TestFollower).count = 0
+
+	proof := s.buildProof(g, big.NewInt(12345), commit, &pCommit)
+	if proof.AnegCommit != nil || proof.AnegResCommit != nil || proof.AnegRangeProof != nil || proof.AnegExpProof != nil {
+		t.Error("Compact prime proof contains aneg parts")
+	}
+	pProof := pCommit.buildProof(g, big.NewInt(12345))
+	pProof.setName("p")
+	basesProof := newBaseMerge(&g, &pProof)
+
+	if !s.verifyProofStructure(big.NewInt(12345), proof) {
+		t.Error("Proof structure rejected.\n")
+		return
+	}
+	listProof := s.generateCommitmentsFromProof(g, []*big.Int{}, big.NewInt(12345), &basesProof, &pProof, proof)
+	if Follower.(*TestFollower).count != s.numRangeProofs() {
+		t.Error("Logging is off on GenerateCommitmentsFromProof")
+	}
+	if !listCmp(listSecrets, listProof) {
+		t.Error("Commitment lists differ.")
+	}
+
+	// Compact and full proofs are not interchangeable
+	if full.verifyProofStructure(big.NewInt(12345), proof) {
+		t.Error("Compact prime proof accepted as full one")
+	}
+	if s.verifyProofStructure(big.NewInt(12345), full.fakeProof(g, big.NewInt(12345))) {
+		t.Error("Full prime proof accepted as compact one")
+	}
+}
+
+func TestValidKeyProofCompactPrimes(t *testing.T) {
+	const p = 26903
+	const q = 27803
+
+	s := NewValidKeyProofStructure(big.NewInt(p*q), big.NewInt(36), big.NewInt(49), []*big.Int{big.NewInt(64)}, withTestSecurityParams(&compactPrimeTestParams))
+	if report := s.Validate(); !report.Ok() {
+		t.Errorf("Compact prime proof structure invalid: %v", report)
+	}
+	proof := s.BuildProof(big.NewInt((p-1)/2), big.NewInt((q-1)/2))
+	if !s.VerifyProof(proof) {
+		t.Error("Proof with compact prime proofs rejected")
+	}
+
+	full := NewValidKeyProofStructure(big.NewInt(p*q), big.NewInt(36), big.NewInt(49), []*big.Int{big.NewInt(64)}, WithSecurityParams("fast-test"))
+	fullProof := full.BuildProof(big.NewInt((p-1)/2), big.NewInt((q-1)/2))
+	compactJSON, _ := json.Marshal(proof.PprimeIsPrimeProof)
+	fullJSON, _ := json.Marshal(fullProof.PprimeIsPrimeProof)
+	if len(compactJSON) >= len(fullJSON) {
+		t.Errorf("Compact prime proof not smaller, %v vs %v bytes", len(compactJSON), len(fullJSON))
+	}
+}
+
+// Compare full and compact prime proofs for a 1024 bit prime
+func benchmarkPrimeProof(b *testing.B, params *SecurityParams) {
+	P, err := rand.Prime(rand.Reader, 1024)
+	if err != nil {
+		b.Fatal(err)
+	}
+	s := newPrimeProofStructure("p", 1024, params)
+	g, gok := buildGroup(findSafePrime(params.groupPrimeSize(2048)))
+	if !gok {
+		b.Fatal("Failed to setup group")
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pCommit := newPedersonSecret(g, "p", big.Convert(P))
+		bases := newBaseMerge(&g, &pCommit)
+		list, commit := s.generateCommitmentsFromSecrets(g, []*big.Int{}, &bases, &pCommit)
+		challenge := common.HashCommit(list)
+		proof := s.buildProof(g, challenge, commit, &pCommit)
+		pProof := pCommit.buildProof(g, challenge)
+		pProof.setName("p")
+		basesProof := newBaseMerge(&g, &pProof)
+		s.generateCommitmentsFromProof(g, []*big.Int{}, challenge, &basesProof, &pProof, proof)
+	}
+}
+
+func BenchmarkPrimeProof(b *testing.B)        { benchmarkPrimeProof(b, &FastTestSecurityParams) }
+func BenchmarkCompactPrimeProof(b *testing.B) { benchmarkPrimeProof(b, &compactPrimeTestParams) }
+
+func TestPrimeProofRounds(t *testing.T) {
+	g, gok := buildGroup(big.NewInt(2039))
+	if !gok {
+		t.Error("Failed to setup group for Prime proof testing")
+		return
+	}
+
+	params := FastTestSecurityParams
+	params.PrimeProofRounds = 3
+
+	Follower.(*TestFollower).count = 0
+
+	s := newPrimeProofStructure("p", 9, &params)
+	single := newPrimeProofStructure("p", 9, &FastTestSecurityParams)
+	if s.numCommitments() <= single.numCommitments() {
+		t.Error("Extra rounds didn't add commitments")
+	}
+
+	const p = 503
+	pCommit := newPedersonSecret(g, "p", big.NewInt(p))
+	bases := newBaseMerge(&g, &pCommit)
+
+	listSecrets, commit := s.generateCommitmentsFromSecrets(g, []*big.Int{}, &bases, &pCommit)
+	if len(listSecrets) != s.numCommitments() {
+		t.Error("NumCommitments is off")
+	}
+	if Follower.(*TestFollower).count != s.numRangeProofs() {
+		t.Error("Logging is off GenerateCommitmentsFromSecrets")
+	}
+	Follower.(*TestFollower).count = 0
+
+	proof := s.buildProof(g, big.NewInt(12345), commit, &pCommit)
+	if len(proof.Rounds) != 2 || proof.Rounds[0].AnegExpProof != nil || proof.AnegExpProof == nil {
+		t.Error("Proof rounds have the wrong shape")
+	}
+	pProof := pCommit.buildProof(g, big.NewInt(12345))
+	pProof.setName("p")
+	basesProof := newBaseMerge(&g, &pProof)
+
+	if !s.verifyProofStructure(big.NewInt(12345), proof) {
+		t.Error("Proof structure rejected.\n")
+		return
+	}
+	listProof := s.generateCommitmentsFromProof(g, []*big.Int{}, big.NewInt(12345), &basesProof, &pProof, proof)
+	if Follower.(*TestFollower).count != s.numRangeProofs() {
+		t.Error("Logging is off on GenerateCommitmentsFromProof")
+	}
+	if !listCmp(listSecrets, listProof) {
+		t.Error("Commitment lists differ.")
+	}
+
+	if single.verifyProofStructure(big.NewInt(12345), proof) {
+		t.Error("Proof with extra rounds accepted by single round structure")
+	}
+	proof.Rounds = proof.Rounds[:1]
+	if s.verifyProofStructure(big.NewInt(12345), proof) {
+		t.Error("Proof with missing round accepted")
+	}
+}
+
+func TestPrimeProofRoundsSharedBases(t *testing.T) {
+	params := FastTestSecurityParams
+	params.PrimeProofRounds = 3
+	s := newPrimeProofStructure("p", 9, &params)
+	single := newPrimeProofStructure("p", 9, &FastTestSecurityParams)
+
+	// A single round keeps deriving its base from its own prea commitment
+	if single.aAdds([]*big.Int{big.NewInt(5)})[0].Cmp(common.GetHashNumber(big.NewInt(5), nil, 0, 9)) != 0 {
+		t.Error("Single round base changed")
+	}
+
+	// With more rounds, changing any prea commitment changes all bases
+	before := s.aAdds([]*big.Int{big.NewInt(5), big.NewInt(6), big.NewInt(7)})
+	after := s.aAdds([]*big.Int{big.NewInt(5), big.NewInt(6), big.NewInt(8)})
+	for i := range before {
+		if before[i].Cmp(after[i]) == 0 {
+			t.Errorf("Base of round %v independent of last prea commitment", i)
+		}
+	}
+	if before[0].Cmp(before[1]) == 0 || before[1].Cmp(before[2]) == 0 {
+		t.Error("Rounds share a base")
+	}
+}
+
+func TestPrimeProofSingleRoundFormat(t *testing.T) {
+	g, gok := buildGroup(big.NewInt(47))
+	if !gok {
+		t.Error("Failed to setup group for Prime proof testing")
+		return
+	}
+
+	params := LegacySecurityParams
+	params.PrimeProofRounds = 1
+	s := newPrimeProofStructure("p", 4, &params)
+	legacy := newPrimeProofStructure("p", 4, &LegacySecurityParams)
+	if len(s.rounds) != 0 || s.numCommitments() != legacy.numCommitments() {
+		t.Error("Single round differs from default")
+	}
+
+	proofJSON, err := json.Marshal(s.fakeProof(g, big.NewInt(12345)))
+	if err != nil {
+		t.Errorf("error during json marshal: %s", err.Error())
+		return
+	}
+	if strings.Contains(string(proofJSON), "Rounds") {
+		t.Error("Single round proof contains rounds")
+	}
+}
+
+func TestPrimeProofExactRanges(t *testing.T) {
+	g, gok := buildGroup(big.NewInt(2039))
+	if !gok {
+		t.Error("Failed to setup group for Prime proof testing")
+		return
+	}
+
+	params := FastTestSecurityParams
+	params.ExactRangeProofs = true
+	s := newPrimeProofStructure("p", 9, &params)
+
+	const p = 503
+	pCommit := newPedersonSecret(g, "p", big.NewInt(p))
+	bases := newBaseMerge(&g, &pCommit)
+
+	listSecrets, commit := s.generateCommitmentsFromSecrets(g, []*big.Int{}, &bases, &pCommit)
+	if len(listSecrets) != s.numCommitments() {
+		t.Error("NumCommitments is off")
+	}
+	proof := s.buildProof(g, big.NewInt(12345), commit, &pCommit)
+	if proof.ARangeProof.Bits == nil || proof.PreaModRangeProof.Bits != nil {
+		t.Error("Exact range proofs not used for exactly the pederson commitments")
+	}
+	pProof := pCommit.buildProof(g, big.NewInt(12345))
+	pProof.setName("p")
+	basesProof := newBaseMerge(&g, &pProof)
+
+	if !s.verifyProofStructure(big.NewInt(12345), proof) {
+		t.Error("Proof structure rejected.\n")
+		return
+	}
+	listProof := s.generateCommitmentsFromProof(g, []*big.Int{}, big.NewInt(12345), &basesProof, &pProof, proof)
+	if !listCmp(listSecrets, listProof) {
+		t.Error("Commitment lists differ.")
+	}
+}
@@ -1,5 +1,6 @@
 package primeproofs
 
+import "github.com/privacybydesign/keyproof/common"
 import "github.com/privacybydesign/gabi/big"
 
 type quasiSafePrimeProductCommit struct {
@@ -13,13 +14,13 @@ type QuasiSafePrimeProductProof struct {
 	ASPPproof AlmostSafePrimeProductProof
 }
 
-func quasiSafePrimeProductBuildCommitments(list []*big.Int, Pprime *big.Int, Qprime *big.Int) ([]*big.Int, quasiSafePrimeProductCommit) {
+func quasiSafePrimeProductBuildCommitments(list []*big.Int, Pprime *big.Int, Qprime *big.Int, params *SecurityParams) ([]*big.Int, quasiSafePrimeProductCommit) {
 	var commit quasiSafePrimeProductCommit
-	list, commit.asppCommit = almostSafePrimeProductBuildCommitments(list, Pprime, Qprime)
+	list, commit.asppCommit = almostSafePrimeProductBuildCommitments(list, Pprime, Qprime, params)
 	return list, commit
 }
 
-func quasiSafePrimeProductBuildProof(Pprime *big.Int, Qprime *big.Int, challenge *big.Int, commit quasiSafePrimeProductCommit) QuasiSafePrimeProductProof {
+func quasiSafePrimeProductBuildProof(Pprime *big.Int, Qprime *big.Int, challenge *big.Int, commit quasiSafePrimeProductCommit, params *SecurityParams) QuasiSafePrimeProductProof {
 	// Calculate useful intermediaries
 	P := new(big.Int).Add(new(big.Int).Lsh(Pprime, 1), big.NewInt(1))
 	Q := new(big.Int).Add(new(big.Int).Lsh(Qprime, 1), big.NewInt(1))
@@ -28,42 +29,109 @@ func quasiSafePrimeProductBuildProof(Pprime *big.Int, Qprime *big.Int, challenge
 
 	// Build the actual proofs
 	var proof QuasiSafePrimeProductProof
-	proof.SFproof = squareFreeBuildProof(N, phiN, challenge, big.NewInt(0))
-	proof.PPPproof = primePowerProductBuildProof(P, Q, challenge, big.NewInt(1))
-	proof.DPPproof = disjointPrimeProductBuildProof(P, Q, challenge, big.NewInt(2))
-	proof.ASPPproof = almostSafePrimeProductBuildProof(Pprime, Qprime, challenge, big.NewInt(3), commit.asppCommit)
+	proof.SFproof = squareFreeBuildProof(N, phiN, challenge, big.NewInt(0), params)
+	proof.PPPproof = primePowerProductBuildProof(P, Q, challenge, big.NewInt(1), params)
+	proof.DPPproof = disjointPrimeProductBuildProof(P, Q, challenge, big.NewInt(2), params)
+	proof.ASPPproof = almostSafePrimeProductBuildProof(Pprime, Qprime, challenge, big.NewInt(3), commit.asppCommit, params)
 
 	return proof
 }
 
-func quasiSafePrimeProductVerifyStructure(proof QuasiSafePrimeProductProof) bool {
-	return squareFreeVerifyStructure(proof.SFproof) &&
-		primePowerProductVerifyStructure(proof.PPPproof) &&
-		disjointPrimeProductVerifyStructure(proof.DPPproof) &&
-		almostSafePrimeProductVerifyStructure(proof.ASPPproof)
+func quasiSafePrimeProductVerifyStructure(proof QuasiSafePrimeProductProof, params *SecurityParams) bool {
+	return squareFreeVerifyStructure(proof.SFproof, params) &&
+		primePowerProductVerifyStructure(proof.PPPproof, params) &&
+		disjointPrimeProductVerifyStructure(proof.DPPproof, params) &&
+		almostSafePrimeProductVerifyStructure(proof.ASPPproof, params)
 }
 
 func quasiSafePrimeProductExtractCommitments(list []*big.Int, proof QuasiSafePrimeProductProof) []*big.Int {
 	return almostSafePrimeProductExtractCommitments(list, proof.ASPPproof)
 }
 
-func quasiSafePrimeProductVerifyProof(N *big.Int, challenge *big.Int, proof QuasiSafePrimeProductProof) bool {
+func quasiSafePrimeProductVerifyProof(N *big.Int, challenge *big.Int, proof QuasiSafePrimeProductProof, params *SecurityParams) bool {
 	// Check N = 5 (mod 8), as this is what differentiates quasi and almost safe prime products
 	if new(big.Int).Mod(N, big.NewInt(8)).Cmp(big.NewInt(5)) != 0 {
 		return false
 	}
 
 	// Verify Minimum factor rule
-	for i := 2; i < minimumFactor; i++ {
-		check := new(big.Int).GCD(nil, nil, N, big.NewInt(int64(i)))
-		if check.Cmp(big.NewInt(1)) != 0 {
-			return false
-		}
+	if !noSmallFactors(N, params) {
+		return false
 	}
 
 	// Validate the individual parts
-	return squareFreeVerifyProof(N, challenge, big.NewInt(0), proof.SFproof) &&
-		primePowerProductVerifyProof(N, challenge, big.NewInt(1), proof.PPPproof) &&
-		disjointPrimeProductVerifyProof(N, challenge, big.NewInt(2), proof.DPPproof) &&
-		almostSafePrimeProductVerifyProof(N, challenge, big.NewInt(3), proof.ASPPproof)
+	return squareFreeVerifyProof(N, challenge, big.NewInt(0), proof.SFproof, params) &&
+		primePowerProductVerifyProof(N, challenge, big.NewInt(1), proof.PPPproof, params) &&
+		disjointPrimeProductVerifyProof(N, challenge, big.NewInt(2), proof.DPPproof, params) &&
+		almostSafePrimeProductVerifyProof(N, challenge, big.NewInt(3), proof.ASPPproof, params)
+}
+
+// Standalone proof that N is the product of two quasi-safe primes, with its
+// own challenge. This is much cheaper than a ValidKeyProof, but says nothing
+// about the primality of P' and Q' or the validity of the bases.
+type QuasiSafePrimeProductProofStructure struct {
+	n      *big.Int
+	params *SecurityParams
+}
+
+type StandaloneQuasiSafePrimeProductProof struct {
+	Challenge *big.Int
+
+	// Name of the security parameter set used, absent for legacy-80
+	SecurityParams string `json:",omitempty"`
+
+	QuasiSafePrimeProductProof
+}
+
+// Separates the challenges of standalone proofs from those of other proofs
+var standaloneQuasiSafePrimeProductDomain = new(big.Int).SetBytes([]byte("quasi-safe-prime-product"))
+
+// Setup a standalone proof for N with the given security parameters, nil
+// selecting legacy-80
+func NewQuasiSafePrimeProductProofStructure(N *big.Int, params *SecurityParams) QuasiSafePrimeProductProofStructure {
+	if params == nil {
+		params = &LegacySecurityParams
+	}
+	return QuasiSafePrimeProductProofStructure{new(big.Int).Set(N), params}
+}
+
+func (s *QuasiSafePrimeProductProofStructure) commitmentList() []*big.Int {
+	var list []*big.Int
+	list = append(list, standaloneQuasiSafePrimeProductDomain)
+	list = append(list, new(big.Int).SetBytes([]byte(s.params.Name)))
+	list = append(list, s.n)
+	return list
+}
+
+func (s *QuasiSafePrimeProductProofStructure) BuildProof(Pprime *big.Int, Qprime *big.Int) StandaloneQuasiSafePrimeProductProof {
+	list, commit := quasiSafePrimeProductBuildCommitments(s.commitmentList(), Pprime, Qprime, s.params)
+	challenge := common.HashCommit(list)
+
+	var proof StandaloneQuasiSafePrimeProductProof
+	proof.Challenge = challenge
+	if s.params != &LegacySecurityParams {
+		proof.SecurityParams = s.params.Name
+	}
+	proof.QuasiSafePrimeProductProof = quasiSafePrimeProductBuildProof(Pprime, Qprime, challenge, commit, s.params)
+	return proof
+}
+
+func (s *QuasiSafePrimeProductProofStructure) VerifyProof(proof StandaloneQuasiSafePrimeProductProof) bool {
+	recordedParams := proof.SecurityParams
+	if recordedParams == "" {
+		recordedParams = LegacySecurityParams.Name
+	}
+	if recordedParams != s.params.Name {
+		return false
+	}
+	if proof.Challenge == nil || !quasiSafePrimeProductVerifyStructure(proof.QuasiSafePrimeProductProof, s.params) {
+		return false
+	}
+
+	list := quasiSafePrimeProductExtractCommitments(s.commitmentList(), proof.QuasiSafePrimeProductProof)
+	if proof.Challenge.Cmp(common.HashCommit(list)) != 0 {
+		return false
+	}
+
+	return quasiSafePrimeProductVerifyProof(s.n, proof.Challenge, proof.QuasiSafePrimeProductProof, s.params)
 }
@@ -8,13 +8,13 @@ import "github.com/privacybydesign/gabi/big"
 func TestQuasiSafePrimeProductCycle(t *testing.T) {
 	const p = 13451
 	const q = 13901
-	listBefore, commit := quasiSafePrimeProductBuildCommitments([]*big.Int{}, big.NewInt(p), big.NewInt(q))
-	proof := quasiSafePrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), commit)
-	if !quasiSafePrimeProductVerifyStructure(proof) {
+	listBefore, commit := quasiSafePrimeProductBuildCommitments([]*big.Int{}, big.NewInt(p), big.NewInt(q), &LegacySecurityParams)
+	proof := quasiSafePrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), commit, &LegacySecurityParams)
+	if !quasiSafePrimeProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Proof structure rejected")
 	}
 	listAfter := quasiSafePrimeProductExtractCommitments([]*big.Int{}, proof)
-	ok := quasiSafePrimeProductVerifyProof(big.NewInt((2*p+1)*(2*q+1)), big.NewInt(12345), proof)
+	ok := quasiSafePrimeProductVerifyProof(big.NewInt((2*p+1)*(2*q+1)), big.NewInt(12345), proof, &LegacySecurityParams)
 	if !ok {
 		t.Error("QuasiSafePrimeProduct rejected")
 	}
@@ -32,9 +32,9 @@ func TestQuasiSafePrimeProductFullCycle(t *testing.T) {
 	// Build proof
 	const p = 13451
 	const q = 13901
-	listBefore, commit := quasiSafePrimeProductBuildCommitments([]*big.Int{}, big.NewInt(p), big.NewInt(q))
+	listBefore, commit := quasiSafePrimeProductBuildCommitments([]*big.Int{}, big.NewInt(p), big.NewInt(q), &LegacySecurityParams)
 	challengeBefore := common.HashCommit(listBefore)
-	proofBefore := quasiSafePrimeProductBuildProof(big.NewInt(p), big.NewInt(q), challengeBefore, commit)
+	proofBefore := quasiSafePrimeProductBuildProof(big.NewInt(p), big.NewInt(q), challengeBefore, commit, &LegacySecurityParams)
 	proofJSON, err := json.Marshal(proofBefore)
 	if err != nil {
 		t.Error(err.Error())
@@ -50,7 +50,7 @@ func TestQuasiSafePrimeProductFullCycle(t *testing.T) {
 	}
 	listAfter := quasiSafePrimeProductExtractCommitments([]*big.Int{}, proofAfter)
 	challengeAfter := common.HashCommit(listAfter)
-	ok := quasiSafePrimeProductVerifyProof(big.NewInt((2*p+1)*(2*q+1)), challengeAfter, proofAfter)
+	ok := quasiSafePrimeProductVerifyProof(big.NewInt((2*p+1)*(2*q+1)), challengeAfter, proofAfter, &LegacySecurityParams)
 	if !ok {
 		t.Error("JSON proof rejected")
 	}
@@ -59,38 +59,96 @@ func TestQuasiSafePrimeProductFullCycle(t *testing.T) {
 func TestQuasiSafePrimeProductVerifyStructure(t *testing.T) {
 	const p = 13451
 	const q = 13901
-	_, commit := quasiSafePrimeProductBuildCommitments([]*big.Int{}, big.NewInt(p), big.NewInt(q))
-	proof := quasiSafePrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), commit)
+	_, commit := quasiSafePrimeProductBuildCommitments([]*big.Int{}, big.NewInt(p), big.NewInt(q), &LegacySecurityParams)
+	proof := quasiSafePrimeProductBuildProof(big.NewInt(p), big.NewInt(q), big.NewInt(12345), commit, &LegacySecurityParams)
 
 	valBackup := proof.SFproof.Responses[2]
 	proof.SFproof.Responses[2] = nil
-	if quasiSafePrimeProductVerifyStructure(proof) {
+	if quasiSafePrimeProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Accepting corrupted sfproof")
 	}
 	proof.SFproof.Responses[2] = valBackup
 
 	valBackup = proof.PPPproof.Responses[2]
 	proof.PPPproof.Responses[2] = nil
-	if quasiSafePrimeProductVerifyStructure(proof) {
+	if quasiSafePrimeProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Accepting corrupted pppproof")
 	}
 	proof.PPPproof.Responses[2] = valBackup
 
 	valBackup = proof.DPPproof.Responses[2]
 	proof.DPPproof.Responses[2] = nil
-	if quasiSafePrimeProductVerifyStructure(proof) {
+	if quasiSafePrimeProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Accepting corrupted dppproof")
 	}
 	proof.DPPproof.Responses[2] = valBackup
 
 	valBackup = proof.ASPPproof.Responses[2]
 	proof.ASPPproof.Responses[2] = nil
-	if quasiSafePrimeProductVerifyStructure(proof) {
+	if quasiSafePrimeProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Accepting corrupted asppproof")
 	}
 	proof.ASPPproof.Responses[2] = valBackup
 
-	if !quasiSafePrimeProductVerifyStructure(proof) {
+	if !quasiSafePrimeProductVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("testcase corrupted testdata")
 	}
 }
+
+func TestStandaloneQuasiSafePrimeProduct(t *testing.T) {
+	const p = 13451
+	const q = 13901
+	s := NewQuasiSafePrimeProductProofStructure(big.NewInt((2*p+1)*(2*q+1)), nil)
+	proofBefore := s.BuildProof(big.NewInt(p), big.NewInt(q))
+	proofJSON, err := json.Marshal(proofBefore)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	var proof StandaloneQuasiSafePrimeProductProof
+	err = json.Unmarshal(proofJSON, &proof)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if !s.VerifyProof(proof) {
+		t.Error("Standalone proof rejected")
+	}
+
+	// Proof for a different modulus
+	other := NewQuasiSafePrimeProductProofStructure(big.NewInt((2*p+1)*(2*q+1)*7), nil)
+	if other.VerifyProof(proof) {
+		t.Error("Standalone proof accepted for different modulus")
+	}
+
+	// Proof with other security parameters
+	params, _ := GetSecurityParams("128-bit")
+	strong := NewQuasiSafePrimeProductProofStructure(big.NewInt((2*p+1)*(2*q+1)), params)
+	if strong.VerifyProof(proof) {
+		t.Error("Standalone proof accepted with different security parameters")
+	}
+
+	proof.Challenge = new(big.Int).Add(proof.Challenge, big.NewInt(1))
+	if s.VerifyProof(proof) {
+		t.Error("Standalone proof with incorrect challenge accepted")
+	}
+	proof.Challenge = nil
+	if s.VerifyProof(proof) {
+		t.Error("Standalone proof without challenge accepted")
+	}
+}
+
+func TestQuasiSafePrimeProductMinimumFactor(t *testing.T) {
+	// Both factors are below 2^16
+	const p = 13451
+	const q = 13901
+	for _, params := range []*SecurityParams{&LegacySecurityParams, &Strong128LargeFactorSecurityParams} {
+		s := NewQuasiSafePrimeProductProofStructure(big.NewInt((2*p+1)*(2*q+1)), params)
+		proof := s.BuildProof(big.NewInt(p), big.NewInt(q))
+		ok := s.VerifyProof(proof)
+		if ok != (params.MinimumFactor <= 2*p+1) {
+			t.Errorf("Incorrect result %v for minimum factor %v", ok, params.MinimumFactor)
+		}
+	}
+}
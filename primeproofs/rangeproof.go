@@ -3,19 +3,52 @@ package primeproofs
 import "github.com/privacybydesign/keyproof/common"
 import "github.com/privacybydesign/gabi/big"
 
+// Binary challenge range proof with statistical slack. This is the cheap
+// option; see bitRangeProofStructure for exact bounds. When the security
+// parameters ask for large challenge range proofs, a single iteration with
+// the full challenge is used instead (see largerangeproof.go). Batched range
+// proofs contain the commitments of the iterations, so the verifier can check
+// them all at once (see rangeproofbatch.go). Exact range proofs replace range
+// proofs on pederson commitments with a bit range proof, either for a single
+// structure or for all of them through the security parameters.
 type rangeProofStructure struct {
 	representationProofStructure
 	rangeSecret string
 	l1          uint
 	l2          uint
+	params      *SecurityParams
+
+	// Only used by exact range proofs
+	exact *bitRangeProofStructure
 }
 
 type RangeProof struct {
 	Results map[string][]*big.Int
+
+	// Only used by large challenge range proofs
+	IntegerCommit      *big.Int `json:",omitempty"`
+	IntegerHiderResult *big.Int `json:",omitempty"`
+
+	// Only used by batched range proofs
+	Commitments []*big.Int `json:",omitempty"`
+
+	// Only used by exact range proofs
+	Bits *BitRangeProof `json:",omitempty"`
 }
 
 type rangeCommit struct {
 	commits map[string][]*big.Int
+
+	// Only used by large challenge range proofs
+	integerCommit          *big.Int
+	integerHider           *big.Int
+	integerHiderRandomizer *big.Int
+
+	// Only used by batched range proofs
+	iterCommits []*big.Int
+
+	// Only used by exact range proofs
+	bitCommit bitRangeCommit
 }
 
 type rangeCommitSecretLookup struct {
@@ -35,15 +68,45 @@ func (r *rangeCommitSecretLookup) getRandomizer(name string) *big.Int {
 	return clist[r.i]
 }
 
+// Use a bit range proof showing exactly |x - 2^l1| < 2^l2, which the binary
+// challenge iterations only show up to slack
+func (s *rangeProofStructure) makeExact() {
+	center := new(big.Int).Lsh(big.NewInt(1), s.l1)
+	radius := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), s.l2), big.NewInt(1))
+	exact := newPedersonBitRangeProofStructure(s.rangeSecret, new(big.Int).Sub(center, radius), new(big.Int).Add(center, radius))
+	s.exact = &exact
+}
+
 func (s *rangeProofStructure) numRangeProofs() int {
 	return 1
 }
 
 func (s *rangeProofStructure) numCommitments() int {
-	return rangeProofIters
+	if s.exact != nil {
+		return s.exact.numCommitments()
+	}
+	return s.params.rangeProofCommitments()
+}
+
+func (s *rangeProofStructure) collectNames(t *nameTracker) {
+	if s.exact != nil {
+		s.exact.collectNames(t)
+		return
+	}
+	s.representationProofStructure.collectNames(t)
+	t.useSecret(s.rangeSecret)
 }
 
 func (s *rangeProofStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) ([]*big.Int, rangeCommit) {
+	if s.exact != nil {
+		var commit rangeCommit
+		list, commit.bitCommit = s.exact.generateCommitmentsFromSecrets(g, list, bases, secretdata)
+		return list, commit
+	}
+	if s.params.LargeChallengeRangeProofs {
+		return s.generateLargeCommitmentsFromSecrets(g, list, bases, secretdata)
+	}
+
 	var commit rangeCommitSecretLookup
 
 	// Build up commit datastructure
@@ -53,11 +116,11 @@ func (s *rangeProofStructure) generateCommitmentsFromSecrets(g group, list []*bi
 	}
 
 	// Some constants for commitment generation
-	genLimit := new(big.Int).Lsh(big.NewInt(1), s.l2+rangeProofEpsilon+1)
-	genOffset := new(big.Int).Lsh(big.NewInt(1), s.l2+rangeProofEpsilon)
+	genLimit := new(big.Int).Lsh(big.NewInt(1), s.l2+s.params.RangeProofEpsilon+1)
+	genOffset := new(big.Int).Lsh(big.NewInt(1), s.l2+s.params.RangeProofEpsilon)
 
 	// Build up the range proof randomizers
-	for i := 0; i < rangeProofIters; i++ {
+	for i := 0; i < s.params.RangeProofIters; i++ {
 		for name, clist := range commit.commits {
 			var rval *big.Int
 			if name == s.rangeSecret {
@@ -72,9 +135,12 @@ func (s *rangeProofStructure) generateCommitmentsFromSecrets(g group, list []*bi
 
 	// Construct the commitments
 	secretMerge := newSecretMerge(&commit, secretdata)
-	for i := 0; i < rangeProofIters; i++ {
+	for i := 0; i < s.params.RangeProofIters; i++ {
 		commit.i = i
 		list = s.representationProofStructure.generateCommitmentsFromSecrets(g, list, bases, &secretMerge)
+		if s.params.BatchedRangeProofs {
+			commit.iterCommits = append(commit.iterCommits, list[len(list)-1])
+		}
 	}
 
 	// Call the logger
@@ -85,16 +151,24 @@ func (s *rangeProofStructure) generateCommitmentsFromSecrets(g group, list []*bi
 }
 
 func (s *rangeProofStructure) buildProof(g group, challenge *big.Int, commit rangeCommit, secretdata secretLookup) RangeProof {
+	if s.exact != nil {
+		proof := s.exact.buildProof(g, challenge, commit.bitCommit, secretdata)
+		return RangeProof{Bits: &proof}
+	}
+	if s.params.LargeChallengeRangeProofs {
+		return s.buildLargeProof(g, challenge, commit, secretdata)
+	}
+
 	// For every value, build up results, handling the secret data seperately
-	proof := RangeProof{map[string][]*big.Int{}}
+	proof := RangeProof{Results: map[string][]*big.Int{}}
 	for name, clist := range commit.commits {
 
 		rlist := []*big.Int{}
 		if name == s.rangeSecret {
 			// special treatment for range secret
-			resultOffset := new(big.Int).Lsh(big.NewInt(1), s.l2+rangeProofEpsilon+1)
+			resultOffset := new(big.Int).Lsh(big.NewInt(1), s.l2+s.params.RangeProofEpsilon+1)
 			l1Offset := new(big.Int).Lsh(big.NewInt(1), s.l1)
-			for i := 0; i < rangeProofIters; i++ {
+			for i := 0; i < s.params.RangeProofIters; i++ {
 				var res *big.Int
 				if challenge.Bit(i) == 1 {
 					res = new(big.Int).Sub(new(big.Int).Add(clist[i], l1Offset), secretdata.getSecret(name))
@@ -105,7 +179,7 @@ func (s *rangeProofStructure) buildProof(g group, challenge *big.Int, commit ran
 				rlist = append(rlist, res)
 			}
 		} else {
-			for i := 0; i < rangeProofIters; i++ {
+			for i := 0; i < s.params.RangeProofIters; i++ {
 				var res *big.Int
 				if challenge.Bit(i) == 1 {
 					res = new(big.Int).Mod(new(big.Int).Sub(clist[i], secretdata.getSecret(name)), g.order)
@@ -118,34 +192,58 @@ func (s *rangeProofStructure) buildProof(g group, challenge *big.Int, commit ran
 		proof.Results[name] = rlist
 	}
 
+	if s.params.BatchedRangeProofs {
+		proof.Commitments = commit.iterCommits
+	}
+
 	return proof
 }
 
 func (s *rangeProofStructure) fakeProof(g group) RangeProof {
+	if s.exact != nil {
+		proof := s.exact.fakeProof(g)
+		return RangeProof{Bits: &proof}
+	}
+	if s.params.LargeChallengeRangeProofs {
+		return s.fakeLargeProof(g)
+	}
+
 	// Some setup
-	genLimit := new(big.Int).Lsh(big.NewInt(1), s.l2+rangeProofEpsilon+1)
+	genLimit := new(big.Int).Lsh(big.NewInt(1), s.l2+s.params.RangeProofEpsilon+1)
 
-	proof := RangeProof{map[string][]*big.Int{}}
+	proof := RangeProof{Results: map[string][]*big.Int{}}
 	for _, curRhs := range s.rhs {
 		if curRhs.secret == s.rangeSecret {
 			rlist := []*big.Int{}
-			for i := 0; i < rangeProofIters; i++ {
+			for i := 0; i < s.params.RangeProofIters; i++ {
 				rlist = append(rlist, common.RandomBigInt(genLimit))
 			}
 			proof.Results[curRhs.secret] = rlist
 		} else {
 			rlist := []*big.Int{}
-			for i := 0; i < rangeProofIters; i++ {
+			for i := 0; i < s.params.RangeProofIters; i++ {
 				rlist = append(rlist, common.RandomBigInt(g.order))
 			}
 			proof.Results[curRhs.secret] = rlist
 		}
 	}
 
+	// Filled in by generateCommitmentsFromProof
+	if s.params.BatchedRangeProofs {
+		proof.Commitments = make([]*big.Int, s.params.RangeProofIters)
+	}
+
 	return proof
 }
 
 func (s *rangeProofStructure) verifyProofStructure(proof RangeProof) bool {
+	if s.exact != nil {
+		return proof.Bits != nil && s.exact.verifyProofStructure(*proof.Bits)
+	}
+	if s.params.LargeChallengeRangeProofs {
+		return s.verifyLargeProofStructure(proof)
+	}
+
 	// Validate presence of map
 	if proof.Results == nil {
 		return false
@@ -157,7 +255,7 @@ func (s *rangeProofStructure) verifyProofStructure(proof RangeProof) bool {
 		if !ok {
 			return false
 		}
-		if len(rlist) != rangeProofIters {
+		if len(rlist) != s.params.RangeProofIters {
 			return false
 		}
 		for _, val := range rlist {
@@ -167,8 +265,19 @@ func (s *rangeProofStructure) verifyProofStructure(proof RangeProof) bool {
 		}
 	}
 
+	if s.params.BatchedRangeProofs {
+		if len(proof.Commitments) != s.params.RangeProofIters {
+			return false
+		}
+		for _, commitment := range proof.Commitments {
+			if commitment == nil {
+				return false
+			}
+		}
+	}
+
 	// Validate size of secret results
-	rangeLimit := new(big.Int).Lsh(big.NewInt(1), s.l2+rangeProofEpsilon+2)
+	rangeLimit := new(big.Int).Lsh(big.NewInt(1), s.l2+s.params.RangeProofEpsilon+2)
 	for _, val := range proof.Results[s.rangeSecret] {
 		if val.Cmp(rangeLimit) >= 0 {
 			return false
@@ -191,12 +300,23 @@ func (r *rangeProofResultLookup) getResult(name string) *big.Int {
 }
 
 func (s *rangeProofStructure) generateCommitmentsFromProof(g group, list []*big.Int, challenge *big.Int, bases baseLookup, proof RangeProof) []*big.Int {
+	if s.exact != nil {
+		return s.exact.generateCommitmentsFromProof(g, list, challenge, bases, *proof.Bits)
+	}
+	if s.params.LargeChallengeRangeProofs {
+		return s.generateLargeCommitmentsFromProof(g, list, challenge, bases, proof)
+	}
+
+	if s.params.BatchedRangeProofs && g.batch != nil {
+		return s.generateBatchedCommitmentsFromProof(g, list, challenge, bases, proof)
+	}
+
 	// Some values needed in all iterations
-	resultOffset := new(big.Int).Lsh(big.NewInt(1), s.l2+rangeProofEpsilon+1)
+	resultOffset := new(big.Int).Lsh(big.NewInt(1), s.l2+s.params.RangeProofEpsilon+1)
 	l1Offset := new(big.Int).Lsh(big.NewInt(1), s.l1)
 
 	// Iterate over all indices
-	for i := 0; i < rangeProofIters; i++ {
+	for i := 0; i < s.params.RangeProofIters; i++ {
 		// Build resultLookup
 		resultLookup := rangeProofResultLookup{map[string]*big.Int{}}
 		for name, rlist := range proof.Results {
@@ -214,9 +334,64 @@ func (s *rangeProofStructure) generateCommitmentsFromProof(g group, list []*big.
 
 		// And generate commitment
 		list = s.representationProofStructure.generateCommitmentsFromProof(g, list, big.NewInt(int64(challenge.Bit(i))), bases, &resultLookup)
+
+		// Without a batch, as when simulating a proof, batched range proofs
+		// get the recomputed commitments
+		if s.params.BatchedRangeProofs && len(proof.Commitments) == s.params.RangeProofIters {
+			proof.Commitments[i] = list[len(list)-1]
+		}
 	}
 
 	Follower.Tick()
 
 	return list
 }
+
+// Add the equations of all iterations to the batch of the group, and use the
+// commitments from the proof
+func (s *rangeProofStructure) generateBatchedCommitmentsFromProof(g group, list []*big.Int, challenge *big.Int, bases baseLookup, proof RangeProof) []*big.Int {
+	resultOffset := new(big.Int).Lsh(big.NewInt(1), s.l2+s.params.RangeProofEpsilon+1)
+	l1Offset := new(big.Int).Lsh(big.NewInt(1), s.l1)
+
+	// Total randomizer of the iterations with challenge bit 1, for the lhs
+	lhsRandomizer := big.NewInt(0)
+	// Combined exponents of the rhs bases, in order of s.rhs
+	rhsExps := make([]*big.Int, len(s.rhs))
+	for j := range rhsExps {
+		rhsExps[j] = big.NewInt(0)
+	}
+
+	var exp big.Int
+	for i := 0; i < s.params.RangeProofIters; i++ {
+		randomizer := g.batch.randomizer()
+		g.batch.addCommitment(&g, proof.Commitments[i], randomizer)
+		if challenge.Bit(i) == 1 {
+			lhsRandomizer.Add(lhsRandomizer, randomizer)
+		}
+
+		for j, curRhs := range s.rhs {
+			res := proof.Results[curRhs.secret][i]
+			if curRhs.secret == s.rangeSecret {
+				res = new(big.Int).Sub(res, resultOffset)
+				if challenge.Bit(i) == 1 {
+					res.Sub(res, l1Offset)
+				}
+			}
+			exp.Mul(big.NewInt(curRhs.power), res)
+			g.orderMod.Mod(&exp, &exp)
+			exp.Mul(&exp, randomizer)
+			rhsExps[j].Add(rhsExps[j], &exp)
+		}
+	}
+
+	for _, curLhs := range s.lhs {
+		g.batch.addTerm(&g, bases, curLhs.base, new(big.Int).Mul(curLhs.power, lhsRandomizer))
+	}
+	for j, curRhs := range s.rhs {
+		g.batch.addTerm(&g, bases, curRhs.base, rhsExps[j])
+	}
+
+	Follower.Tick()
+
+	return append(list, proof.Commitments...)
+}
@@ -82,6 +82,7 @@ func TestRangeProofBasic(t *testing.T) {
 	s.rangeSecret = "x"
 	s.l1 = 3
 	s.l2 = 2
+	s.params = &LegacySecurityParams
 
 	var secret RangeTestSecret
 	secret.secrets = map[string]*big.Int{
@@ -148,6 +149,7 @@ func TestRangeProofComplex(t *testing.T) {
 	}
 	s.l1 = 3
 	s.l2 = 2
+	s.params = &LegacySecurityParams
 
 	var secret RangeTestSecret
 	secret.secrets = map[string]*big.Int{
@@ -212,6 +214,7 @@ func TestRangeProofVerifyStructureEmpty(t *testing.T) {
 	}
 	s.l1 = 3
 	s.l2 = 2
+	s.params = &LegacySecurityParams
 
 	if s.verifyProofStructure(proof) {
 		t.Error("Accepting empty proof")
@@ -230,6 +233,7 @@ func TestRangeProofVerifyStructureMissingVar(t *testing.T) {
 	}
 	s.l1 = 3
 	s.l2 = 2
+	s.params = &LegacySecurityParams
 
 	tlist := []*big.Int{}
 	for i := 0; i < rangeProofIters; i++ {
@@ -257,6 +261,7 @@ func TestRangeProofVerifyStructureTooShortVar(t *testing.T) {
 	}
 	s.l1 = 3
 	s.l2 = 2
+	s.params = &LegacySecurityParams
 
 	tlist := []*big.Int{}
 	for i := 0; i < rangeProofIters; i++ {
@@ -293,6 +298,7 @@ func TestRangeProofVerifyStructureMissingNo(t *testing.T) {
 	}
 	s.l1 = 3
 	s.l2 = 2
+	s.params = &LegacySecurityParams
 
 	tlist := []*big.Int{}
 	for i := 0; i < rangeProofIters; i++ {
@@ -333,6 +339,7 @@ func TestRangeProofFake(t *testing.T) {
 	}
 	s.l1 = 3
 	s.l2 = 2
+	s.params = &LegacySecurityParams
 
 	proof := s.fakeProof(g)
 	if !s.verifyProofStructure(proof) {
@@ -357,6 +364,7 @@ func TestRangeProofJSON(t *testing.T) {
 	}
 	s.l1 = 3
 	s.l2 = 2
+	s.params = &LegacySecurityParams
 
 	proofBefore := s.fakeProof(g)
 	proofJSON, err := json.Marshal(proofBefore)
@@ -376,3 +384,112 @@ func TestRangeProofJSON(t *testing.T) {
 		t.Error("json'ed proof structure rejected")
 	}
 }
+
+func TestRangeProofExact(t *testing.T) {
+	g, gok := buildGroup(big.NewInt(26903))
+	if !gok {
+		t.Error("Failed to setup group for Range proof testing")
+		return
+	}
+
+	Follower.(*TestFollower).count = 0
+
+	s := newPedersonExactRangeProofStructure("x", 0, 5, &LegacySecurityParams)
+	if s.exact == nil {
+		t.Error("Exact range proof not selected")
+		return
+	}
+	if report := validateStructure(&s, "x"); !report.Ok() {
+		t.Errorf("Exact range proof structure rejected: %v", report)
+	}
+
+	xCommit := newPedersonSecret(g, "x", big.NewInt(17))
+	bases := newBaseMerge(&g, &xCommit)
+
+	listSecrets, commit := s.generateCommitmentsFromSecrets(g, []*big.Int{}, &bases, &xCommit)
+	if len(listSecrets) != s.numCommitments() {
+		t.Error("NumCommitments is off")
+	}
+	if Follower.(*TestFollower).count != s.numRangeProofs() {
+		t.Error("Logging is off GenerateCommitmentsFromSecrets")
+	}
+	Follower.(*TestFollower).count = 0
+
+	proofBefore := s.buildProof(g, big.NewInt(12345), commit, &xCommit)
+	proofJSON, err := json.Marshal(proofBefore)
+	if err != nil {
+		t.Errorf("error during json marshal: %s", err.Error())
+		return
+	}
+	var proof RangeProof
+	err = json.Unmarshal(proofJSON, &proof)
+	if err != nil {
+		t.Errorf("error during json unmarshal: %s", err.Error())
+		return
+	}
+
+	xProof := xCommit.buildProof(g, big.NewInt(12345))
+	xProof.setName("x")
+	basesProof := newBaseMerge(&g, &xProof)
+
+	if !s.verifyProofStructure(proof) {
+		t.Error("Proof structure rejected")
+		return
+	}
+	listProof := s.generateCommitmentsFromProof(g, []*big.Int{}, big.NewInt(12345), &basesProof, proof)
+	if Follower.(*TestFollower).count != s.numRangeProofs() {
+		t.Error("Logging is off on GenerateCommitmentsFromProof")
+	}
+	if !listCmp(listSecrets, listProof) {
+		t.Error("Commitment lists differ")
+	}
+
+	// Exact and slack range proofs are not interchangeable
+	slack := newPedersonRangeProofStructure("x", 0, 5, &LegacySecurityParams)
+	if s.verifyProofStructure(slack.fakeProof(g)) {
+		t.Error("Slack range proof accepted as exact one")
+	}
+	if slack.verifyProofStructure(proof) {
+		t.Error("Exact range proof accepted as slack one")
+	}
+}
+
+func TestRangeProofExactOutOfRange(t *testing.T) {
+	g, gok := buildGroup(big.NewInt(26903))
+	if !gok {
+		t.Error("Failed to setup group for Range proof testing")
+		return
+	}
+
+	params := LegacySecurityParams
+	params.ExactRangeProofs = true
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Generating exact proof for value outside of range did not fail")
+		}
+	}()
+
+	// The slack range proof would still accept 33 for l1 = 0 and l2 = 5
+	s := newPedersonRangeProofStructure("x", 0, 5, &params)
+	xCommit := newPedersonSecret(g, "x", big.NewInt(33))
+	bases := newBaseMerge(&g, &xCommit)
+	s.generateCommitmentsFromSecrets(g, []*big.Int{}, &bases, &xCommit)
+}
+
+func TestRangeProofExactBounds(t *testing.T) {
+	// |x - 2^l1| < 2^l2, as for the slack range proof
+	s := newPedersonExactRangeProofStructure("x", 4, 2, &LegacySecurityParams)
+	if s.exact.l1.Cmp(big.NewInt(13)) != 0 || s.exact.l2.Cmp(big.NewInt(19)) != 0 {
+		t.Errorf("Incorrect exact bounds [%v, %v]", s.exact.l1, s.exact.l2)
+	}
+
+	s = newPedersonExactRangeProofStructure("x", 0, 5, &LegacySecurityParams)
+	if s.exact.l1.Cmp(big.NewInt(-30)) != 0 || s.exact.l2.Cmp(big.NewInt(32)) != 0 {
+		t.Errorf("Incorrect exact bounds [%v, %v]", s.exact.l1, s.exact.l2)
+	}
+
+	if newPedersonRangeProofStructure("x", 0, 5, &LegacySecurityParams).exact != nil {
+		t.Error("Exact range proof used without asking for it")
+	}
+}
@@ -0,0 +1,127 @@
+package primeproofs
+
+import (
+	"sync"
+
+	"github.com/privacybydesign/gabi/big"
+	"github.com/privacybydesign/keyproof/common"
+)
+
+// Batch verification of range proofs
+//
+// With BatchedRangeProofs, range proofs contain the commitments A_i of all
+// their iterations, which the verifier puts into the challenge directly
+// instead of recomputing them. The equations of the iterations
+//
+//	A_i = L^c_i * prod_j B_j^e_ij
+//
+// are then checked at once, for all range proofs of a proof together, with
+// the random linear combination
+//
+//	prod_i A_i^r_i = prod L^(sum_i r_i c_i) * prod_j B_j^(sum_i r_i e_ij)
+//
+// for random r_i of RangeProofBatchBits bits. When some equation does not
+// hold, this passes with probability at most 2^-RangeProofBatchBits. That
+// argument needs all elements in the prime order subgroup, so they are
+// checked to be quadratic residues, which is cheap compared to x^q = 1.
+//
+// Without a batch, batched range proofs are checked iteration by iteration as
+// usual, which is also how the prover computes the commitments of simulated
+// proofs.
+
+type rangeProofBatch struct {
+	// Range proofs can be verified concurrently
+	lock sync.Mutex
+
+	bits uint
+	ok   bool
+
+	// Bases and exponents on both sides of the combined equation
+	lhsBases []*big.Int
+	lhsExps  []*big.Int
+	rhsBases []*big.Int
+	rhsExps  []*big.Int
+
+	// Exponents of the generators, which use their tables
+	gExp big.Int
+	hExp big.Int
+}
+
+func newRangeProofBatch(bits uint) *rangeProofBatch {
+	return &rangeProofBatch{bits: bits, ok: true}
+}
+
+func (b *rangeProofBatch) randomizer() *big.Int {
+	return common.RandomBigInt(new(big.Int).Lsh(big.NewInt(1), b.bits))
+}
+
+// Whether x is a quadratic residue modulo p, so in the order q subgroup
+func (b *rangeProofBatch) checkElement(g *group, x *big.Int) bool {
+	if x == nil || x.Sign() <= 0 || x.Cmp(g.p) >= 0 || big.Jacobi(x, g.p) != 1 {
+		b.ok = false
+		return false
+	}
+	return true
+}
+
+// Add the commitment A with its randomizer to the left hand side
+func (b *rangeProofBatch) addCommitment(g *group, commitment *big.Int, randomizer *big.Int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if !b.checkElement(g, commitment) {
+		return
+	}
+	b.lhsBases = append(b.lhsBases, commitment)
+	b.lhsExps = append(b.lhsExps, randomizer)
+}
+
+// Add the named base to the power exp to the right hand side
+func (b *rangeProofBatch) addTerm(g *group, bases baseLookup, name string, exp *big.Int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if name == "g" {
+		b.gExp.Add(&b.gExp, exp)
+		return
+	}
+	if name == "h" {
+		b.hExp.Add(&b.hExp, exp)
+		return
+	}
+
+	base := bases.getBase(name)
+	if !b.checkElement(g, base) {
+		return
+	}
+	if exp.Sign() < 0 {
+		// Move to the other side
+		b.lhsBases = append(b.lhsBases, base)
+		b.lhsExps = append(b.lhsExps, new(big.Int).Neg(exp))
+		return
+	}
+	b.rhsBases = append(b.rhsBases, base)
+	b.rhsExps = append(b.rhsExps, exp)
+}
+
+// Check the combined equation of everything added to the batch
+func (b *rangeProofBatch) verify(g *group) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if !b.ok {
+		return false
+	}
+
+	var lhs, rhs, gPart, hPart, exp, tmp big.Int
+	common.MultiExp(&lhs, b.lhsBases, b.lhsExps, &g.pMod)
+	common.MultiExp(&rhs, b.rhsBases, b.rhsExps, &g.pMod)
+
+	exp.Mod(&b.gExp, g.order)
+	g.exp(&gPart, "g", &exp, g.p)
+	exp.Mod(&b.hExp, g.order)
+	g.exp(&hPart, "h", &exp, g.p)
+	tmp.Mul(&rhs, &gPart)
+	g.pMod.Mod(&rhs, &tmp)
+	tmp.Mul(&rhs, &hPart)
+	g.pMod.Mod(&rhs, &tmp)
+
+	return lhs.Cmp(&rhs) == 0
+}
@@ -0,0 +1,178 @@
+package primeproofs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/privacybydesign/gabi/big"
+)
+
+var batchTestParams = SecurityParams{
+	Name:                "batch-test",
+	RangeProofIters:     16,
+	RangeProofEpsilon:   rangeProofEpsilon,
+	BatchedRangeProofs:  true,
+	RangeProofBatchBits: 64,
+}
+
+func setupBatchedRangeProof(t *testing.T) (group, rangeProofStructure, baseMerge, RangeProof, []*big.Int) {
+	g, gok := buildGroup(big.NewInt(26903))
+	if !gok {
+		t.Fatal("Failed to setup group for batched range proof testing")
+	}
+
+	var s rangeProofStructure
+	s.lhs = []lhsContribution{
+		lhsContribution{"c", big.NewInt(1)},
+	}
+	s.rhs = []rhsContribution{
+		rhsContribution{"g", "x", 1},
+		rhsContribution{"h", "xh", 1},
+	}
+	s.rangeSecret = "x"
+	s.l1 = 3
+	s.l2 = 2
+	s.params = &batchTestParams
+
+	var secret RangeTestSecret
+	secret.secrets = map[string]*big.Int{
+		"x":  big.NewInt(7),
+		"xh": big.NewInt(21),
+	}
+	secret.randomizers = map[string]*big.Int{}
+
+	var commit RangeTestCommit
+	commit.commits = map[string]*big.Int{
+		"c": new(big.Int).Mod(
+			new(big.Int).Mul(
+				new(big.Int).Exp(g.g, big.NewInt(7), g.p),
+				new(big.Int).Exp(g.h, big.NewInt(21), g.p)),
+			g.p),
+	}
+	bases := newBaseMerge(&g, &commit)
+
+	listSecret, rpcommit := s.generateCommitmentsFromSecrets(g, []*big.Int{}, &bases, &secret)
+	proof := s.buildProof(g, big.NewInt(12345), rpcommit, &secret)
+	return g, s, bases, proof, listSecret
+}
+
+func TestRangeProofBatched(t *testing.T) {
+	g, s, bases, proof, listSecret := setupBatchedRangeProof(t)
+
+	if len(proof.Commitments) != s.params.RangeProofIters {
+		t.Error("Commitments missing from batched proof")
+	}
+	if !s.verifyProofStructure(proof) {
+		t.Error("Proof structure rejected")
+		return
+	}
+
+	g.batch = newRangeProofBatch(s.params.RangeProofBatchBits)
+	listProof := s.generateCommitmentsFromProof(g, []*big.Int{}, big.NewInt(12345), &bases, proof)
+	if !listCmp(listSecret, listProof) {
+		t.Error("Commitment lists disagree")
+	}
+	if !g.batch.verify(&g) {
+		t.Error("Batch rejected")
+	}
+}
+
+func TestRangeProofBatchedIncorrectResult(t *testing.T) {
+	g, s, bases, proof, _ := setupBatchedRangeProof(t)
+
+	proof.Results["xh"][3] = new(big.Int).Mod(new(big.Int).Add(proof.Results["xh"][3], big.NewInt(1)), g.order)
+	if !s.verifyProofStructure(proof) {
+		t.Error("Proof structure rejected")
+		return
+	}
+
+	g.batch = newRangeProofBatch(s.params.RangeProofBatchBits)
+	s.generateCommitmentsFromProof(g, []*big.Int{}, big.NewInt(12345), &bases, proof)
+	if g.batch.verify(&g) {
+		t.Error("Batch with incorrect result accepted")
+	}
+}
+
+func TestRangeProofBatchedNonResidue(t *testing.T) {
+	g, s, bases, proof, _ := setupBatchedRangeProof(t)
+
+	// -A is not in the order q subgroup
+	proof.Commitments[0] = new(big.Int).Sub(g.p, proof.Commitments[0])
+
+	g.batch = newRangeProofBatch(s.params.RangeProofBatchBits)
+	s.generateCommitmentsFromProof(g, []*big.Int{}, big.NewInt(12345), &bases, proof)
+	if g.batch.verify(&g) {
+		t.Error("Batch with commitment outside subgroup accepted")
+	}
+}
+
+func TestRangeProofBatchedStructure(t *testing.T) {
+	_, s, _, proof, _ := setupBatchedRangeProof(t)
+
+	commitments := proof.Commitments
+	proof.Commitments = commitments[1:]
+	if s.verifyProofStructure(proof) {
+		t.Error("Accepted proof with missing commitments")
+	}
+	proof.Commitments = append([]*big.Int{nil}, commitments[1:]...)
+	if s.verifyProofStructure(proof) {
+		t.Error("Accepted proof with nil commitment")
+	}
+}
+
+func TestRangeProofBatchedFake(t *testing.T) {
+	g, s, bases, _, _ := setupBatchedRangeProof(t)
+
+	// The commitments of simulated proofs are filled in when computing them
+	proof := s.fakeProof(g)
+	list := s.generateCommitmentsFromProof(g, []*big.Int{}, big.NewInt(12345), &bases, proof)
+	if !s.verifyProofStructure(proof) {
+		t.Error("Fake proof structure rejected")
+	}
+	if !listCmp(list, proof.Commitments) {
+		t.Error("Commitments of fake proof not filled in")
+	}
+
+	g.batch = newRangeProofBatch(s.params.RangeProofBatchBits)
+	s.generateCommitmentsFromProof(g, []*big.Int{}, big.NewInt(12345), &bases, proof)
+	if !g.batch.verify(&g) {
+		t.Error("Batch of fake proof rejected")
+	}
+}
+
+func TestValidKeyProofBatched(t *testing.T) {
+	const p = 26903
+	const q = 27803
+
+	s := NewValidKeyProofStructure(big.NewInt(p*q), big.NewInt(36), big.NewInt(49), []*big.Int{big.NewInt(64)}, WithSecurityParams("128-bit-batched"))
+	proofBefore := s.BuildProof(big.NewInt((p-1)/2), big.NewInt((q-1)/2))
+
+	proofJSON, err := json.Marshal(proofBefore)
+	if err != nil {
+		t.Errorf("error during json marshal: %s", err.Error())
+		return
+	}
+	var proof ValidKeyProof
+	if err := json.Unmarshal(proofJSON, &proof); err != nil {
+		t.Errorf("error during json unmarshal: %s", err.Error())
+		return
+	}
+
+	Follower.(*TestFollower).count = 0
+	if !s.VerifyProof(proof) {
+		t.Error("Proof rejected")
+	}
+	if Follower.(*TestFollower).count != s.numRangeProofs() {
+		t.Error("Logging is off on GenerateCommitmentsFromProof")
+	}
+
+	// Change a single result of a single range proof
+	rangeProof := proof.BasesValidProof.RootsRangeProof[0]
+	for _, rlist := range rangeProof.Results {
+		rlist[0] = new(big.Int).Add(rlist[0], big.NewInt(1))
+		break
+	}
+	if s.VerifyProof(proof) {
+		t.Error("Proof with incorrect range proof result accepted")
+	}
+}
@@ -26,6 +26,16 @@ func (s *representationProofStructure) numCommitments() int {
 	return 1
 }
 
+func (s *representationProofStructure) collectNames(t *nameTracker) {
+	for _, curLhs := range s.lhs {
+		t.useBase(curLhs.base)
+	}
+	for _, curRhs := range s.rhs {
+		t.useBase(curRhs.base)
+		t.useSecret(curRhs.secret)
+	}
+}
+
 func (s *representationProofStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, bases baseLookup, secretdata secretLookup) []*big.Int {
 	commitment := big.NewInt(1)
 	var exp, contribution big.Int
@@ -45,27 +55,21 @@ func (s *representationProofStructure) generateCommitmentsFromSecrets(g group, l
 }
 
 func (s *representationProofStructure) generateCommitmentsFromProof(g group, list []*big.Int, challenge *big.Int, bases baseLookup, proofdata proofLookup) []*big.Int {
-	var base, tmp, lhs big.Int
-	lhs.SetUint64(1)
+	// The commitment is lhs^challenge * rhs, computed as one product of powers
+	names := []string{}
+	exps := []*big.Int{}
 	for _, curLhs := range s.lhs {
-		bases.exp(&base, curLhs.base, curLhs.power, g.p)
-		tmp.Mul(&lhs, &base)
-		g.pMod.Mod(&lhs, &tmp)
+		names = append(names, curLhs.base)
+		exps = append(exps, new(big.Int).Mul(curLhs.power, challenge))
 	}
-
-	commitment := new(big.Int).Exp(&lhs, challenge, g.p)
-	var exp, contribution big.Int
 	for _, curRhs := range s.rhs {
-		// base := bases.Exp(curRhs.Base, big.NewInt(curRhs.Power), g.P)
-		// contribution := new(big.Int).Exp(base, proofdata.GetResult(curRhs.Secret), g.P)
-		exp.Mul(big.NewInt(curRhs.power), proofdata.getResult(curRhs.secret))
-		g.orderMod.Mod(&exp, &exp)
-		bases.exp(&contribution, curRhs.base, &exp, g.p)
-		commitment.Mul(commitment, &contribution)
-		g.pMod.Mod(commitment, commitment)
+		exp := new(big.Int).Mul(big.NewInt(curRhs.power), proofdata.getResult(curRhs.secret))
+		g.orderMod.Mod(exp, exp)
+		names = append(names, curRhs.base)
+		exps = append(exps, exp)
 	}
 
-	return append(list, commitment)
+	return append(list, g.expProduct(new(big.Int), bases, names, exps))
 }
 
 func (s *representationProofStructure) isTrue(g group, bases baseLookup, secretdata secretLookup) bool {
@@ -0,0 +1,90 @@
+package primeproofs
+
+import "github.com/privacybydesign/gabi/big"
+
+// Proof that a revocation accumulator key is well formed. The accumulator
+// works in QR_N for a modulus N of two safe primes, with generators G and H.
+// Besides N being a product of safe primes, its security relies on G and H
+// generating QR_N: the key proof shows both are squares, after which
+// gcd(G-1, N) = gcd(H-1, N) = 1 shows their order is P'Q', as in
+// discretelogproof.go.
+//
+// The proof is a key family proof of a single key with G and H in the place
+// of Z and S, separated from those by its own domain.
+
+type RevocationKeyProofStructure struct {
+	family KeyFamilyProofStructure
+	g      *big.Int
+	h      *big.Int
+}
+
+type RevocationKeyProof struct {
+	Challenge  *big.Int
+	GroupPrime *big.Int
+
+	// Determines how the group generators are chosen
+	GroupVersion int
+
+	// Name of the security parameter set used, absent for legacy-80
+	SecurityParams string `json:",omitempty"`
+
+	KeyProof ValidKeyProof
+}
+
+// Domain separating revocation key proofs from key family proofs
+var revocationKeyProofDomain = new(big.Int).SetBytes([]byte("revocation-key"))
+
+func NewRevocationKeyProofStructure(N *big.Int, G *big.Int, H *big.Int, opts ...ValidKeyProofOption) RevocationKeyProofStructure {
+	var structure RevocationKeyProofStructure
+	structure.family = NewKeyFamilyProofStructure(NewValidKeyProofStructure(N, G, H, nil, opts...))
+	structure.family.domain = revocationKeyProofDomain
+	structure.g = new(big.Int).Set(G)
+	structure.h = new(big.Int).Set(H)
+	return structure
+}
+
+// Whether G and H generate QR_N, given that they are squares and N is a
+// product of safe primes
+func (s *RevocationKeyProofStructure) generatorsValid() bool {
+	key := &s.family.keys[0]
+	return generatesQR(s.g, key.n) && generatesQR(s.h, key.n) && s.g.Cmp(s.h) != 0
+}
+
+// Check a convenient safe prime is available as group prime, see
+// FindConvenientSafePrime
+func (s *RevocationKeyProofStructure) CheckGroupPrime() error {
+	return s.family.CheckGroupPrime()
+}
+
+func (s *RevocationKeyProofStructure) BuildProof(Pprime *big.Int, Qprime *big.Int) RevocationKeyProof {
+	if !s.generatorsValid() {
+		panic("G and H do not generate QR_N")
+	}
+
+	proof := s.family.BuildProof([]*big.Int{Pprime}, []*big.Int{Qprime})
+	return RevocationKeyProof{
+		Challenge:      proof.Challenge,
+		GroupPrime:     proof.GroupPrime,
+		GroupVersion:   proof.GroupVersion,
+		SecurityParams: proof.SecurityParams,
+		KeyProof:       proof.Keys[0],
+	}
+}
+
+func (s *RevocationKeyProofStructure) VerifyProof(proof RevocationKeyProof) bool {
+	if !s.generatorsValid() {
+		return false
+	}
+
+	return s.family.VerifyProof(KeyFamilyProof{
+		Challenge:      proof.Challenge,
+		GroupPrime:     proof.GroupPrime,
+		GroupVersion:   proof.GroupVersion,
+		SecurityParams: proof.SecurityParams,
+		Keys:           []ValidKeyProof{proof.KeyProof},
+	})
+}
+
+func (s *RevocationKeyProofStructure) SecurityEstimate() SecurityEstimate {
+	return s.family.keys[0].SecurityEstimate()
+}
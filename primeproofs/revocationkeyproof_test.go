@@ -0,0 +1,69 @@
+package primeproofs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/privacybydesign/gabi/big"
+)
+
+func TestRevocationKeyProof(t *testing.T) {
+	const p = 26903
+	const q = 27803
+
+	s := NewRevocationKeyProofStructure(big.NewInt(p*q), big.NewInt(36), big.NewInt(49), WithSecurityParams("fast-test"))
+	proofBefore := s.BuildProof(big.NewInt((p-1)/2), big.NewInt((q-1)/2))
+
+	proofJSON, err := json.Marshal(proofBefore)
+	if err != nil {
+		t.Errorf("error during json marshal: %s", err.Error())
+		return
+	}
+	var proof RevocationKeyProof
+	err = json.Unmarshal(proofJSON, &proof)
+	if err != nil {
+		t.Errorf("error during json unmarshal: %s", err.Error())
+		return
+	}
+
+	if !s.VerifyProof(proof) {
+		t.Error("Revocation key proof rejected")
+	}
+
+	swapped := NewRevocationKeyProofStructure(big.NewInt(p*q), big.NewInt(49), big.NewInt(36), WithSecurityParams("fast-test"))
+	if swapped.VerifyProof(proof) {
+		t.Error("Revocation key proof accepted with generators swapped")
+	}
+
+	// Not interchangeable with a key family proof of the same key
+	family := NewKeyFamilyProofStructure(NewValidKeyProofStructure(big.NewInt(p*q), big.NewInt(36), big.NewInt(49), nil, WithSecurityParams("fast-test")))
+	if family.VerifyProof(KeyFamilyProof{proof.Challenge, proof.GroupPrime, proof.GroupVersion, proof.SecurityParams, []ValidKeyProof{proof.KeyProof}}) {
+		t.Error("Revocation key proof accepted as key family proof")
+	}
+}
+
+func TestRevocationKeyProofGenerators(t *testing.T) {
+	const p = 26903
+	const q = 27803
+
+	s := NewRevocationKeyProofStructure(big.NewInt(p*q), big.NewInt(36), big.NewInt(49))
+	if !s.generatorsValid() {
+		t.Error("Rejected generators of QR_N")
+	}
+
+	same := NewRevocationKeyProofStructure(big.NewInt(p*q), big.NewInt(36), big.NewInt(36))
+	if same.generatorsValid() {
+		t.Error("Accepted G = H")
+	}
+
+	// Generates only the squares modulo Q
+	s.h = big.NewInt(p + 1)
+	if s.generatorsValid() {
+		t.Error("Accepted H = 1 modulo P")
+	}
+	s.h = big.NewInt(49)
+	s.g = big.NewInt(1)
+	if s.generatorsValid() {
+		t.Error("Accepted G = 1")
+	}
+}
@@ -1,52 +1,175 @@
 package primeproofs
 
-import "github.com/privacybydesign/gabi/big"
-import "github.com/privacybydesign/gabi/safeprime"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/privacybydesign/gabi/big"
+	"github.com/privacybydesign/gabi/safeprime"
+)
 
 // Performance parameter, defines ammount of extra bits allowed when using a convenient safe prime
 const convenientRange = 100
 
 // A convenient safe prime is a safe prime of the form
 // 2^exp - diff for small positive diff.
-type convenientSafePrime struct {
+type ConvenientSafePrime struct {
 	Exp  int
 	Diff int
 }
 
-var convenientSafePrimes = []convenientSafePrime{
+// Built in table, sorted by exponent. Together these cover every size from
+// 1024 up to 4098 bits and from 4583 up to 4742 bits, which includes the group
+// primes for moduli up to 3576 bits, and 4096 bit moduli.
+//
+// Moduli above that, up to 8192 bits, are not covered: a single search for a
+// safe prime of 6000 to 9000 bits can take a day or more on one core, so
+// those sizes fall back to safeprime.Generate, and FindConvenientSafePrime
+// reports them. Tables for them can be made with SearchConvenientSafePrimeTable
+// and loaded with RegisterConvenientSafePrimes.
+var convenientSafePrimes = []ConvenientSafePrime{
 	{787, 7341},
 	{836, 12077},
 	{912, 7577},
 	{933, 6249},
 	{985, 3645},
 	{1008, 3317},
+	{1123, 149649},
+	{1222, 209705},
 	{1259, 2505},
 	{1307, 4425},
+	{1406, 32501},
 	{1503, 1629},
 	{1567, 3309},
+	{1666, 257381},
+	{1765, 522129},
+	{1864, 105293},
+	{1963, 2205165},
 	{2043, 11301},
+	{2142, 13888865},
 	{2145, 429},
+	{2244, 1040213},
+	{2343, 4335081},
+	{2442, 1800137},
+	{2541, 4362213},
 	{2639, 163185},
 	{2659, 91209},
 	{2661, 71745},
 	{2705, 5445},
+	{2804, 1038233},
+	{2903, 3600861},
+	{3002, 7837901},
+	{3101, 2335893},
+	{3200, 21464429},
+	{3299, 6465825},
+	{3398, 21313397},
+	{3497, 1846689},
+	{3596, 651293},
+	{3695, 2156841},
+	{3794, 1592261},
+	{3893, 6531693},
+	{3992, 7155077},
+	{4091, 4353309},
 	{4099, 5025},
 	{4682, 190265},
 	{4743, 268629},
 }
 
-func findConvenientPrime(size int) *big.Int {
-	for _, cp := range convenientSafePrimes {
+// Largest size for which the built in table has a convenient safe prime
+const MaxBuiltinConvenientSafePrimeSize = 4742
+
+// Extra convenient safe primes registered at runtime, sorted by exponent
+var registeredSafePrimes []ConvenientSafePrime
+var registeredSafePrimesLock sync.RWMutex
+
+func (cp ConvenientSafePrime) value() *big.Int {
+	var ret, diff big.Int
+	diff.SetUint64(uint64(cp.Diff))
+	ret.SetUint64(1)
+	ret.Lsh(&ret, uint(cp.Exp))
+	ret.Sub(&ret, &diff)
+	return &ret
+}
+
+// Check the table entry is a safe prime. The prime is validated fully when
+// building a group from it, so a fast probabilistic check suffices here.
+func (cp ConvenientSafePrime) isSafePrime() bool {
+	if cp.Exp < 3 || cp.Diff <= 0 || cp.Diff%4 != 1 {
+		return false
+	}
+	p := cp.value()
+	if p.Sign() <= 0 || !p.ProbablyPrime(1) {
+		return false
+	}
+	return new(big.Int).Rsh(p, 1).ProbablyPrime(1)
+}
+
+func lookupConvenientPrimeIn(table []ConvenientSafePrime, size int) (ConvenientSafePrime, bool) {
+	for _, cp := range table {
 		if int(cp.Exp) > size && int(cp.Exp)-size < convenientRange {
-			var ret, diff big.Int
-			diff.SetUint64(uint64(cp.Diff))
-			ret.SetUint64(1)
-			ret.Lsh(&ret, uint(cp.Exp))
-			ret.Sub(&ret, &diff)
-			return &ret
+			return cp, true
 		}
 	}
-	return nil
+	return ConvenientSafePrime{}, false
+}
+
+func lookupConvenientPrime(size int) (ConvenientSafePrime, bool) {
+	if cp, ok := lookupConvenientPrimeIn(convenientSafePrimes, size); ok {
+		return cp, true
+	}
+
+	registeredSafePrimesLock.RLock()
+	defer registeredSafePrimesLock.RUnlock()
+	return lookupConvenientPrimeIn(registeredSafePrimes, size)
+}
+
+func findConvenientPrime(size int) *big.Int {
+	cp, ok := lookupConvenientPrime(size)
+	if !ok {
+		return nil
+	}
+	return cp.value()
+}
+
+// The convenient safe prime used for groups of the given size, built in or
+// registered. Returns an error when there is none, in which case building a
+// proof generates a safe prime instead, which is very slow for large sizes.
+func FindConvenientSafePrime(size int) (*big.Int, error) {
+	if result := findConvenientPrime(size); result != nil {
+		return result, nil
+	}
+	if size > MaxBuiltinConvenientSafePrimeSize {
+		return nil, fmt.Errorf("no convenient safe prime of %d bits registered, the built in ones reach only %d bits", size, MaxBuiltinConvenientSafePrimeSize)
+	}
+	return nil, fmt.Errorf("no convenient safe prime of %d bits built in or registered", size)
+}
+
+// Check whether prime occurs in one of the convenient safe prime tables
+func isConvenientPrime(prime *big.Int) bool {
+	for _, candidate := range ConvenientSafePrimes() {
+		if candidate.Cmp(prime) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// All convenient safe primes, built in and registered, e.g. to use as the
+// allowed group primes of a VerifierPolicy
+func ConvenientSafePrimes() []*big.Int {
+	registeredSafePrimesLock.RLock()
+	defer registeredSafePrimesLock.RUnlock()
+
+	result := []*big.Int{}
+	for _, cp := range append(append([]ConvenientSafePrime{}, convenientSafePrimes...), registeredSafePrimes...) {
+		result = append(result, cp.value())
+	}
+	return result
 }
 
 func findSafePrime(size int) *big.Int {
@@ -60,3 +183,149 @@ func findSafePrime(size int) *big.Int {
 	}
 	return result
 }
+
+// Register extra convenient safe primes, used when none of the built in
+// ones fits the required size. Returns an error and registers nothing if any
+// of the entries is not a safe prime.
+func RegisterConvenientSafePrimes(table []ConvenientSafePrime) error {
+	for _, cp := range table {
+		if !cp.isSafePrime() {
+			return fmt.Errorf("2^%d - %d is not a safe prime", cp.Exp, cp.Diff)
+		}
+	}
+
+	registeredSafePrimesLock.Lock()
+	defer registeredSafePrimesLock.Unlock()
+	registeredSafePrimes = append(registeredSafePrimes, table...)
+	sort.SliceStable(registeredSafePrimes, func(i, j int) bool {
+		return registeredSafePrimes[i].Exp < registeredSafePrimes[j].Exp
+	})
+	return nil
+}
+
+// Write a table of convenient safe primes in its serialized (JSON) form
+func WriteConvenientSafePrimeTable(w io.Writer, table []ConvenientSafePrime) error {
+	return json.NewEncoder(w).Encode(table)
+}
+
+// Read a serialized table of convenient safe primes, checking all entries
+func ReadConvenientSafePrimeTable(r io.Reader) ([]ConvenientSafePrime, error) {
+	var table []ConvenientSafePrime
+	if err := json.NewDecoder(r).Decode(&table); err != nil {
+		return nil, err
+	}
+	for _, cp := range table {
+		if !cp.isSafePrime() {
+			return nil, fmt.Errorf("2^%d - %d is not a safe prime", cp.Exp, cp.Diff)
+		}
+	}
+	return table, nil
+}
+
+// Size of the segments of differences sieved at once
+const convenientSieveSegment = 1 << 16
+
+// Odd primes used to sieve candidate differences
+var convenientSievePrimes = func() []int64 {
+	const limit = 1 << 16
+	composite := make([]bool, limit)
+	var primes []int64
+	for i := 3; i < limit; i += 2 {
+		if composite[i] {
+			continue
+		}
+		primes = append(primes, int64(i))
+		for j := i * i; j < limit; j += 2 * i {
+			composite[j] = true
+		}
+	}
+	return primes
+}()
+
+// Search the smallest diff <= maxDiff for which 2^exp - diff is a safe prime.
+func SearchConvenientSafePrime(exp int, maxDiff int) (ConvenientSafePrime, error) {
+	if exp < 32 {
+		return ConvenientSafePrime{}, errors.New("exponent too small for convenient safe prime search")
+	}
+
+	// Candidates have diff = 1 (mod 4), so that p = 3 (mod 4). The j-th candidate
+	// in the sieve is diff = 1 + 4*j. For every sieving prime r, exclude the
+	// candidates with r | p or r | (p-1)/2, i.e. diff = 2^exp or 2^exp - 1 (mod r).
+	pow := new(big.Int).Lsh(big.NewInt(1), uint(exp))
+	powMod := make([]int64, len(convenientSievePrimes))
+	inv4 := make([]int64, len(convenientSievePrimes))
+	for i, r := range convenientSievePrimes {
+		powMod[i] = new(big.Int).Mod(pow, big.NewInt(r)).Int64()
+		inv4[i] = new(big.Int).ModInverse(big.NewInt(4), big.NewInt(r)).Int64()
+	}
+
+	sieve := make([]bool, convenientSieveSegment)
+	for start := int64(0); 1+4*start <= int64(maxDiff); start += convenientSieveSegment {
+		for i := range sieve {
+			sieve[i] = false
+		}
+		for i, r := range convenientSievePrimes {
+			for _, excluded := range []int64{powMod[i], (powMod[i] + r - 1) % r} {
+				// Solve 1 + 4*(start + k) = excluded (mod r) for k
+				k := ((excluded-1-4*start)%r + r) % r * inv4[i] % r
+				for ; k < convenientSieveSegment; k += r {
+					sieve[k] = true
+				}
+			}
+		}
+
+		for k, excluded := range sieve {
+			diff := 1 + 4*(start+int64(k))
+			if diff > int64(maxDiff) {
+				break
+			}
+			if excluded {
+				continue
+			}
+
+			// Quick fermat tests first, as nearly all candidates fail
+			p := new(big.Int).Sub(pow, big.NewInt(diff))
+			q := new(big.Int).Rsh(p, 1)
+			if new(big.Int).Exp(big.NewInt(2), new(big.Int).Sub(q, big.NewInt(1)), q).Cmp(big.NewInt(1)) != 0 {
+				continue
+			}
+			if new(big.Int).Exp(big.NewInt(2), new(big.Int).Sub(p, big.NewInt(1)), p).Cmp(big.NewInt(1)) != 0 {
+				continue
+			}
+			if q.ProbablyPrime(40) && p.ProbablyPrime(40) {
+				return ConvenientSafePrime{exp, int(diff)}, nil
+			}
+		}
+	}
+
+	return ConvenientSafePrime{}, fmt.Errorf("no safe prime 2^%d - diff with diff <= %d", exp, maxDiff)
+}
+
+// Search convenient safe primes such that every size from minSize up to
+// maxSize has one, skipping sizes already covered by known primes.
+func SearchConvenientSafePrimeTable(minSize int, maxSize int) ([]ConvenientSafePrime, error) {
+	var table []ConvenientSafePrime
+
+	Follower.StepStart("Searching convenient safe primes", 0)
+	defer Follower.StepDone()
+
+	for size := minSize; size <= maxSize; {
+		cp, ok := lookupConvenientPrime(size)
+		if !ok {
+			cp, ok = lookupConvenientPrimeIn(table, size)
+		}
+		if !ok {
+			var err error
+			cp, err = SearchConvenientSafePrime(size+convenientRange-1, math.MaxInt32)
+			if err != nil {
+				return nil, err
+			}
+			table = append(table, cp)
+			Follower.Tick()
+		}
+		// All sizes from size up to cp.Exp are covered by cp
+		size = cp.Exp
+	}
+
+	return table, nil
+}
@@ -1,6 +1,8 @@
 package primeproofs
 
 import "testing"
+import "bytes"
+import "strings"
 
 var testcases = []int{
 	250,
@@ -26,3 +28,108 @@ func TestFindSafePrime(t *testing.T) {
 		}
 	}
 }
+
+func TestConvenientSafePrimeTable(t *testing.T) {
+	for _, cp := range convenientSafePrimes {
+		if cp.Diff%4 != 1 || cp.Diff >= 1<<30 {
+			t.Errorf("Invalid table entry 2^%d - %d", cp.Exp, cp.Diff)
+		}
+	}
+	for i := 1; i < len(convenientSafePrimes); i++ {
+		if convenientSafePrimes[i-1].Exp >= convenientSafePrimes[i].Exp {
+			t.Errorf("Table not sorted at 2^%d", convenientSafePrimes[i].Exp)
+		}
+	}
+
+	for size := 1024; size <= 4098; size++ {
+		if findConvenientPrime(size) == nil {
+			t.Errorf("No convenient prime for size %d", size)
+		}
+	}
+	for size := 4583; size <= 4742; size++ {
+		if findConvenientPrime(size) == nil {
+			t.Errorf("No convenient prime for size %d", size)
+		}
+	}
+	for _, modulusSize := range []int{1024, 1536, 2048, 3072, 4096} {
+		if findConvenientPrime(LegacySecurityParams.groupPrimeSize(modulusSize)) == nil {
+			t.Errorf("No convenient group prime for %d bit moduli", modulusSize)
+		}
+	}
+}
+
+func TestFindConvenientSafePrime(t *testing.T) {
+	if _, err := FindConvenientSafePrime(MaxBuiltinConvenientSafePrimeSize); err != nil {
+		t.Error(err.Error())
+	}
+	if _, err := FindConvenientSafePrime(MaxBuiltinConvenientSafePrimeSize + 1); err == nil {
+		t.Error("Found convenient safe prime beyond the built in table")
+	}
+	if _, err := FindConvenientSafePrime(LegacySecurityParams.groupPrimeSize(8192)); err == nil {
+		t.Error("Found convenient group prime for 8192 bit moduli")
+	}
+}
+
+func TestSearchConvenientSafePrime(t *testing.T) {
+	cp, err := SearchConvenientSafePrime(787, 1<<20)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if cp.Exp != 787 || cp.Diff != 7341 {
+		t.Errorf("Found 2^%d - %d instead of the first table entry", cp.Exp, cp.Diff)
+	}
+
+	_, err = SearchConvenientSafePrime(787, 7000)
+	if err == nil {
+		t.Error("Found safe prime with too large difference")
+	}
+}
+
+func TestRegisterConvenientSafePrimes(t *testing.T) {
+	table, err := SearchConvenientSafePrimeTable(300, 400)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := WriteConvenientSafePrimeTable(&buf, table); err != nil {
+		t.Error(err.Error())
+		return
+	}
+	readTable, err := ReadConvenientSafePrimeTable(&buf)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if len(readTable) != len(table) {
+		t.Error("Table changed by serialization")
+		return
+	}
+
+	if err := RegisterConvenientSafePrimes(readTable); err != nil {
+		t.Error(err.Error())
+	}
+	for size := 300; size <= 400; size++ {
+		if findConvenientPrime(size) == nil {
+			t.Errorf("Registered table doesn't cover size %d", size)
+		}
+	}
+}
+
+func TestRegisterInvalidSafePrimes(t *testing.T) {
+	// 2^787 - 7341 is the largest safe prime of this form, so 2^787 - 7337 is not one
+	if RegisterConvenientSafePrimes([]ConvenientSafePrime{{787, 7341}, {787, 7337}}) == nil {
+		t.Error("Registered invalid safe prime")
+	}
+
+	_, err := ReadConvenientSafePrimeTable(strings.NewReader(`[{"Exp": 787, "Diff": 7337}]`))
+	if err == nil {
+		t.Error("Read table with invalid safe prime")
+	}
+	_, err = ReadConvenientSafePrimeTable(strings.NewReader(`[{"Exp": 787`))
+	if err == nil {
+		t.Error("Read corrupted table")
+	}
+}
@@ -1,5 +1,6 @@
 package primeproofs
 
+// Default values, as used by the legacy-80 parameter set
 const almostSafePrimeProductNonceSize = 256 // Probably not that important
 const almostSafePrimeProductIters = 250     // needed since error prob is 4/5
 const disjointPrimeProductIters = 8         // error prob of 1/minimumFactor
@@ -10,3 +11,187 @@ const minimumFactor = 1024
 
 const rangeProofIters = 80    // Binary challenge, so error rate of 1/2
 const rangeProofEpsilon = 256 // Number of bits for statistical hiding
+
+const paillierBlumIters = 80 // Default, error prob of 1/2
+
+const discreteLogProofIters = 80 // Binary challenge, so error rate of 1/2
+
+// A SecurityParams gives the soundness and hiding parameters used by a proof.
+// Proofs record the name of the set they were built with.
+type SecurityParams struct {
+	Name string
+
+	RangeProofIters           int  // Binary challenge, so error rate of 1/2
+	RangeProofEpsilon         uint // Number of bits for statistical hiding
+	LargeChallengeRangeProofs bool // Single iteration range proofs, see largerangeproof.go
+	BatchedRangeProofs        bool // Verify all range proof iterations at once, see rangeproofbatch.go
+	RangeProofBatchBits       uint // Size of the batching randomizers, error prob of 2^-RangeProofBatchBits
+	ExactRangeProofs          bool // Bit range proofs on all pederson commitments, see bitrangeproof.go
+
+	AlmostSafePrimeProductNonceSize uint
+	AlmostSafePrimeProductIters     int // error prob of 4/5
+	DisjointPrimeProductIters       int // error prob of 1/MinimumFactor
+	PrimePowerProductIters          int // error prob of 1/2
+	SquareFreeIters                 int // error prob of 1/MinimumFactor
+
+	DirectSquareProofs     bool // Prove the bases are squares in Z_N*, see directsquareproof.go
+	DirectSquareProofIters int  // error prob of 1/2
+
+	DiscreteLogProofIters int // error prob of 1/2, see discretelogproof.go
+
+	CompactPrimeProofs bool // Leave out the aneg half of the prime proofs, see primeproof.go
+	PrimeProofRounds   int  // Number of bases per prime proof, 0 is taken as 1
+
+	MinimumFactor int
+}
+
+var LegacySecurityParams = SecurityParams{
+	Name:                            "legacy-80",
+	RangeProofIters:                 rangeProofIters,
+	RangeProofEpsilon:               rangeProofEpsilon,
+	AlmostSafePrimeProductNonceSize: almostSafePrimeProductNonceSize,
+	AlmostSafePrimeProductIters:     almostSafePrimeProductIters,
+	DisjointPrimeProductIters:       disjointPrimeProductIters,
+	PrimePowerProductIters:          primePowerProductIters,
+	SquareFreeIters:                 squareFreeIters,
+	DiscreteLogProofIters:           discreteLogProofIters,
+	MinimumFactor:                   minimumFactor,
+}
+
+// The errors of all sub-proofs add up, and a 2048 bit key has about 2^14 range
+// proofs, so every component is given about 2^-134 to keep the combined
+// soundness error below 2^-128, see SecurityEstimate
+var Strong128SecurityParams = SecurityParams{
+	Name:                            "128-bit",
+	RangeProofIters:                 148, // 2^14 proofs with an error of 2^-148 each
+	RangeProofEpsilon:               rangeProofEpsilon,
+	AlmostSafePrimeProductNonceSize: almostSafePrimeProductNonceSize,
+	AlmostSafePrimeProductIters:     415, // (4/5)^415 < 2^-133
+	DisjointPrimeProductIters:       14,  // 1024^-14 = 2^-140
+	PrimePowerProductIters:          134,
+	SquareFreeIters:                 14,
+	DiscreteLogProofIters:           134,
+	MinimumFactor:                   minimumFactor,
+}
+
+// As Strong128SecurityParams, but with batch verified range proofs, which are
+// larger but much faster to verify
+var Strong128BatchedSecurityParams = SecurityParams{
+	Name:                            "128-bit-batched",
+	RangeProofIters:                 148,
+	RangeProofEpsilon:               rangeProofEpsilon,
+	BatchedRangeProofs:              true,
+	RangeProofBatchBits:             134,
+	AlmostSafePrimeProductNonceSize: almostSafePrimeProductNonceSize,
+	AlmostSafePrimeProductIters:     415,
+	DisjointPrimeProductIters:       14,
+	PrimePowerProductIters:          134,
+	SquareFreeIters:                 14,
+	DiscreteLogProofIters:           134,
+	MinimumFactor:                   minimumFactor,
+}
+
+// As Strong128SecurityParams, but proving the bases are squares directly in
+// Z_N*, which is much cheaper for keys with many bases
+var Strong128DirectSquaresSecurityParams = SecurityParams{
+	Name:                            "128-bit-direct-squares",
+	RangeProofIters:                 148,
+	RangeProofEpsilon:               rangeProofEpsilon,
+	AlmostSafePrimeProductNonceSize: almostSafePrimeProductNonceSize,
+	AlmostSafePrimeProductIters:     415,
+	DisjointPrimeProductIters:       14,
+	PrimePowerProductIters:          134,
+	SquareFreeIters:                 14,
+	DirectSquareProofs:              true,
+	DirectSquareProofIters:          134,
+	DiscreteLogProofIters:           134,
+	MinimumFactor:                   minimumFactor,
+}
+
+// As Strong128SecurityParams, but excluding factors below 2^16, so the square
+// free and disjoint prime product proofs need fewer iterations
+var Strong128LargeFactorSecurityParams = SecurityParams{
+	Name:                            "128-bit-large-factor",
+	RangeProofIters:                 148,
+	RangeProofEpsilon:               rangeProofEpsilon,
+	AlmostSafePrimeProductNonceSize: almostSafePrimeProductNonceSize,
+	AlmostSafePrimeProductIters:     415,
+	DisjointPrimeProductIters:       9, // 65536^-9 = 2^-144
+	PrimePowerProductIters:          134,
+	SquareFreeIters:                 9,
+	DiscreteLogProofIters:           134,
+	MinimumFactor:                   1 << 16,
+}
+
+// As Strong128SecurityParams, but with compact prime proofs, which are about
+// half the size and time of the full ones
+var Strong128CompactPrimesSecurityParams = SecurityParams{
+	Name:                            "128-bit-compact-primes",
+	RangeProofIters:                 148,
+	RangeProofEpsilon:               rangeProofEpsilon,
+	AlmostSafePrimeProductNonceSize: almostSafePrimeProductNonceSize,
+	AlmostSafePrimeProductIters:     415,
+	DisjointPrimeProductIters:       14,
+	PrimePowerProductIters:          134,
+	SquareFreeIters:                 14,
+	DiscreteLogProofIters:           134,
+	CompactPrimeProofs:              true,
+	MinimumFactor:                   minimumFactor,
+}
+
+// Only for testing, provides hardly any soundness
+var FastTestSecurityParams = SecurityParams{
+	Name:                            "fast-test",
+	RangeProofIters:                 1,
+	RangeProofEpsilon:               64,
+	LargeChallengeRangeProofs:       true,
+	AlmostSafePrimeProductNonceSize: 64,
+	AlmostSafePrimeProductIters:     10,
+	DisjointPrimeProductIters:       2,
+	PrimePowerProductIters:          8,
+	SquareFreeIters:                 2,
+	DiscreteLogProofIters:           8,
+	MinimumFactor:                   minimumFactor,
+}
+
+var securityParamSets = map[string]*SecurityParams{
+	LegacySecurityParams.Name:                 &LegacySecurityParams,
+	Strong128SecurityParams.Name:              &Strong128SecurityParams,
+	Strong128BatchedSecurityParams.Name:       &Strong128BatchedSecurityParams,
+	Strong128DirectSquaresSecurityParams.Name: &Strong128DirectSquaresSecurityParams,
+	Strong128LargeFactorSecurityParams.Name:   &Strong128LargeFactorSecurityParams,
+	Strong128CompactPrimesSecurityParams.Name: &Strong128CompactPrimesSecurityParams,
+	FastTestSecurityParams.Name:               &FastTestSecurityParams,
+}
+
+// Look up a named security parameter set
+func GetSecurityParams(name string) (*SecurityParams, bool) {
+	params, ok := securityParamSets[name]
+	return params, ok
+}
+
+// Number of commitments generated by a range proof
+func (p *SecurityParams) rangeProofCommitments() int {
+	if p.LargeChallengeRangeProofs {
+		return 3
+	}
+	return p.RangeProofIters
+}
+
+// Number of rounds in each prime proof
+func (p *SecurityParams) primeProofRounds() int {
+	if p.PrimeProofRounds < 1 {
+		return 1
+	}
+	return p.PrimeProofRounds
+}
+
+// Minimum size of the group prime for proofs about numbers of at most nBits bits
+func (p *SecurityParams) groupPrimeSize(nBits int) int {
+	size := nBits + 2*int(p.RangeProofEpsilon) + 10
+	if p.LargeChallengeRangeProofs {
+		// Range proof results also contain challenge*secret
+		size += largeRangeProofChallengeSize
+	}
+	return size
+}
@@ -0,0 +1,46 @@
+package primeproofs
+
+import "testing"
+
+func TestSecurityParamsLookup(t *testing.T) {
+	for _, name := range []string{"legacy-80", "128-bit", "128-bit-batched", "128-bit-direct-squares", "128-bit-large-factor", "128-bit-compact-primes", "fast-test"} {
+		params, ok := GetSecurityParams(name)
+		if !ok {
+			t.Errorf("Missing parameter set %v", name)
+			continue
+		}
+		if params.Name != name {
+			t.Errorf("Parameter set %v has name %v", name, params.Name)
+		}
+	}
+
+	if _, ok := GetSecurityParams("nonexistent"); ok {
+		t.Error("Found nonexistent parameter set")
+	}
+}
+
+func TestSecurityParamsLegacy(t *testing.T) {
+	// The legacy set should match the behaviour from before parameter sets existed
+	if LegacySecurityParams.groupPrimeSize(2048) != 2048+2*256+10 {
+		t.Error("Legacy group prime size changed")
+	}
+	if LegacySecurityParams.rangeProofCommitments() != 80 {
+		t.Error("Legacy range proof commitment count changed")
+	}
+}
+
+func TestSecurityParamsLargeChallenge(t *testing.T) {
+	if FastTestSecurityParams.rangeProofCommitments() != 3 {
+		t.Error("Incorrect number of large challenge range proof commitments")
+	}
+	if FastTestSecurityParams.groupPrimeSize(100) <= 100+2*64+10 {
+		t.Error("Group prime size doesn't account for large challenge")
+	}
+}
+
+// Use a parameter set that isn't registered, for parameters only tests need
+func withTestSecurityParams(params *SecurityParams) ValidKeyProofOption {
+	return func(s *ValidKeyProofStructure) {
+		s.params = params
+	}
+}
@@ -7,7 +7,7 @@ type SquareFreeProof struct {
 	Responses []*big.Int
 }
 
-func squareFreeBuildProof(N *big.Int, phiN *big.Int, challenge *big.Int, index *big.Int) SquareFreeProof {
+func squareFreeBuildProof(N *big.Int, phiN *big.Int, challenge *big.Int, index *big.Int, params *SecurityParams) SquareFreeProof {
 	// Precalculate the primary part of the response
 	M := new(big.Int).ModInverse(N, phiN)
 	if M == nil {
@@ -17,7 +17,7 @@ func squareFreeBuildProof(N *big.Int, phiN *big.Int, challenge *big.Int, index *
 	// Generate the challenges and responses
 	var proof SquareFreeProof
 	proof.Responses = []*big.Int{}
-	for i := 0; i < squareFreeIters; i++ {
+	for i := 0; i < params.SquareFreeIters; i++ {
 		// Generate the challenge
 		curc := common.GetHashNumber(challenge, index, i, uint(N.BitLen()))
 		curc.Mod(curc, N)
@@ -33,8 +33,8 @@ func squareFreeBuildProof(N *big.Int, phiN *big.Int, challenge *big.Int, index *
 	return proof
 }
 
-func squareFreeVerifyStructure(proof SquareFreeProof) bool {
-	if proof.Responses == nil || len(proof.Responses) != squareFreeIters {
+func squareFreeVerifyStructure(proof SquareFreeProof, params *SecurityParams) bool {
+	if proof.Responses == nil || len(proof.Responses) != params.SquareFreeIters {
 		return false
 	}
 
@@ -47,14 +47,14 @@ func squareFreeVerifyStructure(proof SquareFreeProof) bool {
 	return true
 }
 
-func squareFreeVerifyProof(N *big.Int, challenge *big.Int, index *big.Int, proof SquareFreeProof) bool {
+func squareFreeVerifyProof(N *big.Int, challenge *big.Int, index *big.Int, proof SquareFreeProof, params *SecurityParams) bool {
 	// Verify proof structure
-	if len(proof.Responses) != squareFreeIters {
+	if len(proof.Responses) != params.SquareFreeIters {
 		return false
 	}
 
 	// Generate the challenges and verify responses
-	for i := 0; i < squareFreeIters; i++ {
+	for i := 0; i < params.SquareFreeIters; i++ {
 		// Generate the challenge
 		curc := common.GetHashNumber(challenge, index, i, uint(N.BitLen()))
 		curc.Mod(curc, N)
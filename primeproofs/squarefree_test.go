@@ -6,11 +6,11 @@ import "github.com/privacybydesign/gabi/big"
 func TestSquareFreeCycle(t *testing.T) {
 	const p = 1031
 	const q = 1063
-	proof := squareFreeBuildProof(big.NewInt(int64(p*q)), big.NewInt(int64((p-1)*(q-1))), big.NewInt(12345), big.NewInt(0))
-	if !squareFreeVerifyStructure(proof) {
+	proof := squareFreeBuildProof(big.NewInt(int64(p*q)), big.NewInt(int64((p-1)*(q-1))), big.NewInt(12345), big.NewInt(0), &LegacySecurityParams)
+	if !squareFreeVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("proof structure rejected")
 	}
-	ok := squareFreeVerifyProof(big.NewInt(int64(p*q)), big.NewInt(12345), big.NewInt(0), proof)
+	ok := squareFreeVerifyProof(big.NewInt(int64(p*q)), big.NewInt(12345), big.NewInt(0), proof, &LegacySecurityParams)
 	if !ok {
 		t.Errorf("SquareFreeProof rejected.")
 	}
@@ -19,9 +19,9 @@ func TestSquareFreeCycle(t *testing.T) {
 func TestSquareFreeCycleIncorrect(t *testing.T) {
 	const p = 1031
 	const q = 1063
-	proof := squareFreeBuildProof(big.NewInt(int64(p*q)), big.NewInt(int64((p-1)*(q-1))), big.NewInt(12345), big.NewInt(0))
+	proof := squareFreeBuildProof(big.NewInt(int64(p*q)), big.NewInt(int64((p-1)*(q-1))), big.NewInt(12345), big.NewInt(0), &LegacySecurityParams)
 	proof.Responses[0].Add(proof.Responses[0], big.NewInt(1))
-	ok := squareFreeVerifyProof(big.NewInt(int64(p*q)), big.NewInt(12345), big.NewInt(0), proof)
+	ok := squareFreeVerifyProof(big.NewInt(int64(p*q)), big.NewInt(12345), big.NewInt(0), proof, &LegacySecurityParams)
 	if ok {
 		t.Errorf("Incorrect SquareFreeProof accepted.")
 	}
@@ -30,8 +30,8 @@ func TestSquareFreeCycleIncorrect(t *testing.T) {
 func TestSquareFreeCycleWrongChallenge(t *testing.T) {
 	const p = 1031
 	const q = 1063
-	proof := squareFreeBuildProof(big.NewInt(int64(p*q)), big.NewInt(int64((p-1)*(q-1))), big.NewInt(12345), big.NewInt(0))
-	ok := squareFreeVerifyProof(big.NewInt(int64(p*q)), big.NewInt(12346), big.NewInt(0), proof)
+	proof := squareFreeBuildProof(big.NewInt(int64(p*q)), big.NewInt(int64((p-1)*(q-1))), big.NewInt(12345), big.NewInt(0), &LegacySecurityParams)
+	ok := squareFreeVerifyProof(big.NewInt(int64(p*q)), big.NewInt(12346), big.NewInt(0), proof, &LegacySecurityParams)
 	if ok {
 		t.Errorf("Incorrect SquareFreeProof accepted.")
 	}
@@ -40,8 +40,8 @@ func TestSquareFreeCycleWrongChallenge(t *testing.T) {
 func TestSquareFreeCycleWrongIndex(t *testing.T) {
 	const p = 1031
 	const q = 1063
-	proof := squareFreeBuildProof(big.NewInt(int64(p*q)), big.NewInt(int64((p-1)*(q-1))), big.NewInt(12345), big.NewInt(0))
-	ok := squareFreeVerifyProof(big.NewInt(int64(p*q)), big.NewInt(12345), big.NewInt(1), proof)
+	proof := squareFreeBuildProof(big.NewInt(int64(p*q)), big.NewInt(int64((p-1)*(q-1))), big.NewInt(12345), big.NewInt(0), &LegacySecurityParams)
+	ok := squareFreeVerifyProof(big.NewInt(int64(p*q)), big.NewInt(12345), big.NewInt(1), proof, &LegacySecurityParams)
 	if ok {
 		t.Errorf("Incorrect SquareFreeProof accepted.")
 	}
@@ -50,23 +50,23 @@ func TestSquareFreeCycleWrongIndex(t *testing.T) {
 func TestSquareFreeVerifyStructure(t *testing.T) {
 	const p = 1031
 	const q = 1063
-	proof := squareFreeBuildProof(big.NewInt(int64(p*q)), big.NewInt(int64((p-1)*(q-1))), big.NewInt(12345), big.NewInt(0))
+	proof := squareFreeBuildProof(big.NewInt(int64(p*q)), big.NewInt(int64((p-1)*(q-1))), big.NewInt(12345), big.NewInt(0), &LegacySecurityParams)
 
 	listBackup := proof.Responses
 	proof.Responses = proof.Responses[:len(proof.Responses)-1]
-	if squareFreeVerifyStructure(proof) {
+	if squareFreeVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Accepting too short responses")
 	}
 	proof.Responses = listBackup
 
 	valBackup := proof.Responses[2]
 	proof.Responses[2] = nil
-	if squareFreeVerifyStructure(proof) {
+	if squareFreeVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("Accepting missing respone")
 	}
 	proof.Responses[2] = valBackup
 
-	if !squareFreeVerifyStructure(proof) {
+	if !squareFreeVerifyStructure(proof, &LegacySecurityParams) {
 		t.Error("testcase corrupted testdata")
 	}
 }
@@ -0,0 +1,176 @@
+package primeproofs
+
+import "github.com/privacybydesign/keyproof/common"
+import "github.com/privacybydesign/gabi/big"
+
+// Proofs that a modulus N is the product of two distinct primes, without
+// requiring these to be safe primes. These combine the square free proof,
+// which shows gcd(N, phi(N)) = 1 (exactly the requirement on Paillier moduli),
+// with a prime power product proof showing N has at most two prime factors.
+//
+// The standard prime power product proof uses the units +-1, +-2, which only
+// suffice when N = 5 mod 8 and its factors are 3 mod 8 and 7 mod 8. Instead,
+// the prover here commits to two units W1, W2 with known quadratic character,
+// such that for every x one of x, W1*x, W2*x, W1*W2*x is a square.
+//
+// For Blum moduli (P = Q = 3 mod 4), W1 is fixed to -1, W2 must have Jacobi
+// symbol -1 and the responses are fourth roots instead of square roots.
+//
+// The disjoint prime product proof is not part of this, as it requires
+// gcd(oddpart(N-1), phi(N)) = 1, which general two prime moduli don't satisfy.
+
+type ModulusKind int
+
+const (
+	TwoPrimeModulus ModulusKind = iota // N = PQ with P, Q distinct primes and gcd(N, phi(N)) = 1, e.g. Paillier
+	BlumModulus                        // As TwoPrimeModulus, with additionally P = Q = 3 mod 4
+)
+
+type twoPrimeProductCommit struct {
+	units []*big.Int
+}
+
+type TwoPrimeProductProof struct {
+	Units    []*big.Int
+	SFproof  SquareFreeProof
+	PPPproof PrimePowerProductProof
+}
+
+// Build a standalone, non-interactive proof that P*Q is a modulus of the given kind.
+// Standalone proofs use the legacy-80 security parameters.
+func BuildTwoPrimeProductProof(P *big.Int, Q *big.Int, kind ModulusKind) TwoPrimeProductProof {
+	params := &LegacySecurityParams
+	N := new(big.Int).Mul(P, Q)
+	list := []*big.Int{N, big.NewInt(int64(kind))}
+	list, commit := twoPrimeProductBuildCommitments(list, P, Q, kind, params)
+	return twoPrimeProductBuildProof(P, Q, kind, common.HashCommit(list), commit, params)
+}
+
+// Verify a standalone proof that N is a modulus of the given kind
+func VerifyTwoPrimeProductProof(N *big.Int, kind ModulusKind, proof TwoPrimeProductProof) bool {
+	params := &LegacySecurityParams
+	if N == nil || !twoPrimeProductVerifyStructure(proof, params) {
+		return false
+	}
+	list := []*big.Int{N, big.NewInt(int64(kind))}
+	list = twoPrimeProductExtractCommitments(list, proof)
+	return twoPrimeProductVerifyProof(N, kind, common.HashCommit(list), proof, params)
+}
+
+// Find a number that is a (non)residue modulo P and a (non)residue modulo Q, as given by the legendre symbols
+func twoPrimeProductFindUnit(P *big.Int, Q *big.Int, legendreP int, legendreQ int) *big.Int {
+	findLocal := func(prime *big.Int, legendre int) *big.Int {
+		for {
+			a := common.RandomBigInt(prime)
+			if common.LegendreSymbol(a, prime) == legendre {
+				return a
+			}
+		}
+	}
+	return common.Crt(findLocal(P, legendreP), P, findLocal(Q, legendreQ), Q)
+}
+
+func twoPrimeProductBuildCommitments(list []*big.Int, P *big.Int, Q *big.Int, kind ModulusKind, params *SecurityParams) ([]*big.Int, twoPrimeProductCommit) {
+	var commit twoPrimeProductCommit
+	N := new(big.Int).Mul(P, Q)
+
+	if kind == BlumModulus {
+		if P.Bit(1) != 1 || Q.Bit(1) != 1 {
+			panic("Trying to build Blum modulus proof for non-Blum modulus")
+		}
+		commit.units = []*big.Int{
+			new(big.Int).Sub(N, big.NewInt(1)),
+			twoPrimeProductFindUnit(P, Q, -1, 1),
+		}
+	} else {
+		commit.units = []*big.Int{
+			twoPrimeProductFindUnit(P, Q, -1, 1),
+			twoPrimeProductFindUnit(P, Q, 1, -1),
+		}
+	}
+
+	list = append(list, commit.units...)
+	return list, commit
+}
+
+// The full list of units 1, W1, W2, W1*W2 allowed in the prime power product proof
+func twoPrimeProductAllUnits(N *big.Int, units []*big.Int) []*big.Int {
+	return []*big.Int{
+		big.NewInt(1),
+		units[0],
+		units[1],
+		new(big.Int).Mod(new(big.Int).Mul(units[0], units[1]), N),
+	}
+}
+
+func twoPrimeProductSquarings(kind ModulusKind) int {
+	if kind == BlumModulus {
+		return 2
+	}
+	return 1
+}
+
+func twoPrimeProductBuildProof(P *big.Int, Q *big.Int, kind ModulusKind, challenge *big.Int, commit twoPrimeProductCommit, params *SecurityParams) TwoPrimeProductProof {
+	// Calculate useful intermediaries
+	N := new(big.Int).Mul(P, Q)
+	phiN := new(big.Int).Mul(new(big.Int).Sub(P, big.NewInt(1)), new(big.Int).Sub(Q, big.NewInt(1)))
+
+	// Build the actual proofs
+	var proof TwoPrimeProductProof
+	proof.Units = commit.units
+	proof.SFproof = squareFreeBuildProof(N, phiN, challenge, big.NewInt(0), params)
+	proof.PPPproof = primePowerProductBuildProofWithUnits(P, Q, challenge, big.NewInt(1),
+		twoPrimeProductAllUnits(N, commit.units), twoPrimeProductSquarings(kind), params)
+
+	return proof
+}
+
+func twoPrimeProductVerifyStructure(proof TwoPrimeProductProof, params *SecurityParams) bool {
+	if proof.Units == nil || len(proof.Units) != 2 || proof.Units[0] == nil || proof.Units[1] == nil {
+		return false
+	}
+	return squareFreeVerifyStructure(proof.SFproof, params) &&
+		primePowerProductVerifyStructure(proof.PPPproof, params)
+}
+
+func twoPrimeProductExtractCommitments(list []*big.Int, proof TwoPrimeProductProof) []*big.Int {
+	return append(list, proof.Units...)
+}
+
+func twoPrimeProductVerifyProof(N *big.Int, kind ModulusKind, challenge *big.Int, proof TwoPrimeProductProof, params *SecurityParams) bool {
+	// N should be composite, and have no small factors for the square free proof to be sound
+	if N.Cmp(big.NewInt(int64(params.MinimumFactor))) <= 0 || N.ProbablyPrime(80) {
+		return false
+	}
+	if !noSmallFactors(N, params) {
+		return false
+	}
+
+	// Check the units
+	for _, unit := range proof.Units {
+		if unit.Sign() <= 0 || unit.Cmp(N) >= 0 {
+			return false
+		}
+		if new(big.Int).GCD(nil, nil, unit, N).Cmp(big.NewInt(1)) != 0 {
+			return false
+		}
+	}
+	if kind == BlumModulus {
+		if proof.Units[0].Cmp(new(big.Int).Sub(N, big.NewInt(1))) != 0 {
+			return false
+		}
+		if common.LegendreSymbol(proof.Units[1], N) != -1 {
+			return false
+		}
+	}
+
+	// Validate the individual parts
+	return squareFreeVerifyProof(N, challenge, big.NewInt(0), proof.SFproof, params) &&
+		primePowerProductVerifyProofWithUnits(N, challenge, big.NewInt(1),
+			twoPrimeProductAllUnits(N, proof.Units), twoPrimeProductSquarings(kind), proof.PPPproof, params)
+}
+
+// Check N has no factors below the minimum factor of the parameter set
+func noSmallFactors(N *big.Int, params *SecurityParams) bool {
+	return !common.HasSmallFactor(N, params.MinimumFactor)
+}
@@ -0,0 +1,125 @@
+package primeproofs
+
+import "testing"
+import "encoding/json"
+import "github.com/privacybydesign/gabi/big"
+
+func TestTwoPrimeProductCycle(t *testing.T) {
+	// Both factors 1 mod 8, so +-1, +-2 are all squares and the standard units would not work
+	const p = 2147483033
+	const q = 2147483137
+	proof := BuildTwoPrimeProductProof(big.NewInt(p), big.NewInt(q), TwoPrimeModulus)
+	if !VerifyTwoPrimeProductProof(big.NewInt(p*q), TwoPrimeModulus, proof) {
+		t.Error("TwoPrimeProduct rejected")
+	}
+	if VerifyTwoPrimeProductProof(big.NewInt(p*q), BlumModulus, proof) {
+		t.Error("TwoPrimeProduct accepted as Blum modulus proof")
+	}
+	if VerifyTwoPrimeProductProof(big.NewInt(2147483059*2147483399), TwoPrimeModulus, proof) {
+		t.Error("TwoPrimeProduct accepted for wrong modulus")
+	}
+}
+
+func TestTwoPrimeProductBlumCycle(t *testing.T) {
+	const p = 2147483059
+	const q = 2147483399
+	proof := BuildTwoPrimeProductProof(big.NewInt(p), big.NewInt(q), BlumModulus)
+	if !VerifyTwoPrimeProductProof(big.NewInt(p*q), BlumModulus, proof) {
+		t.Error("Blum proof rejected")
+	}
+	if VerifyTwoPrimeProductProof(big.NewInt(p*q), TwoPrimeModulus, proof) {
+		t.Error("Blum proof accepted as two prime proof")
+	}
+}
+
+func TestTwoPrimeProductBlumNonBlum(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Building Blum proof for non-Blum modulus did not panic")
+		}
+	}()
+	BuildTwoPrimeProductProof(big.NewInt(2147483033), big.NewInt(2147483399), BlumModulus)
+}
+
+func TestTwoPrimeProductPrime(t *testing.T) {
+	// A prime can't be proven, but the verifier should also reject it regardless of the proof
+	proof := BuildTwoPrimeProductProof(big.NewInt(2147483033), big.NewInt(2147483137), TwoPrimeModulus)
+	if VerifyTwoPrimeProductProof(big.NewInt(2147483033), TwoPrimeModulus, proof) {
+		t.Error("Accepted prime modulus")
+	}
+}
+
+func TestTwoPrimeProductJSON(t *testing.T) {
+	const p = 2147483059
+	const q = 2147483399
+	proofBefore := BuildTwoPrimeProductProof(big.NewInt(p), big.NewInt(q), BlumModulus)
+	proofJSON, err := json.Marshal(proofBefore)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	var proofAfter TwoPrimeProductProof
+	err = json.Unmarshal(proofJSON, &proofAfter)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	if !VerifyTwoPrimeProductProof(big.NewInt(p*q), BlumModulus, proofAfter) {
+		t.Error("JSON proof rejected")
+	}
+}
+
+func TestTwoPrimeProductVerifyStructure(t *testing.T) {
+	const p = 2147483033
+	const q = 2147483137
+	proof := BuildTwoPrimeProductProof(big.NewInt(p), big.NewInt(q), TwoPrimeModulus)
+
+	if twoPrimeProductVerifyStructure(TwoPrimeProductProof{}, &LegacySecurityParams) {
+		t.Error("Accepting empty proof")
+	}
+
+	valBackup := proof.Units[1]
+	proof.Units[1] = nil
+	if twoPrimeProductVerifyStructure(proof, &LegacySecurityParams) {
+		t.Error("Accepting missing unit")
+	}
+	proof.Units[1] = valBackup
+
+	valBackup = proof.SFproof.Responses[2]
+	proof.SFproof.Responses[2] = nil
+	if twoPrimeProductVerifyStructure(proof, &LegacySecurityParams) {
+		t.Error("Accepting corrupted sfproof")
+	}
+	proof.SFproof.Responses[2] = valBackup
+
+	valBackup = proof.PPPproof.Responses[2]
+	proof.PPPproof.Responses[2] = nil
+	if twoPrimeProductVerifyStructure(proof, &LegacySecurityParams) {
+		t.Error("Accepting corrupted pppproof")
+	}
+	proof.PPPproof.Responses[2] = valBackup
+
+	if !twoPrimeProductVerifyStructure(proof, &LegacySecurityParams) {
+		t.Error("Testcase corrupted testdata")
+	}
+}
+
+func TestTwoPrimeProductBadUnits(t *testing.T) {
+	const p = 2147483033
+	const q = 2147483137
+	N := big.NewInt(p * q)
+	proof := BuildTwoPrimeProductProof(big.NewInt(p), big.NewInt(q), TwoPrimeModulus)
+
+	// Units not coprime to N
+	proof.Units[0] = big.NewInt(p)
+	if VerifyTwoPrimeProductProof(N, TwoPrimeModulus, proof) {
+		t.Error("Accepting unit sharing a factor with N")
+	}
+
+	// Units out of range
+	proof.Units[0] = new(big.Int).Add(N, big.NewInt(1))
+	if VerifyTwoPrimeProductProof(N, TwoPrimeModulus, proof) {
+		t.Error("Accepting unit outside of Z_N")
+	}
+}
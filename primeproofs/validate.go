@@ -0,0 +1,156 @@
+package primeproofs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A ValidationReport lists the bases and secrets a proof structure refers to
+// without them being provided, and those that are provided but never used.
+type ValidationReport struct {
+	MissingBases   []string
+	MissingSecrets []string
+	UnusedBases    []string
+	UnusedSecrets  []string
+}
+
+func (r ValidationReport) Ok() bool {
+	return len(r.MissingBases) == 0 && len(r.MissingSecrets) == 0 &&
+		len(r.UnusedBases) == 0 && len(r.UnusedSecrets) == 0
+}
+
+func (r ValidationReport) String() string {
+	if r.Ok() {
+		return "structure valid"
+	}
+	var parts []string
+	if len(r.MissingBases) != 0 {
+		parts = append(parts, fmt.Sprintf("missing bases: %s", strings.Join(r.MissingBases, ", ")))
+	}
+	if len(r.MissingSecrets) != 0 {
+		parts = append(parts, fmt.Sprintf("missing secrets: %s", strings.Join(r.MissingSecrets, ", ")))
+	}
+	if len(r.UnusedBases) != 0 {
+		parts = append(parts, fmt.Sprintf("unused bases: %s", strings.Join(r.UnusedBases, ", ")))
+	}
+	if len(r.UnusedSecrets) != 0 {
+		parts = append(parts, fmt.Sprintf("unused secrets: %s", strings.Join(r.UnusedSecrets, ", ")))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (r ValidationReport) merge(other ValidationReport) ValidationReport {
+	return ValidationReport{
+		MissingBases:   append(r.MissingBases, other.MissingBases...),
+		MissingSecrets: append(r.MissingSecrets, other.MissingSecrets...),
+		UnusedBases:    append(r.UnusedBases, other.UnusedBases...),
+		UnusedSecrets:  append(r.UnusedSecrets, other.UnusedSecrets...),
+	}
+}
+
+// Keeps track of which names are provided and used while walking a proof structure
+type nameTracker struct {
+	providedBases   map[string]bool
+	providedSecrets map[string]bool
+	usedBases       map[string]bool
+	usedSecrets     map[string]bool
+}
+
+type nameCollector interface {
+	collectNames(t *nameTracker)
+}
+
+// A secret lookup that can list the names it answers for, being those it holds
+// randomizers for and the simulated branches of or-proofs
+type namedSecretLookup interface {
+	secretLookup
+	secretNames() []string
+}
+
+func newNameTracker() *nameTracker {
+	return &nameTracker{
+		providedBases:   map[string]bool{},
+		providedSecrets: map[string]bool{},
+		usedBases:       map[string]bool{},
+		usedSecrets:     map[string]bool{},
+	}
+}
+
+func (t *nameTracker) provideBases(lookups ...baseLookup) {
+	for _, lookup := range lookups {
+		for _, name := range lookup.names() {
+			t.providedBases[name] = true
+		}
+	}
+}
+
+func (t *nameTracker) provideSecrets(lookups ...namedSecretLookup) {
+	for _, lookup := range lookups {
+		for _, name := range lookup.secretNames() {
+			t.providedSecrets[name] = true
+		}
+	}
+}
+
+// A pederson commitment provides itself as base, and its value and hider as secrets
+func (t *nameTracker) providePederson(p *pedersonSecret) {
+	t.provideBases(p)
+	t.provideSecrets(p)
+}
+
+func (t *nameTracker) useBase(names ...string) {
+	for _, name := range names {
+		t.usedBases[name] = true
+	}
+}
+
+func (t *nameTracker) useSecret(names ...string) {
+	for _, name := range names {
+		t.usedSecrets[name] = true
+	}
+}
+
+// Uses both the value and the hider of a pederson commitment
+func (t *nameTracker) usePedersonSecret(names ...string) {
+	for _, name := range names {
+		p := newPedersonName(name)
+		t.useSecret(p.secretNames()...)
+	}
+}
+
+func (t *nameTracker) report() ValidationReport {
+	var result ValidationReport
+	result.MissingBases = namesMissing(t.usedBases, t.providedBases)
+	result.MissingSecrets = namesMissing(t.usedSecrets, t.providedSecrets)
+	result.UnusedBases = namesMissing(t.providedBases, t.usedBases)
+	result.UnusedSecrets = namesMissing(t.providedSecrets, t.usedSecrets)
+	return result
+}
+
+// Returns the sorted list of names in a that are not in b
+func namesMissing(a, b map[string]bool) []string {
+	var result []string
+	for name := range a {
+		if !b[name] {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Validate a (sub)structure given the names of the pederson commitments
+// provided to it from the outside. The group bases g and h are always available.
+func validateStructure(s nameCollector, pedersons ...string) ValidationReport {
+	var g group
+	t := newNameTracker()
+	t.provideBases(&g)
+	t.useBase(g.names()...)
+	for _, name := range pedersons {
+		p := newPedersonName(name)
+		t.providePederson(&p)
+	}
+	s.collectNames(t)
+	return t.report()
+}
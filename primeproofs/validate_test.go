@@ -0,0 +1,89 @@
+package primeproofs
+
+import "testing"
+import "github.com/privacybydesign/gabi/big"
+
+func TestValidateRepresentation(t *testing.T) {
+	s := representationProofStructure{
+		[]lhsContribution{
+			lhsContribution{"x", big.NewInt(1)},
+		},
+		[]rhsContribution{
+			rhsContribution{"g", "x", 1},
+			rhsContribution{"h", "x_hider", 1},
+		},
+	}
+	report := validateStructure(&s, "x")
+	if !report.Ok() {
+		t.Errorf("Correct structure rejected: %v", report)
+	}
+
+	s.rhs[1].secret = "y_hider"
+	report = validateStructure(&s, "x")
+	if report.Ok() {
+		t.Error("Mis-wired structure accepted")
+	}
+	if len(report.MissingSecrets) != 1 || report.MissingSecrets[0] != "y_hider" {
+		t.Errorf("Incorrect missing secrets %v", report.MissingSecrets)
+	}
+	if len(report.UnusedSecrets) != 1 || report.UnusedSecrets[0] != "x_hider" {
+		t.Errorf("Incorrect unused secrets %v", report.UnusedSecrets)
+	}
+}
+
+func TestValidateMissingBase(t *testing.T) {
+	s := newPedersonRepresentationProofStructure("x")
+	report := validateStructure(&s)
+	if len(report.MissingBases) != 1 || report.MissingBases[0] != "x" {
+		t.Errorf("Incorrect missing bases %v", report.MissingBases)
+	}
+}
+
+func TestValidateSubStructures(t *testing.T) {
+	prime := newPrimeProofStructure("p", 4, &LegacySecurityParams)
+	if report := validateStructure(&prime, "p"); !report.Ok() {
+		t.Errorf("Prime proof structure rejected: %v", report)
+	}
+
+	// m1 is only used as a secret here, so only check nothing is missing
+	mult := newMultiplicationProofStructure("m1", "m2", "mod", "result", 4, &LegacySecurityParams)
+	if report := validateStructure(&mult, "m1", "m2", "mod", "result"); len(report.MissingBases) != 0 || len(report.MissingSecrets) != 0 {
+		t.Errorf("Multiplication proof structure rejected: %v", report)
+	}
+
+	add := newAdditionProofStructure("a1", "a2", "mod", "result", 4, &LegacySecurityParams)
+	if report := validateStructure(&add, "a1", "a2", "mod", "result"); !report.Ok() {
+		t.Errorf("Addition proof structure rejected: %v", report)
+	}
+}
+
+func TestValidateValidKeyProofStructure(t *testing.T) {
+	const p = 26903
+	const q = 27803
+
+	s := NewValidKeyProofStructure(big.NewInt(p*q), big.NewInt(36), big.NewInt(49), []*big.Int{big.NewInt(64)})
+	if report := s.Validate(); !report.Ok() {
+		t.Errorf("Valid key proof structure rejected: %v", report)
+	}
+
+	s.pQNRel.rhs[0].secret = "r"
+	report := s.Validate()
+	if len(report.MissingSecrets) != 1 || report.MissingSecrets[0] != "r" {
+		t.Errorf("Incorrect missing secrets %v", report.MissingSecrets)
+	}
+}
+
+func TestValidateDiscreteLogBases(t *testing.T) {
+	const p = 26903
+	const q = 27803
+
+	s := NewValidKeyProofStructure(big.NewInt(p*q), big.NewInt(36), big.NewInt(49), []*big.Int{big.NewInt(64)})
+	s.basesLogs.bases[1] = big.NewInt(81)
+	report := s.Validate()
+	if len(report.MissingBases) != 1 || report.MissingBases[0] != "B_1" {
+		t.Errorf("Incorrect missing bases %v", report.MissingBases)
+	}
+	if len(report.UnusedBases) != 1 || report.UnusedBases[0] != "s_2" {
+		t.Errorf("Incorrect unused bases %v", report.UnusedBases)
+	}
+}
@@ -2,9 +2,12 @@ package primeproofs
 
 import "github.com/privacybydesign/keyproof/common"
 import "github.com/privacybydesign/gabi/big"
+import "fmt"
 
 type ValidKeyProofStructure struct {
 	n          *big.Int
+	params     *SecurityParams
+	policy     VerifierPolicy
 	pRep       representationProofStructure
 	qRep       representationProofStructure
 	pprimeRep  representationProofStructure
@@ -17,6 +20,7 @@ type ValidKeyProofStructure struct {
 	qprimeIsPrime primeProofStructure
 
 	basesValid isSquareProofStructure
+	basesLogs  discreteLogProofStructure
 }
 
 type ValidKeyProof struct {
@@ -28,12 +32,21 @@ type ValidKeyProof struct {
 	Challenge   *big.Int
 	GroupPrime  *big.Int
 
+	// Determines how the group generators are chosen, absent for legacy proofs
+	GroupVersion int `json:",omitempty"`
+
+	// Name of the security parameter set used, absent for legacy-80
+	SecurityParams string `json:",omitempty"`
+
 	PprimeIsPrimeProof PrimeProof
 	QprimeIsPrimeProof PrimeProof
 
 	QSPPproof QuasiSafePrimeProductProof
 
 	BasesValidProof IsSquareProof
+
+	// Only present when built with the discrete logs of Z and the R_i
+	BasesLogProof *DiscreteLogProof `json:",omitempty"`
 }
 
 type safePrimeSecret struct {
@@ -55,6 +68,40 @@ func (s *safePrimeSecret) getRandomizer(name string) *big.Int {
 	return nil
 }
 
+func (s *safePrimeSecret) secretNames() []string {
+	return []string{"pqnrel"}
+}
+
+// Secrets and randomizers of a proof for a single key
+type validKeyProofCommit struct {
+	pprime *big.Int
+	qprime *big.Int
+	p      *big.Int
+	q      *big.Int
+
+	pSecret      pedersonSecret
+	qSecret      pedersonSecret
+	pprimeSecret pedersonSecret
+	qprimeSecret pedersonSecret
+	pQNRelSecret safePrimeSecret
+
+	pprimeIsPrime primeProofCommit
+	qprimeIsPrime primeProofCommit
+	qspp          quasiSafePrimeProductCommit
+	basesValid    isSquareProofCommit
+	hasLogs       bool
+	basesLogs     discreteLogProofCommit
+}
+
+// The pederson commitments of the commit, all provided as bases and secrets
+func (c *validKeyProofCommit) pedersons() []*pedersonSecret {
+	return []*pedersonSecret{&c.pSecret, &c.qSecret, &c.pprimeSecret, &c.qprimeSecret}
+}
+
+func (c *validKeyProofCommit) secrets() secretMerge {
+	return newSecretMerge(&c.pSecret, &c.qSecret, &c.pprimeSecret, &c.qprimeSecret, &c.pQNRelSecret)
+}
+
 func (p *ValidKeyProof) getResult(name string) *big.Int {
 	if name == "pqnrel" {
 		return p.PQNRel
@@ -62,10 +109,28 @@ func (p *ValidKeyProof) getResult(name string) *big.Int {
 	return nil
 }
 
-func NewValidKeyProofStructure(N *big.Int, Z *big.Int, S *big.Int, Bases []*big.Int) ValidKeyProofStructure {
+// Options for NewValidKeyProofStructure
+type ValidKeyProofOption func(s *ValidKeyProofStructure)
+
+// Select the named security parameter set, panics if it doesn't exist
+func WithSecurityParams(name string) ValidKeyProofOption {
+	params, ok := GetSecurityParams(name)
+	if !ok {
+		panic(fmt.Sprintf("Unknown security parameter set %v", name))
+	}
+	return func(s *ValidKeyProofStructure) {
+		s.params = params
+	}
+}
+
+func NewValidKeyProofStructure(N *big.Int, Z *big.Int, S *big.Int, Bases []*big.Int, opts ...ValidKeyProofOption) ValidKeyProofStructure {
 	var structure ValidKeyProofStructure
 
 	structure.n = new(big.Int).Set(N)
+	structure.params = &LegacySecurityParams
+	for _, opt := range opts {
+		opt(&structure)
+	}
 	structure.pRep = newPedersonRepresentationProofStructure("p")
 	structure.qRep = newPedersonRepresentationProofStructure("q")
 	structure.pprimeRep = newPedersonRepresentationProofStructure("pprime")
@@ -105,14 +170,15 @@ func NewValidKeyProofStructure(N *big.Int, Z *big.Int, S *big.Int, Bases []*big.
 		},
 	}
 
-	structure.pprimeIsPrime = newPrimeProofStructure("pprime", uint((N.BitLen()+1)/2))
-	structure.qprimeIsPrime = newPrimeProofStructure("qprime", uint((N.BitLen()+1)/2))
+	structure.pprimeIsPrime = newPrimeProofStructure("pprime", uint((N.BitLen()+1)/2), structure.params)
+	structure.qprimeIsPrime = newPrimeProofStructure("qprime", uint((N.BitLen()+1)/2), structure.params)
 
 	BaseList := []*big.Int{}
 	BaseList = append(BaseList, Z)
 	BaseList = append(BaseList, S)
 	BaseList = append(BaseList, Bases...)
-	structure.basesValid = newIsSquareProofStructure(N, BaseList)
+	structure.basesValid = newIsSquareProofStructure(N, BaseList, structure.params)
+	structure.basesLogs = newDiscreteLogProofStructure(N, S, append([]*big.Int{Z}, Bases...), structure.params)
 
 	return structure
 }
@@ -121,13 +187,71 @@ func (s *ValidKeyProofStructure) numRangeProofs() int {
 	return s.pprimeIsPrime.numRangeProofs() + s.qprimeIsPrime.numRangeProofs() + s.basesValid.numRangeProofs()
 }
 
+// A commit carrying only the names of its pederson commitments
+func (s *ValidKeyProofStructure) newCommit() validKeyProofCommit {
+	var commit validKeyProofCommit
+	commit.pprimeSecret = newPedersonName("pprime")
+	commit.qprimeSecret = newPedersonName("qprime")
+	commit.pSecret = newPedersonName("p")
+	commit.qSecret = newPedersonName("q")
+	return commit
+}
+
+func (s *ValidKeyProofStructure) collectNames(t *nameTracker) {
+	commit := s.newCommit()
+	for _, p := range commit.pedersons() {
+		t.providePederson(p)
+	}
+	t.provideSecrets(&commit.pQNRelSecret)
+
+	s.pRep.collectNames(t)
+	s.qRep.collectNames(t)
+	s.pprimeRep.collectNames(t)
+	s.qprimeRep.collectNames(t)
+	s.pPprimeRel.collectNames(t)
+	s.qQprimeRel.collectNames(t)
+	s.pQNRel.collectNames(t)
+	s.pprimeIsPrime.collectNames(t)
+	s.qprimeIsPrime.collectNames(t)
+}
+
+// Validate checks that every base and secret referenced by the proof
+// structure and its sub-structures is provided, and that everything provided
+// is used. The bases validity proof uses its own set of bases, so it is
+// checked separately, as is that the discrete log proof covers the bases
+// shown to be squares.
+func (s *ValidKeyProofStructure) Validate() ValidationReport {
+	report := validateStructure(s)
+	report = report.merge(validateStructure(&s.basesValid))
+	return report.merge(s.basesLogs.validate(&s.basesValid))
+}
+
+// Check a convenient safe prime is available as group prime, see
+// FindConvenientSafePrime
+func (s *ValidKeyProofStructure) CheckGroupPrime() error {
+	_, err := FindConvenientSafePrime(s.params.groupPrimeSize(s.n.BitLen()))
+	return err
+}
+
 func (s *ValidKeyProofStructure) BuildProof(Pprime *big.Int, Qprime *big.Int) ValidKeyProof {
+	return s.BuildProofWithDiscreteLogs(Pprime, Qprime, nil)
+}
+
+// Build a proof that also shows S generates QR_N and knowledge of the
+// discrete logs of Z and the R_i to base S, given in that order. Without
+// logs this is the same as BuildProof.
+func (s *ValidKeyProofStructure) BuildProofWithDiscreteLogs(Pprime *big.Int, Qprime *big.Int, logs []*big.Int) ValidKeyProof {
+	// Catch mis-wired structures before they fail somewhere deep inside proving
+	if report := s.Validate(); !report.Ok() {
+		panic(fmt.Sprintf("Invalid proof structure: %v", report))
+	}
+
 	// Generate proof group
 	Follower.StepStart("Generating group prime", 0)
-	primeSize := s.n.BitLen() + 2*rangeProofEpsilon + 10
+	primeSize := s.params.groupPrimeSize(s.n.BitLen())
 
 	GroupPrime := findSafePrime(primeSize)
-	g, gok := buildGroup(GroupPrime)
+	g, gok := buildGroupVersion(GroupPrime, groupVersionHashed)
 	if !gok {
 		panic("Safe prime generated by gabi was not a safe prime!?")
 	}
@@ -135,37 +259,68 @@ func (s *ValidKeyProofStructure) BuildProof(Pprime *big.Int, Qprime *big.Int) Va
 
 	Follower.StepStart("Generating commitments", s.numRangeProofs())
 
+	// Build up commitment list
+	var list []*big.Int
+	var commit validKeyProofCommit
+	list = append(list, GroupPrime)
+	list = appendGroupVersion(list, groupVersionHashed)
+	list = appendSecurityParams(list, s.params)
+	list, commit = s.generateCommitmentsFromSecrets(g, list, Pprime, Qprime, logs)
+	Follower.StepDone()
+
+	Follower.StepStart("Generating proof", 0)
+	// Calculate challenge
+	challenge := common.HashCommit(list)
+
+	// Calculate proofs
+	proof := s.buildProof(g, challenge, commit)
+	proof.GroupPrime = GroupPrime
+	proof.GroupVersion = groupVersionHashed
+	if s.params != &LegacySecurityParams {
+		proof.SecurityParams = s.params.Name
+	}
+	proof.Challenge = challenge
+	Follower.StepDone()
+
+	return proof
+}
+
+// Commitments for the key, excluding the group and security parameters, which
+// are shared by all keys in a KeyFamilyProof
+func (s *ValidKeyProofStructure) generateCommitmentsFromSecrets(g group, list []*big.Int, Pprime *big.Int, Qprime *big.Int, logs []*big.Int) ([]*big.Int, validKeyProofCommit) {
+	commit := s.newCommit()
+	commit.pprime = Pprime
+	commit.qprime = Qprime
+	commit.hasLogs = logs != nil
+
 	// Build up some derived values
-	P := new(big.Int).Add(new(big.Int).Lsh(Pprime, 1), big.NewInt(1))
-	Q := new(big.Int).Add(new(big.Int).Lsh(Qprime, 1), big.NewInt(1))
+	commit.p = new(big.Int).Add(new(big.Int).Lsh(Pprime, 1), big.NewInt(1))
+	commit.q = new(big.Int).Add(new(big.Int).Lsh(Qprime, 1), big.NewInt(1))
 
 	// Build up the secrets
-	PprimeSecret := newPedersonSecret(g, "pprime", Pprime)
-	QprimeSecret := newPedersonSecret(g, "qprime", Qprime)
-	PSecret := newPedersonSecret(g, "p", P)
-	QSecret := newPedersonSecret(g, "q", Q)
+	commit.pprimeSecret = newPedersonSecret(g, commit.pprimeSecret.name, Pprime)
+	commit.qprimeSecret = newPedersonSecret(g, commit.qprimeSecret.name, Qprime)
+	commit.pSecret = newPedersonSecret(g, commit.pSecret.name, commit.p)
+	commit.qSecret = newPedersonSecret(g, commit.qSecret.name, commit.q)
 
-	PQNRelSecret := safePrimeSecret{
-		new(big.Int).Mod(new(big.Int).Mul(PSecret.hider, QSecret.secret), g.order),
+	commit.pQNRelSecret = safePrimeSecret{
+		new(big.Int).Mod(new(big.Int).Mul(commit.pSecret.hider, commit.qSecret.secret), g.order),
 		common.RandomBigInt(g.order),
 	}
 
 	// Build up bases and secrets structures
-	bases := newBaseMerge(&g, &PSecret, &QSecret, &PprimeSecret, &QprimeSecret)
-	secrets := newSecretMerge(&PSecret, &QSecret, &PprimeSecret, &QprimeSecret, &PQNRelSecret)
+	baseList := []baseLookup{&g}
+	for _, p := range commit.pedersons() {
+		baseList = append(baseList, p)
+	}
+	bases := newBaseMerge(baseList...)
+	secrets := commit.secrets()
 
-	// Build up commitment list
-	var list []*big.Int
-	var PprimeIsPrimeCommit primeProofCommit
-	var QprimeIsPrimeCommit primeProofCommit
-	var QSPPcommit quasiSafePrimeProductCommit
-	var BasesValidCommit isSquareProofCommit
-	list = append(list, GroupPrime)
 	list = append(list, s.n)
-	list = PprimeSecret.generateCommitments(list)
-	list = QprimeSecret.generateCommitments(list)
-	list = PSecret.generateCommitments(list)
-	list = QSecret.generateCommitments(list)
+	list = commit.pprimeSecret.generateCommitments(list)
+	list = commit.qprimeSecret.generateCommitments(list)
+	list = commit.pSecret.generateCommitments(list)
+	list = commit.qSecret.generateCommitments(list)
 	list = s.pRep.generateCommitmentsFromSecrets(g, list, &bases, &secrets)
 	list = s.qRep.generateCommitmentsFromSecrets(g, list, &bases, &secrets)
 	list = s.pprimeRep.generateCommitmentsFromSecrets(g, list, &bases, &secrets)
@@ -173,37 +328,41 @@ func (s *ValidKeyProofStructure) BuildProof(Pprime *big.Int, Qprime *big.Int) Va
 	list = s.pPprimeRel.generateCommitmentsFromSecrets(g, list, &bases, &secrets)
 	list = s.qQprimeRel.generateCommitmentsFromSecrets(g, list, &bases, &secrets)
 	list = s.pQNRel.generateCommitmentsFromSecrets(g, list, &bases, &secrets)
-	list, PprimeIsPrimeCommit = s.pprimeIsPrime.generateCommitmentsFromSecrets(g, list, &bases, &secrets)
-	list, QprimeIsPrimeCommit = s.qprimeIsPrime.generateCommitmentsFromSecrets(g, list, &bases, &secrets)
-	list, QSPPcommit = quasiSafePrimeProductBuildCommitments(list, Pprime, Qprime)
-	list, BasesValidCommit = s.basesValid.generateCommitmentsFromSecrets(g, list, P, Q)
-	Follower.StepDone()
+	list, commit.pprimeIsPrime = s.pprimeIsPrime.generateCommitmentsFromSecrets(g, list, &bases, &secrets)
+	list, commit.qprimeIsPrime = s.qprimeIsPrime.generateCommitmentsFromSecrets(g, list, &bases, &secrets)
+	list, commit.qspp = quasiSafePrimeProductBuildCommitments(list, Pprime, Qprime, s.params)
+	list, commit.basesValid = s.basesValid.generateCommitmentsFromSecrets(g, list, commit.p, commit.q)
+	if commit.hasLogs {
+		list, commit.basesLogs = s.basesLogs.generateCommitmentsFromSecrets(list, logs)
+	}
 
-	Follower.StepStart("Generating proof", 0)
-	// Calculate challenge
-	challenge := common.HashCommit(list)
+	return list, commit
+}
+
+// Proof for the key, without the group, security parameters and challenge
+func (s *ValidKeyProofStructure) buildProof(g group, challenge *big.Int, commit validKeyProofCommit) ValidKeyProof {
+	secrets := commit.secrets()
 
-	// Calculate proofs
 	var proof ValidKeyProof
-	proof.GroupPrime = GroupPrime
 	proof.PQNRel = new(big.Int).Mod(
 		new(big.Int).Sub(
-			PQNRelSecret.pQNRelRandomizer,
+			commit.pQNRelSecret.pQNRelRandomizer,
 			new(big.Int).Mul(
 				challenge,
-				PQNRelSecret.pQNRel)),
+				commit.pQNRelSecret.pQNRel)),
 		g.order)
-	proof.PProof = PSecret.buildProof(g, challenge)
-	proof.QProof = QSecret.buildProof(g, challenge)
-	proof.PprimeProof = PprimeSecret.buildProof(g, challenge)
-	proof.QprimeProof = QprimeSecret.buildProof(g, challenge)
-	proof.Challenge = challenge
-	proof.PprimeIsPrimeProof = s.pprimeIsPrime.buildProof(g, challenge, PprimeIsPrimeCommit, &secrets)
-	proof.QprimeIsPrimeProof = s.qprimeIsPrime.buildProof(g, challenge, QprimeIsPrimeCommit, &secrets)
-	proof.QSPPproof = quasiSafePrimeProductBuildProof(Pprime, Qprime, challenge, QSPPcommit)
-	proof.BasesValidProof = s.basesValid.buildProof(g, challenge, BasesValidCommit)
-	Follower.StepDone()
-
+	proof.PProof = commit.pSecret.buildProof(g, challenge)
+	proof.QProof = commit.qSecret.buildProof(g, challenge)
+	proof.PprimeProof = commit.pprimeSecret.buildProof(g, challenge)
+	proof.QprimeProof = commit.qprimeSecret.buildProof(g, challenge)
+	proof.PprimeIsPrimeProof = s.pprimeIsPrime.buildProof(g, challenge, commit.pprimeIsPrime, &secrets)
+	proof.QprimeIsPrimeProof = s.qprimeIsPrime.buildProof(g, challenge, commit.qprimeIsPrime, &secrets)
+	proof.QSPPproof = quasiSafePrimeProductBuildProof(commit.pprime, commit.qprime, challenge, commit.qspp, s.params)
+	proof.BasesValidProof = s.basesValid.buildProof(g, challenge, commit.basesValid)
+	if commit.hasLogs {
+		basesLogProof := s.basesLogs.buildProof(challenge, commit.basesLogs)
+		proof.BasesLogProof = &basesLogProof
+	}
 	return proof
 }
 
@@ -211,13 +370,66 @@ func (s *ValidKeyProofStructure) VerifyProof(proof ValidKeyProof) bool {
 	// Check proof structure
 	Follower.StepStart("Verifying structure", 0)
 	defer Follower.StepDone()
-	if proof.GroupPrime == nil || proof.GroupPrime.BitLen() < s.n.BitLen()+2*rangeProofEpsilon+10 {
+	minPrimeSize := s.params.groupPrimeSize(s.n.BitLen())
+	if proof.GroupPrime == nil || proof.GroupPrime.BitLen() < minPrimeSize {
+		return false
+	}
+	if !s.policy.allowsGroupPrime(proof.GroupPrime, minPrimeSize) {
 		return false
 	}
 	if !proof.GroupPrime.ProbablyPrime(80) || !new(big.Int).Rsh(proof.GroupPrime, 1).ProbablyPrime(80) {
 		return false
 	}
-	if proof.PQNRel == nil || proof.Challenge == nil {
+	recordedParams := proof.SecurityParams
+	if recordedParams == "" {
+		recordedParams = LegacySecurityParams.Name
+	}
+	if recordedParams != s.params.Name {
+		return false
+	}
+	if proof.Challenge == nil || !s.verifyProofStructure(proof.Challenge, proof) {
+		return false
+	}
+	Follower.StepDone()
+
+	Follower.StepStart("Rebuilding commitments", s.numRangeProofs())
+
+	// Rebuild group
+	g, gok := buildGroupVersion(proof.GroupPrime, proof.GroupVersion)
+	if !gok {
+		return false
+	}
+	if s.params.BatchedRangeProofs && !s.params.LargeChallengeRangeProofs {
+		g.batch = newRangeProofBatch(s.params.RangeProofBatchBits)
+	}
+
+	// Build up commitment list
+	var list []*big.Int
+	list = append(list, proof.GroupPrime)
+	list = appendGroupVersion(list, proof.GroupVersion)
+	list = appendSecurityParams(list, s.params)
+	list = s.generateCommitmentsFromProof(g, list, proof.Challenge, proof)
+
+	Follower.StepDone()
+
+	Follower.StepStart("Verifying proof", 0)
+
+	// Check challenge
+	if proof.Challenge.Cmp(common.HashCommit(list)) != 0 {
+		return false
+	}
+
+	// And all batched range proofs
+	if g.batch != nil && !g.batch.verify(&g) {
+		return false
+	}
+
+	return quasiSafePrimeProductVerifyProof(s.n, proof.Challenge, proof.QSPPproof, s.params)
+}
+
+// Check the structure of the proof for the key, given the challenge
+func (s *ValidKeyProofStructure) verifyProofStructure(challenge *big.Int, proof ValidKeyProof) bool {
+	if proof.PQNRel == nil {
 		return false
 	}
 	if !proof.PProof.verifyStructure() || !proof.QProof.verifyStructure() {
@@ -226,26 +438,27 @@ func (s *ValidKeyProofStructure) VerifyProof(proof ValidKeyProof) bool {
 	if !proof.PprimeProof.verifyStructure() || !proof.QprimeProof.verifyStructure() {
 		return false
 	}
-	if !s.pprimeIsPrime.verifyProofStructure(proof.Challenge, proof.PprimeIsPrimeProof) ||
-		!s.qprimeIsPrime.verifyProofStructure(proof.Challenge, proof.QprimeIsPrimeProof) {
+	if !s.pprimeIsPrime.verifyProofStructure(challenge, proof.PprimeIsPrimeProof) ||
+		!s.qprimeIsPrime.verifyProofStructure(challenge, proof.QprimeIsPrimeProof) {
 		return false
 	}
-	if !quasiSafePrimeProductVerifyStructure(proof.QSPPproof) {
+	if !quasiSafePrimeProductVerifyStructure(proof.QSPPproof, s.params) {
 		return false
 	}
 	if !s.basesValid.verifyProofStructure(proof.BasesValidProof) {
 		return false
 	}
-	Follower.StepDone()
-
-	Follower.StepStart("Rebuilding commitments", s.numRangeProofs())
-
-	// Rebuild group
-	g, gok := buildGroup(proof.GroupPrime)
-	if !gok {
+	if proof.BasesLogProof == nil && s.policy.RequireDiscreteLogProof {
+		return false
+	}
+	if proof.BasesLogProof != nil && !s.basesLogs.verifyProofStructure(*proof.BasesLogProof) {
 		return false
 	}
+	return true
+}
 
+// Rebuild the commitments for the key, the counterpart of generateCommitmentsFromSecrets
+func (s *ValidKeyProofStructure) generateCommitmentsFromProof(g group, list []*big.Int, challenge *big.Int, proof ValidKeyProof) []*big.Int {
 	// Setup names in the pederson proofs
 	proof.PProof.setName("p")
 	proof.QProof.setName("q")
@@ -256,35 +469,42 @@ func (s *ValidKeyProofStructure) VerifyProof(proof ValidKeyProof) bool {
 	bases := newBaseMerge(&g, &proof.PProof, &proof.QProof, &proof.PprimeProof, &proof.QprimeProof)
 	proofs := newProofMerge(&proof.PProof, &proof.QProof, &proof.PprimeProof, &proof.QprimeProof, &proof)
 
-	// Build up commitment list
-	var list []*big.Int
-	list = append(list, proof.GroupPrime)
 	list = append(list, s.n)
 	list = proof.PprimeProof.generateCommitments(list)
 	list = proof.QprimeProof.generateCommitments(list)
 	list = proof.PProof.generateCommitments(list)
 	list = proof.QProof.generateCommitments(list)
-	list = s.pRep.generateCommitmentsFromProof(g, list, proof.Challenge, &bases, &proofs)
-	list = s.qRep.generateCommitmentsFromProof(g, list, proof.Challenge, &bases, &proofs)
-	list = s.pprimeRep.generateCommitmentsFromProof(g, list, proof.Challenge, &bases, &proofs)
-	list = s.qprimeRep.generateCommitmentsFromProof(g, list, proof.Challenge, &bases, &proofs)
-	list = s.pPprimeRel.generateCommitmentsFromProof(g, list, proof.Challenge, &bases, &proofs)
-	list = s.qQprimeRel.generateCommitmentsFromProof(g, list, proof.Challenge, &bases, &proofs)
-	list = s.pQNRel.generateCommitmentsFromProof(g, list, proof.Challenge, &bases, &proofs)
-	list = s.pprimeIsPrime.generateCommitmentsFromProof(g, list, proof.Challenge, &bases, &proofs, proof.PprimeIsPrimeProof)
-	list = s.qprimeIsPrime.generateCommitmentsFromProof(g, list, proof.Challenge, &bases, &proofs, proof.QprimeIsPrimeProof)
+	list = s.pRep.generateCommitmentsFromProof(g, list, challenge, &bases, &proofs)
+	list = s.qRep.generateCommitmentsFromProof(g, list, challenge, &bases, &proofs)
+	list = s.pprimeRep.generateCommitmentsFromProof(g, list, challenge, &bases, &proofs)
+	list = s.qprimeRep.generateCommitmentsFromProof(g, list, challenge, &bases, &proofs)
+	list = s.pPprimeRel.generateCommitmentsFromProof(g, list, challenge, &bases, &proofs)
+	list = s.qQprimeRel.generateCommitmentsFromProof(g, list, challenge, &bases, &proofs)
+	list = s.pQNRel.generateCommitmentsFromProof(g, list, challenge, &bases, &proofs)
+	list = s.pprimeIsPrime.generateCommitmentsFromProof(g, list, challenge, &bases, &proofs, proof.PprimeIsPrimeProof)
+	list = s.qprimeIsPrime.generateCommitmentsFromProof(g, list, challenge, &bases, &proofs, proof.QprimeIsPrimeProof)
 	list = quasiSafePrimeProductExtractCommitments(list, proof.QSPPproof)
-	list = s.basesValid.generateCommitmentsFromProof(g, list, proof.Challenge, proof.BasesValidProof)
-
-	Follower.StepDone()
-
-	Follower.StepStart("Verifying proof", 0)
+	list = s.basesValid.generateCommitmentsFromProof(g, list, challenge, proof.BasesValidProof)
+	if proof.BasesLogProof != nil {
+		list = s.basesLogs.generateCommitmentsFromProof(list, challenge, *proof.BasesLogProof)
+	}
+	return list
+}
 
-	// Check challenge
-	if proof.Challenge.Cmp(common.HashCommit(list)) != 0 {
-		return false
+// Bind the group version into the challenge. Legacy proofs didn't include it,
+// so to keep those verifying it is left out for the legacy version.
+func appendGroupVersion(list []*big.Int, version int) []*big.Int {
+	if version == groupVersionLegacy {
+		return list
 	}
+	return append(list, big.NewInt(int64(version)))
+}
 
-	// And the QSPP proof
-	return quasiSafePrimeProductVerifyProof(s.n, proof.Challenge, proof.QSPPproof)
+// Bind the security parameters into the challenge, left out for legacy-80 for
+// the same reason as the group version.
+func appendSecurityParams(list []*big.Int, params *SecurityParams) []*big.Int {
+	if params == &LegacySecurityParams {
+		return list
+	}
+	return append(list, new(big.Int).SetBytes([]byte(params.Name)))
 }
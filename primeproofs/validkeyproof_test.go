@@ -59,6 +59,17 @@ func TestValidKeyProofStructure(t *testing.T) {
 	}
 	proof.GroupPrime = backup
 
+	proof.GroupVersion = groupVersionLegacy
+	if s.VerifyProof(proof) {
+		t.Error("Accepting proof with changed group version")
+	}
+
+	proof.GroupVersion = 42
+	if s.VerifyProof(proof) {
+		t.Error("Accepting unknown group version")
+	}
+	proof.GroupVersion = groupVersionHashed
+
 	backup = proof.PProof.Commit
 	proof.PProof.Commit = nil
 	if s.VerifyProof(proof) {
@@ -166,3 +177,81 @@ func TestValidKeyProofJSON(t *testing.T) {
 		t.Error("Proof rejected.\n")
 	}
 }
+
+func TestValidKeyProofSecurityParams(t *testing.T) {
+	const p = 26903
+	const q = 27803
+	const a = 36
+	const b = 49
+	const c = 64
+
+	Follower.(*TestFollower).count = 0
+
+	s := NewValidKeyProofStructure(big.NewInt(p*q), big.NewInt(a), big.NewInt(b), []*big.Int{big.NewInt(c)}, WithSecurityParams("fast-test"))
+	proofBefore := s.BuildProof(big.NewInt((p-1)/2), big.NewInt((q-1)/2))
+
+	if Follower.(*TestFollower).count != s.numRangeProofs() {
+		t.Error("Logging is off GenerateCommitmentsFromSecrets")
+	}
+
+	if proofBefore.SecurityParams != "fast-test" {
+		t.Error("Security parameters not recorded in proof")
+	}
+
+	proofJSON, err := json.Marshal(proofBefore)
+	if err != nil {
+		t.Errorf("error during json marshal: %s", err.Error())
+		return
+	}
+	var proof ValidKeyProof
+	err = json.Unmarshal(proofJSON, &proof)
+	if err != nil {
+		t.Errorf("error during json unmarshal: %s", err.Error())
+		return
+	}
+
+	Follower.(*TestFollower).count = 0
+	if !s.VerifyProof(proof) {
+		t.Error("Proof rejected")
+	}
+	if Follower.(*TestFollower).count != s.numRangeProofs() {
+		t.Error("Logging is off on GenerateCommitmentsFromProof")
+	}
+
+	legacy := NewValidKeyProofStructure(big.NewInt(p*q), big.NewInt(a), big.NewInt(b), []*big.Int{big.NewInt(c)})
+	if legacy.VerifyProof(proof) {
+		t.Error("Proof accepted with different security parameters")
+	}
+
+	proof.SecurityParams = ""
+	if s.VerifyProof(proof) {
+		t.Error("Proof accepted with security parameters removed")
+	}
+}
+
+func TestValidKeyProofUnknownSecurityParams(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Unknown security parameters accepted")
+		}
+	}()
+	NewValidKeyProofStructure(big.NewInt(26903*27803), big.NewInt(36), big.NewInt(49), []*big.Int{big.NewInt(64)}, WithSecurityParams("nonexistent"))
+}
+
+func TestValidKeyProofExactRanges(t *testing.T) {
+	const p = 26903
+	const q = 27803
+
+	params := FastTestSecurityParams
+	params.Name = "exact-range-test"
+	params.ExactRangeProofs = true
+
+	s := NewValidKeyProofStructure(big.NewInt(p*q), big.NewInt(36), big.NewInt(49), []*big.Int{big.NewInt(64)}, withTestSecurityParams(&params))
+	if report := s.Validate(); !report.Ok() {
+		t.Errorf("Exact range proof structure invalid: %v", report)
+	}
+	proof := s.BuildProof(big.NewInt((p-1)/2), big.NewInt((q-1)/2))
+	if !s.VerifyProof(proof) {
+		t.Error("Proof with exact range proofs rejected")
+	}
+}
@@ -0,0 +1,61 @@
+package primeproofs
+
+import "github.com/privacybydesign/gabi/big"
+
+// A VerifierPolicy restricts the group primes accepted during verification.
+// The group prime is chosen by the prover, so without a policy a malicious
+// prover could make verification arbitrarily expensive.
+type VerifierPolicy struct {
+	// Maximum size of the group prime in bits. Zero selects the default, which
+	// accepts anything the prover would generate for the modulus size.
+	MaxGroupPrimeBits int
+
+	// When not empty, only these group primes are accepted
+	AllowedGroupPrimes []*big.Int
+
+	// Only accept group primes from the convenient safe prime tables
+	RequireConvenientPrime bool
+
+	// Only accept proofs showing S generates QR_N and knowledge of the
+	// discrete logs of Z and the R_i, see discretelogproof.go
+	RequireDiscreteLogProof bool
+}
+
+// Use the given verifier policy when verifying proofs
+func WithVerifierPolicy(policy VerifierPolicy) ValidKeyProofOption {
+	return func(s *ValidKeyProofStructure) {
+		s.policy = policy
+	}
+}
+
+// Check whether the policy allows verification with the given group prime,
+// for a structure requiring primes of at least minBits bits.
+func (p *VerifierPolicy) allowsGroupPrime(prime *big.Int, minBits int) bool {
+	maxBits := p.MaxGroupPrimeBits
+	if maxBits == 0 {
+		// Generated primes have exactly minBits bits, convenient ones less than convenientRange more
+		maxBits = minBits + convenientRange - 1
+	}
+	if prime.BitLen() > maxBits {
+		return false
+	}
+
+	if len(p.AllowedGroupPrimes) != 0 {
+		allowed := false
+		for _, allowedPrime := range p.AllowedGroupPrimes {
+			if allowedPrime != nil && allowedPrime.Cmp(prime) == 0 {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	if p.RequireConvenientPrime && !isConvenientPrime(prime) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,100 @@
+package primeproofs
+
+import (
+	"math"
+	"testing"
+
+	"github.com/privacybydesign/gabi/big"
+)
+
+func TestVerifierPolicyDefault(t *testing.T) {
+	var policy VerifierPolicy
+	convenient := findConvenientPrime(2048)
+	if !policy.allowsGroupPrime(convenient, 2048) {
+		t.Error("Default policy rejects convenient prime")
+	}
+	if !policy.allowsGroupPrime(new(big.Int).Lsh(big.NewInt(1), 2047), 2048) {
+		t.Error("Default policy rejects prime of minimum size")
+	}
+	if policy.allowsGroupPrime(convenient, 1900) {
+		t.Error("Default policy accepts prime much larger than needed")
+	}
+}
+
+func TestVerifierPolicyMaxBits(t *testing.T) {
+	policy := VerifierPolicy{MaxGroupPrimeBits: 2100}
+	if policy.allowsGroupPrime(findConvenientPrime(2100), 2000) {
+		t.Error("Accepted prime larger than maximum")
+	}
+	if !policy.allowsGroupPrime(findConvenientPrime(2000), 1500) {
+		t.Error("Rejected prime smaller than maximum")
+	}
+}
+
+func TestVerifierPolicyAllowList(t *testing.T) {
+	prime := findConvenientPrime(2048)
+	other := findConvenientPrime(2150)
+	policy := VerifierPolicy{MaxGroupPrimeBits: 4096, AllowedGroupPrimes: []*big.Int{prime}}
+	if !policy.allowsGroupPrime(prime, 2048) {
+		t.Error("Rejected allowed prime")
+	}
+	if policy.allowsGroupPrime(other, 2048) {
+		t.Error("Accepted prime not on allow list")
+	}
+
+	policy.AllowedGroupPrimes = ConvenientSafePrimes()
+	if !policy.allowsGroupPrime(other, 2048) {
+		t.Error("Rejected prime from convenient table")
+	}
+}
+
+func TestVerifierPolicyConvenient(t *testing.T) {
+	policy := VerifierPolicy{RequireConvenientPrime: true}
+	prime := findConvenientPrime(2048)
+	if !policy.allowsGroupPrime(prime, 2048) {
+		t.Error("Rejected convenient prime")
+	}
+	if policy.allowsGroupPrime(new(big.Int).Sub(prime, big.NewInt(4)), 2048) {
+		t.Error("Accepted prime not in table")
+	}
+
+	// Searched so it is not in an earlier registered table
+	cp, err := SearchConvenientSafePrime(600, math.MaxInt32)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if policy.allowsGroupPrime(cp.value(), 600) {
+		t.Error("Accepted prime before registration")
+	}
+	if err := RegisterConvenientSafePrimes([]ConvenientSafePrime{cp}); err != nil {
+		t.Fatal(err.Error())
+	}
+	if !policy.allowsGroupPrime(cp.value(), 600) {
+		t.Error("Rejected registered convenient prime")
+	}
+}
+
+func TestValidKeyProofVerifierPolicy(t *testing.T) {
+	const p = 26903
+	const q = 27803
+	s := NewValidKeyProofStructure(big.NewInt(p*q), big.NewInt(36), big.NewInt(49), []*big.Int{big.NewInt(64)})
+	proof := s.BuildProof(big.NewInt((p-1)/2), big.NewInt((q-1)/2))
+
+	allowed := NewValidKeyProofStructure(big.NewInt(p*q), big.NewInt(36), big.NewInt(49), []*big.Int{big.NewInt(64)},
+		WithVerifierPolicy(VerifierPolicy{AllowedGroupPrimes: []*big.Int{proof.GroupPrime}}))
+	if !allowed.VerifyProof(proof) {
+		t.Error("Proof with allowed group prime rejected")
+	}
+
+	other := NewValidKeyProofStructure(big.NewInt(p*q), big.NewInt(36), big.NewInt(49), []*big.Int{big.NewInt(64)},
+		WithVerifierPolicy(VerifierPolicy{AllowedGroupPrimes: []*big.Int{new(big.Int).Add(proof.GroupPrime, big.NewInt(2))}}))
+	if other.VerifyProof(proof) {
+		t.Error("Proof with group prime not on allow list accepted")
+	}
+
+	small := NewValidKeyProofStructure(big.NewInt(p*q), big.NewInt(36), big.NewInt(49), []*big.Int{big.NewInt(64)},
+		WithVerifierPolicy(VerifierPolicy{MaxGroupPrimeBits: proof.GroupPrime.BitLen() - 1}))
+	if small.VerifyProof(proof) {
+		t.Error("Proof with too large group prime accepted")
+	}
+}